@@ -7,11 +7,16 @@
 //   - Compress: Создает файл архива
 //   - Decompress: Выполняет распаковку архива
 //   - IntegrityTest: Проверяет целостность данных в архиве
+//   - Repair: Исправляет архив по записи восстановления
+//   - Verify: Проверяет подпись архива
 //   - ViewStat: Печатает подробную информацию об архиве
 //   - ViewList: Печатает список файлов в архиве
 package arc
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"os"
@@ -21,8 +26,13 @@ import (
 	"sync/atomic"
 	"syscall"
 
+	"github.com/gh0st17/archiver/arc/internal/crypt"
+	"github.com/gh0st17/archiver/arc/internal/decompress"
 	"github.com/gh0st17/archiver/arc/internal/generic"
+	"github.com/gh0st17/archiver/arc/internal/header"
+	"github.com/gh0st17/archiver/arc/internal/recovery"
 	"github.com/gh0st17/archiver/arc/internal/userinput"
+	"github.com/gh0st17/archiver/arc/internal/volume"
 	c "github.com/gh0st17/archiver/compressor"
 	"github.com/gh0st17/archiver/errtype"
 	"github.com/gh0st17/archiver/filesystem"
@@ -31,14 +41,40 @@ import (
 
 const (
 	magicNumber uint16 = 0x5717
-	headerLen   int64  = 3
+	// Длина заголовка архива: сигнатура, версия формата,
+	// тип компрессора и флаги возможностей
+	headerLen int64 = 8
+	// Длина заголовка архива формата 1.0.x:
+	// сигнатура и тип компрессора
+	legacyHeaderLen int64 = 3
 )
 
 // Структура параметров архива
 type Arc struct {
 	path    string // Путь к файлу архива
 	verbose bool
-	sigChan chan os.Signal
+	times   bool     // Печать всех временных меток в статистике
+	extract []string // Пути элементов для выборочной распаковки
+	format  header.Format
+	start   int64 // Смещение первого элемента в архиве
+	// Размер записи восстановления в процентах
+	recovery int
+	// Шифр блоков данных и его параметры
+	cipher      *crypt.Cipher
+	cryptParams crypt.Params
+	// Ключ данных, зашифрованный для получателей
+	stanzas []crypt.Stanza
+	// Шифровать заголовки элементов и индекс
+	hideHeaders bool
+	// Ключ подписи архива при сжатии
+	signKey ed25519.PrivateKey
+	// Размер тома, 0 -- архив из одного файла
+	volSize int64
+	// Доверенные ключи подписи и признак
+	// проверки подписи перед распаковкой
+	signers    []ed25519.PublicKey
+	requireSig bool
+	sigChan    chan os.Signal
 	generic.RestoreParams
 }
 
@@ -57,49 +93,366 @@ func NewArc(p params.Params) (arc *Arc, err error) {
 	arc.ReplaceAll = &p.ReplaceAll
 	arc.DictPath = p.DictPath
 	arc.verbose = p.Verbose
+	arc.times = p.PrintTimes
 
 	arc.sigChan = make(chan os.Signal, 1)
 	signal.Notify(arc.sigChan, os.Interrupt, syscall.SIGTERM)
 	go arc.sigFunc()
 
+	arc.XattrFilter = header.XattrFilter{
+		Include: p.XattrInclude,
+		Exclude: p.XattrExclude,
+	}
+
 	if len(p.InputPaths) > 0 {
 		allowRemove.Store(true)
+		arc.Xattrs = p.Xattrs
+		arc.SymAbs = p.SymAbs
 		arc.Ct = p.Ct
 		arc.Cl = p.Cl
+		for _, r := range p.CodecRules {
+			arc.CodecRules = append(arc.CodecRules, header.CodecRule{
+				Pattern: r.Pattern,
+				Codec:   header.Codec{Type: r.Ct, Level: r.Cl},
+			})
+		}
+		arc.Digest = digestType(p.Digest)
+		arc.recovery = p.Recovery
+		arc.volSize = p.VolumeSize
+		arc.SolidSize = p.SolidSize
+		arc.Dedup = p.Dedup
+		arc.DupFiles = p.DupFiles
+		arc.hideHeaders = p.EncHeaders
+		if err = arc.createCipher(p); err != nil {
+			return nil, err
+		}
+		if p.Sign != "" {
+			if arc.signKey, err = readSignKey(p.Sign); err != nil {
+				return nil, err
+			}
+		}
+		if p.Sample {
+			arc.Blocks = generic.BlockSample
+		} else if p.Adaptive {
+			arc.Blocks = generic.BlockAdaptive
+		}
 	} else {
 		allowRemove.Store(false)
-		arcFile, err := os.Open(arc.path)
+		arcFile, err := volume.Open(arc.path, os.O_RDONLY)
 		if err != nil {
 			return nil, errtype.Join(ErrOpenArc, err)
 		}
 		defer arcFile.Close()
 
-		var magic uint16
-		if err = filesystem.BinaryRead(arcFile, &magic); err != nil {
-			return nil, errtype.Join(ErrReadMagic, err)
-		}
-		if magic != magicNumber {
-			return nil, ErrNotArc(arcFile.Name())
+		// Исправление архива не зависит от его заголовка,
+		// который тоже может быть поврежден
+		if err = arc.readArcHeader(arcFile); err != nil && !p.Repair {
+			return nil, err
 		}
 
-		var compType byte
-		if err = filesystem.BinaryRead(arcFile, &compType); err != nil {
-			return nil, err
+		// Для просмотра списка и статистики пароль нужен,
+		// только если заголовки зашифрованы. Без пароля
+		// печатается лишь признак шифрования архива
+		view := p.PrintList || p.PrintStat
+		needKey := !p.Repair && !p.Verify && (!view || arc.format.Has(header.FeatHiddenHeaders))
+		if arc.format.Has(header.FeatEncrypted) && needKey {
+			err = arc.openCipher(p)
+			if err != nil && !(view && (err == ErrNoPassphrase || err == ErrNoIdentity)) {
+				return nil, err
+			}
 		}
 
-		if compType <= byte(c.Flate) {
-			arc.Ct = c.Type(compType)
-		} else {
-			return nil, ErrUnknownComp
+		if arc.signers, err = readSigners(p.Signers); err != nil {
+			return nil, err
 		}
+		arc.requireSig = p.RequireSig || len(arc.signers) > 0
 
 		arc.Integ = p.XIntegTest
+		arc.NoPerm = p.NoPerm
+		arc.Owner = ownerParams(p)
+		arc.Damaged = damageMode(p.Damaged)
 		arc.OutputDir = p.OutputDir
+		for _, path := range p.Extract {
+			arc.extract = append(arc.extract, filesystem.Clean(path))
+		}
 	}
 
 	return arc, nil
 }
 
+// Читает заголовок архива, определяет формат архива,
+// тип компрессора и смещение первого элемента.
+//
+// В формате 1.0.x за сигнатурой следует тип компрессора.
+// В последующих форматах за ней следует версия формата с
+// установленным битом [header.VersionMark], тип компрессора
+// и флаги возможностей. Архивы более новых версий или с
+// неизвестными возможностями не читаются
+func (arc *Arc) readArcHeader(arcFile volume.File) (err error) {
+	var (
+		magic     uint16
+		ver, comp byte
+		features  header.Features
+	)
+
+	if err = filesystem.BinaryRead(arcFile, &magic); err != nil {
+		return errtype.Join(ErrReadMagic, err)
+	}
+	if magic != magicNumber {
+		return ErrNotArc(arcFile.Name())
+	}
+
+	if err = filesystem.BinaryRead(arcFile, &ver); err != nil {
+		return errtype.Join(ErrReadVersion, err)
+	}
+
+	if ver&header.VersionMark == 0 { // Формат 1.0.x
+		arc.format = header.Format{Version: header.Legacy}
+		arc.start = legacyHeaderLen
+		comp = ver
+	} else {
+		version := header.Version(ver &^ header.VersionMark)
+		if version != header.Current {
+			return ErrVersion(byte(version))
+		}
+
+		if err = filesystem.BinaryRead(arcFile, &comp); err != nil {
+			return errtype.Join(ErrReadCompType, err)
+		}
+		if err = filesystem.BinaryRead(arcFile, &features); err != nil {
+			return errtype.Join(ErrReadFeatures, err)
+		}
+		if unknown := features &^ header.KnownFeatures; unknown != 0 {
+			return ErrFeatures(uint32(unknown))
+		}
+
+		arc.format = header.Format{Version: version, Features: features}
+		arc.start = headerLen
+
+		if features.Has(header.FeatEncrypted | header.FeatRecipients) {
+			var count uint16
+			if err = filesystem.BinaryRead(arcFile, &count); err != nil {
+				return errtype.Join(ErrReadCryptParams, err)
+			}
+			arc.stanzas = make([]crypt.Stanza, count)
+			if err = filesystem.BinaryRead(arcFile, arc.stanzas); err != nil {
+				return errtype.Join(ErrReadCryptParams, err)
+			}
+			arc.start += int64(binary.Size(count) + binary.Size(arc.stanzas))
+		} else if features.Has(header.FeatEncrypted) {
+			if err = filesystem.BinaryRead(arcFile, &arc.cryptParams); err != nil {
+				return errtype.Join(ErrReadCryptParams, err)
+			}
+			arc.start += int64(binary.Size(arc.cryptParams))
+		}
+	}
+
+	if comp > byte(c.Flate) {
+		return ErrUnknownComp
+	}
+	arc.Ct = c.Type(comp)
+
+	return nil
+}
+
+// Открывает файл архива для чтения, устанавливает формат
+// разбора заголовков и перемещается к первому элементу.
+// Если заголовки архива зашифрованы, то возвращается
+// расшифровывающий их поток, который не затрагивает
+// запись восстановления. Код аутентификации заголовка
+// элемента проверяется при чтении его типа
+func (arc Arc) openArc() (io.ReadSeekCloser, error) {
+	arcFile, err := volume.Open(arc.path, os.O_RDONLY)
+	if err != nil {
+		return nil, errtype.Join(ErrOpenArc, err)
+	}
+
+	header.SetFormat(arc.format)
+	generic.SetCipher(arc.cipher)
+	generic.SetHeaderCipher(nil)
+
+	end := int64(-1)
+	if arc.format.Has(header.FeatHiddenHeaders | header.FeatRecovery) {
+		if end, err = recovery.End(arcFile); err != nil {
+			end = -1
+		}
+	}
+
+	if arc.format.Has(header.FeatHiddenHeaders) {
+		if arc.cipher == nil {
+			arcFile.Close()
+			return nil, ErrNoPassphrase
+		}
+		generic.SetHeaderCipher(arc.cipher)
+	}
+
+	if _, err = arcFile.Seek(arc.start, io.SeekStart); err != nil {
+		arcFile.Close()
+		return nil, errtype.Join(ErrSeek, err)
+	}
+
+	if !arc.format.Has(header.FeatHiddenHeaders) {
+		return arcFile, nil
+	}
+
+	stream, err := arc.cipher.Stream(arcFile, arc.start, end)
+	if err != nil {
+		arcFile.Close()
+		return nil, errtype.Join(ErrSeek, err)
+	}
+
+	return stream, nil
+}
+
+// Строит таблицу фрагментов архива с дедупликацией по
+// отдельному читателю архива и устанавливает ее для
+// распаковки. Для архива без дедупликации возвращает nil
+func (arc Arc) openChunks() (*decompress.ChunkStore, error) {
+	if !arc.format.Has(header.FeatDedup) {
+		decompress.SetChunkStore(nil)
+		return nil, nil
+	}
+
+	arcFile, err := arc.openArc()
+	if err != nil {
+		return nil, err
+	}
+
+	cs, err := decompress.NewChunkStore(arcFile, arc.start, arc.Ct)
+	if err != nil {
+		arcFile.Close()
+		return nil, errtype.Join(ErrReadHeaders, err)
+	}
+	decompress.SetChunkStore(cs)
+
+	return cs, nil
+}
+
+// Возвращает параметры восстановления владельца
+func ownerParams(p params.Params) header.OwnerParams {
+	op := header.OwnerParams{Users: p.UserMap, Groups: p.GroupMap}
+
+	switch p.Owner {
+	case params.OwnerName:
+		op.Mode = header.OwnerName
+	case params.OwnerNum:
+		op.Mode = header.OwnerNum
+	case params.OwnerNone:
+		op.Mode = header.OwnerNone
+	default: // Менять владельца может только root
+		if os.Geteuid() == 0 {
+			op.Mode = header.OwnerName
+		}
+	}
+
+	return op
+}
+
+// Создает шифр нового архива для получателей из
+// параметров или по паролю, если задано шифрование
+func (arc *Arc) createCipher(p params.Params) (err error) {
+	if len(p.Recipients) > 0 {
+		keys, err := readRecipients(p.Recipients)
+		if err != nil {
+			return err
+		}
+		if arc.cipher, arc.stanzas, err = crypt.CreateFor(keys); err != nil {
+			return errtype.Join(ErrEncryptInit, err)
+		}
+	} else if p.Encrypt || p.EncHeaders {
+		pass, err := passphrase(p, true)
+		if err != nil {
+			return err
+		}
+		if arc.cipher, arc.cryptParams, err = crypt.Create(pass); err != nil {
+			return errtype.Join(ErrEncryptInit, err)
+		}
+	}
+
+	return nil
+}
+
+// Создает шифр существующего архива закрытым
+// ключом получателя или по паролю
+func (arc *Arc) openCipher(p params.Params) (err error) {
+	if arc.format.Has(header.FeatRecipients) {
+		ids, err := readIdentities(p.Identities)
+		if err != nil {
+			return err
+		}
+		arc.cipher, err = crypt.OpenFor(ids, arc.stanzas)
+		return err
+	}
+
+	pass, err := passphrase(p, false)
+	if err != nil {
+		return err
+	}
+	arc.cipher, err = crypt.Open(pass, arc.cryptParams)
+	return err
+}
+
+// Возвращает пароль архива из файла, переменной
+// окружения или терминала. При вводе в терминале
+// с confirm == true пароль вводится дважды
+func passphrase(p params.Params, confirm bool) (pass []byte, err error) {
+	switch {
+	case p.PassFile != "":
+		if pass, err = os.ReadFile(p.PassFile); err != nil {
+			return nil, errtype.Join(ErrReadPassphrase, err)
+		}
+		pass = bytes.TrimRight(pass, "\r\n")
+	case p.PassEnv != "":
+		pass = []byte(os.Getenv(p.PassEnv))
+	case userinput.IsNonInteractive():
+		return nil, ErrNoPassphrase
+	default:
+		if pass, err = userinput.ReadPassword("Пароль: "); err != nil {
+			return nil, errtype.Join(ErrReadPassphrase, err)
+		}
+		if confirm {
+			again, err := userinput.ReadPassword("Повторите пароль: ")
+			if err != nil {
+				return nil, errtype.Join(ErrReadPassphrase, err)
+			}
+			if !bytes.Equal(pass, again) {
+				return nil, ErrPassMismatch
+			}
+		}
+	}
+
+	if len(pass) == 0 {
+		return nil, ErrNoPassphrase
+	}
+	return pass, nil
+}
+
+// Возвращает тип контрольной суммы содержимого
+// по параметру dt
+func digestType(dt params.DigestType) header.DigestType {
+	switch dt {
+	case params.DigestCRC32C:
+		return header.DigestCRC32C
+	case params.DigestSHA256:
+		return header.DigestSHA256
+	default:
+		return header.DigestNone
+	}
+}
+
+// Возвращает режим обработки поврежденных
+// блоков по параметру dm
+func damageMode(dm params.DamageMode) generic.DamageMode {
+	switch dm {
+	case params.DamageTruncate:
+		return generic.DamageTruncate
+	case params.DamageSkip:
+		return generic.DamageSkip
+	default:
+		return generic.DamageZero
+	}
+}
+
 // Печать статистики использования памяти
 func (Arc) PrintMemStat() {
 	var m runtime.MemStats
@@ -121,9 +474,9 @@ func (arc Arc) sigFunc() {
 	os.Exit(0)
 }
 
-// Удаляет архив
+// Удаляет архив вместе с его томами
 func (arc Arc) removeTmp() {
-	os.Remove(arc.path)
+	volume.Remove(arc.path)
 }
 
 // Закрывает файл архива и удаляет его
@@ -133,8 +486,8 @@ func (arc Arc) closeRemove(arcFile io.Closer) {
 }
 
 // Создает файл архива и пишет информацию об архиве
-func (arc Arc) writeArcHeader() (arcFile *os.File, err error) {
-	if _, err = os.Stat(arc.path); err == nil && !*arc.ReplaceAll {
+func (arc Arc) writeArcHeader() (arcFile volume.File, err error) {
+	if volume.Exists(arc.path) && !*arc.ReplaceAll {
 		allowRemove.Store(false)
 		if userinput.ReplacePrompt(arc.path, nil, nil) {
 			os.Exit(0)
@@ -142,8 +495,8 @@ func (arc Arc) writeArcHeader() (arcFile *os.File, err error) {
 		allowRemove.Store(true)
 	}
 
-	// Создаем файл
-	arcFile, err = os.Create(arc.path)
+	// Создаем файл или первый том
+	arcFile, err = volume.Create(arc.path, arc.volSize)
 	if err != nil {
 		return nil, errtype.Join(ErrCreateArc, err)
 	}
@@ -153,10 +506,64 @@ func (arc Arc) writeArcHeader() (arcFile *os.File, err error) {
 		return nil, errtype.Join(ErrWriteMagic, err)
 	}
 
+	// Пишем версию формата
+	if err = filesystem.BinaryWrite(arcFile, header.VersionMark|byte(header.Current)); err != nil {
+		return nil, errtype.Join(ErrWriteVersion, err)
+	}
+
 	// Пишем тип компрессора
 	if err = filesystem.BinaryWrite(arcFile, arc.Ct); err != nil {
 		return nil, errtype.Join(ErrWriteCompType, err)
 	}
 
+	// Пишем флаги возможностей
+	features := header.FeatIndex | header.FeatCodec |
+		header.FeatLongPaths | header.FeatDigest | header.FeatBlockCRC
+	if arc.Blocks != generic.BlockAlways {
+		features |= header.FeatBlockFlags
+	}
+	if arc.recovery > 0 {
+		features |= header.FeatRecovery
+	}
+	if arc.cipher != nil {
+		features |= header.FeatEncrypted
+	}
+	if arc.hideHeaders {
+		features |= header.FeatHiddenHeaders
+	}
+	if len(arc.stanzas) > 0 {
+		features |= header.FeatRecipients
+	}
+	if arc.signKey != nil {
+		features |= header.FeatSigned
+	}
+	if arc.SolidSize > 0 {
+		features |= header.FeatSolid
+	}
+	if arc.Dedup {
+		features |= header.FeatDedup
+	}
+	if arc.DupFiles {
+		features |= header.FeatDupFiles
+	}
+	if err = filesystem.BinaryWrite(arcFile, features); err != nil {
+		return nil, errtype.Join(ErrWriteFeatures, err)
+	}
+
+	// Пишем ключ данных для получателей
+	// или параметры шифрования по паролю
+	if len(arc.stanzas) > 0 {
+		if err = filesystem.BinaryWrite(arcFile, uint16(len(arc.stanzas))); err != nil {
+			return nil, errtype.Join(ErrWriteCryptParams, err)
+		}
+		if err = filesystem.BinaryWrite(arcFile, arc.stanzas); err != nil {
+			return nil, errtype.Join(ErrWriteCryptParams, err)
+		}
+	} else if arc.cipher != nil {
+		if err = filesystem.BinaryWrite(arcFile, arc.cryptParams); err != nil {
+			return nil, errtype.Join(ErrWriteCryptParams, err)
+		}
+	}
+
 	return arcFile, nil
 }
@@ -0,0 +1,206 @@
+//go:build linux
+// +build linux
+
+package arc_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	p "github.com/gh0st17/archiver/params"
+	"golang.org/x/sys/unix"
+)
+
+func TestXattrs(t *testing.T) {
+	var (
+		src   = filepath.Join(t.TempDir(), "src")
+		file  = filepath.Join(src, "file")
+		attrs = map[string]string{
+			"user.keep":  "value",
+			"user.drop":  "other",
+			"user.empty": "",
+		}
+	)
+
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range attrs {
+		err := unix.Setxattr(file, name, []byte(value), 0)
+		if errors.Is(err, unix.ENOTSUP) {
+			t.Skip("extended attributes are not supported")
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	getXattr := func(path, name string) (string, error) {
+		buf := make([]byte, 256)
+		n, err := unix.Getxattr(path, name, buf)
+		if err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	}
+
+	out := roundTrip(t, src, p.Params{Xattrs: true}, p.Params{})
+	for name, value := range attrs {
+		if got, err := getXattr(filepath.Join(out, "file"), name); err != nil {
+			t.Errorf("'%s': %v", name, err)
+		} else if got != value {
+			t.Errorf("'%s': expected '%s' got '%s'", name, value, got)
+		}
+	}
+
+	out = roundTrip(t, src, p.Params{Xattrs: true}, p.Params{
+		XattrExclude: []string{"user.drop"},
+	})
+	if _, err := getXattr(filepath.Join(out, "file"), "user.drop"); !errors.Is(err, unix.ENODATA) {
+		t.Error("excluded attribute restored:", err)
+	}
+
+	out = roundTrip(t, src, p.Params{}, p.Params{})
+	if _, err := getXattr(filepath.Join(out, "file"), "user.keep"); !errors.Is(err, unix.ENODATA) {
+		t.Error("attribute stored without Xattrs:", err)
+	}
+}
+
+func TestSparse(t *testing.T) {
+	const size = 64 << 20
+
+	var (
+		src  = filepath.Join(t.TempDir(), "src")
+		file = filepath.Join(src, "sparse")
+		data = []byte("data in the middle")
+	)
+
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "plain"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteAt(data, size/2); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	allocated := func(path string) int64 {
+		t.Helper()
+		var st unix.Stat_t
+		if err := unix.Stat(path, &st); err != nil {
+			t.Fatal(err)
+		}
+		return st.Blocks * 512
+	}
+	if allocated(file) >= size {
+		t.Skip("file system does not support sparse files")
+	}
+
+	out := roundTrip(t, src, p.Params{}, p.Params{})
+
+	if plain, err := os.ReadFile(filepath.Join(out, "plain")); err != nil {
+		t.Fatal(err)
+	} else if string(plain) != string(data) {
+		t.Errorf("plain: expected data %q got %q", data, plain)
+	}
+
+	out = filepath.Join(out, "sparse")
+	restored, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != size {
+		t.Fatalf("expected size %d got %d", size, len(restored))
+	}
+	if got := string(restored[size/2 : size/2+len(data)]); got != string(data) {
+		t.Errorf("expected data %q got %q", data, got)
+	}
+	if n := allocated(out); n >= size/2 {
+		t.Errorf("holes not restored: %d bytes allocated", n)
+	}
+}
+
+func TestLongPaths(t *testing.T) {
+	var (
+		src  = filepath.Join(t.TempDir(), "src")
+		deep = src
+		name = strings.Repeat("d", 200)
+		data = []byte("long path data")
+	)
+
+	// Путь длиннее 1023 байт, а путь распаковки
+	// вместе с директорией выхода длиннее PATH_MAX
+	for len(deep)+len(name) < unix.PathMax-20 {
+		deep = filepath.Join(deep, name)
+	}
+	deep = filepath.Join(deep, strings.Repeat("e", unix.PathMax-20-len(deep)))
+	if err := os.MkdirAll(deep, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(deep, "file"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(deep, "file"), filepath.Join(deep, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := filepath.Rel(src, deep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = filepath.Join(roundTrip(t, src, p.Params{}, p.Params{}), out)
+
+	for _, file := range []string{"file", "link"} {
+		path, err := shortPath(filepath.Join(out, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored, err := os.ReadFile(path); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(restored, data) {
+			t.Errorf("'%s': content mismatch", file)
+		}
+	}
+}
+
+// Возвращает путь к path, не превышающий PATH_MAX,
+// переходя в директории по частям
+func shortPath(path string) (string, error) {
+	fd := unix.AT_FDCWD
+	if filepath.IsAbs(path) {
+		fd, _ = unix.Open("/", unix.O_RDONLY|unix.O_DIRECTORY, 0)
+	}
+
+	dir, name := filepath.Split(path)
+	for _, elem := range strings.Split(filepath.Clean(dir), "/") {
+		if elem == "" {
+			continue
+		}
+		next, err := unix.Openat(fd, elem, unix.O_RDONLY|unix.O_DIRECTORY, 0)
+		if fd != unix.AT_FDCWD {
+			unix.Close(fd)
+		}
+		if err != nil {
+			return "", err
+		}
+		fd = next
+	}
+
+	return fmt.Sprintf("/proc/self/fd/%d/%s", fd, name), nil
+}
@@ -0,0 +1,1242 @@
+package arc_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gh0st17/archiver/arc"
+	"github.com/gh0st17/archiver/compressor"
+	"github.com/gh0st17/archiver/filesystem"
+	p "github.com/gh0st17/archiver/params"
+)
+
+// Сжимает src в архив во временной директории и
+// распаковывает его, возвращает путь к распакованному src
+func roundTrip(t *testing.T, src string, cp, dp p.Params) string {
+	t.Helper()
+
+	var (
+		tmp     = t.TempDir()
+		arcPath = filepath.Join(tmp, arcName)
+		out     = filepath.Join(tmp, "out")
+	)
+
+	if cp.Ct == 0 {
+		cp.Ct, cp.Cl = compressor.GZip, -1
+	}
+	compressTo(t, arcPath, src, cp)
+
+	dp.ArcPath, dp.OutputDir = arcPath, out
+	dp.ReplaceAll = true
+	if err := openArc(t, dp).Decompress(); err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(out, filesystem.Clean(src))
+}
+
+// Создает в директории src файлы files с их содержимым
+func writeFiles(t *testing.T, src string, files map[string][]byte) {
+	t.Helper()
+
+	for name, data := range files {
+		path := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Сжимает src в архив arcPath с параметрами cp и
+// возвращает размер архива или суммарный размер томов
+func compressTo(t *testing.T, arcPath, src string, cp p.Params) int64 {
+	t.Helper()
+
+	cp.ArcPath, cp.InputPaths = arcPath, []string{src}
+
+	archive, err := arc.NewArc(cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = archive.Compress(cp.InputPaths); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := []string{arcPath}
+	if cp.VolumeSize > 0 {
+		if paths, err = filepath.Glob(arcPath + ".*"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var size int64
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size += info.Size()
+	}
+	return size
+}
+
+// Открывает архив с параметрами ap
+func openArc(t *testing.T, ap p.Params) *arc.Arc {
+	t.Helper()
+
+	archive, err := arc.NewArc(ap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+// Распаковывает из архива arcPath в out файлы
+// paths или, если они не заданы, весь архив
+func extractTo(t *testing.T, arcPath, out string, paths ...string) {
+	t.Helper()
+
+	dp := p.Params{ArcPath: arcPath, OutputDir: out, ReplaceAll: true, Extract: paths}
+	if err := openArc(t, dp).Decompress(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Сравнивает файлы, распакованные в out из
+// директории src, с ожидаемым содержимым want
+func checkExtracted(t *testing.T, out, src string, want map[string][]byte) {
+	t.Helper()
+
+	for name, data := range want {
+		got, err := os.ReadFile(filepath.Join(out, filesystem.Clean(src), name))
+		if err != nil {
+			t.Error(err)
+		} else if !bytes.Equal(got, data) {
+			t.Errorf("'%s': extracted data differs", name)
+		}
+	}
+}
+
+// Проверяет целостность архива arcPath и то,
+// что все count его файлов не повреждены
+func checkIntegrity(t *testing.T, arcPath string, count int) {
+	t.Helper()
+
+	archive := openArc(t, p.Params{ArcPath: arcPath, IntegTest: true})
+	report := captureStdout(t, archive.IntegrityTest)
+	if strings.Count(report, ": OK") != count {
+		t.Errorf("integrity test:\n%s", report)
+	}
+}
+
+func TestEmptyDirs(t *testing.T) {
+	var (
+		src   = filepath.Join(t.TempDir(), "src")
+		empty = filepath.Join(src, "empty", "nested")
+		mtime = time.Date(2001, 2, 3, 4, 5, 6, 0, time.Local)
+	)
+
+	if err := os.MkdirAll(empty, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "file"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{empty, filepath.Dir(empty), src} {
+		if err := os.Chtimes(dir, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := roundTrip(t, src, p.Params{}, p.Params{})
+
+	for _, dir := range []string{".", "empty", "empty/nested"} {
+		info, err := os.Stat(filepath.Join(out, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.IsDir() {
+			t.Fatalf("'%s' is not a directory", dir)
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("'%s': expected mtime %s got %s", dir, mtime, info.ModTime())
+		}
+	}
+}
+
+func TestModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permission bits are not supported")
+	}
+
+	var (
+		src   = filepath.Join(t.TempDir(), "src")
+		modes = map[string]os.FileMode{
+			"exec":          0755,
+			"private":       0600,
+			"setuid":        0750 | os.ModeSetuid,
+			"dir":           0700 | os.ModeDir,
+			"sticky":        0777 | os.ModeDir | os.ModeSticky,
+			"dir/readonly":  0444,
+			"sticky/setgid": 0640 | os.ModeSetgid,
+		}
+	)
+
+	for _, name := range []string{"dir", "sticky"} {
+		if err := os.MkdirAll(filepath.Join(src, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name := range modes {
+		path := filepath.Join(src, name)
+		if !modes[name].IsDir() {
+			if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for name, mode := range modes { // Директории в конце
+		if err := os.Chmod(filepath.Join(src, name), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := roundTrip(t, src, p.Params{}, p.Params{})
+
+	for name, mode := range modes {
+		info, err := os.Lstat(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode() != mode {
+			t.Errorf("'%s': expected mode %s got %s", name, mode, info.Mode())
+		}
+	}
+
+	out = roundTrip(t, src, p.Params{}, p.Params{NoPerm: true})
+	if info, err := os.Stat(filepath.Join(out, "setuid")); err != nil {
+		t.Fatal(err)
+	} else if info.Mode()&os.ModeSetuid != 0 {
+		t.Error("mode restored with NoPerm")
+	}
+}
+
+func TestNanoTimes(t *testing.T) {
+	var (
+		src   = filepath.Join(t.TempDir(), "src")
+		file  = filepath.Join(src, "file")
+		atime = time.Date(2002, 3, 4, 5, 6, 7, 123456789, time.Local)
+		mtime = time.Date(2003, 4, 5, 6, 7, 8, 987654321, time.Local)
+	)
+
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{file, src} {
+		if err := os.Chtimes(path, atime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := roundTrip(t, src, p.Params{}, p.Params{})
+
+	for _, path := range []string{filepath.Join(out, "file"), out} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.ModTime().Equal(mtime) {
+			t.Errorf("'%s': expected mtime %s got %s", path, mtime, info.ModTime())
+		}
+	}
+}
+
+func TestSelectiveExtract(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+
+	for _, dir := range []string{"keep/nested", "skip"} {
+		if err := os.MkdirAll(filepath.Join(src, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"keep/nested/file", "skip/file", "single"} {
+		if err := os.WriteFile(filepath.Join(src, file), []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := roundTrip(t, src, p.Params{}, p.Params{
+		Extract: []string{filepath.Join(src, "keep"), filepath.Join(src, "single")},
+	})
+
+	for _, file := range []string{"keep/nested/file", "single"} {
+		if data, err := os.ReadFile(filepath.Join(out, file)); err != nil {
+			t.Error(err)
+		} else if string(data) != file {
+			t.Errorf("'%s': unexpected content %q", file, data)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(out, "skip")); err == nil {
+		t.Error("'skip' should not be extracted")
+	}
+}
+
+func TestDamagedIndex(t *testing.T) {
+	var (
+		tmp     = t.TempDir()
+		src     = filepath.Join(tmp, "src")
+		arcPath = filepath.Join(tmp, arcName)
+		want    = map[string][]byte{
+			filepath.Join("dir", "a"): []byte("first file"),
+			filepath.Join("dir", "b"): []byte("second file"),
+			"c":                       []byte("third file"),
+		}
+	)
+
+	writeFiles(t, src, want)
+	compressTo(t, arcPath, src, p.Params{})
+	orig, err := os.ReadFile(arcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Завершающая запись: смещение индекса,
+	// CRC индекса и сигнатура
+	trailer := len(orig) - 16
+	damage := map[string]func(raw []byte){
+		"crc": func(raw []byte) { raw[trailer+8] ^= 0xFF },
+		"offset": func(raw []byte) {
+			binary.LittleEndian.PutUint64(raw[trailer:], uint64(len(raw)))
+		},
+		"index": func(raw []byte) {
+			raw[binary.LittleEndian.Uint64(raw[trailer:])] ^= 0xFF
+		},
+	}
+
+	for name, f := range damage {
+		raw := append([]byte{}, orig...)
+		f(raw)
+		if err = os.WriteFile(arcPath, raw, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// Список и распаковка выполняются по
+		// полному сканированию архива
+		list := captureStdout(t, openArc(t, p.Params{ArcPath: arcPath, PrintList: true}).ViewList)
+		if !strings.Contains(list, "Индекс архива поврежден") {
+			t.Errorf("%s: damaged index is not reported:\n%s", name, list)
+		}
+		for file := range want {
+			if !strings.Contains(list, file) {
+				t.Errorf("%s: '%s' is not listed:\n%s", name, file, list)
+			}
+		}
+
+		out := filepath.Join(tmp, name)
+		extractTo(t, arcPath, out, filepath.Join(src, "dir", "b"))
+		checkExtracted(t, out, src, map[string][]byte{filepath.Join("dir", "b"): want[filepath.Join("dir", "b")]})
+		if _, err = os.Stat(filepath.Join(out, filesystem.Clean(src), "c")); err == nil {
+			t.Errorf("%s: unselected file is extracted", name)
+		}
+
+		out = filepath.Join(tmp, name+"-all")
+		extractTo(t, arcPath, out)
+		checkExtracted(t, out, src, want)
+	}
+}
+
+// Создает архив формата 1.0.x с файлом path и
+// содержимым data без сжатия
+func writeLegacyArc(t *testing.T, arcPath, path string, data []byte, mtime time.Time) {
+	t.Helper()
+
+	var (
+		buf bytes.Buffer
+		le  = binary.LittleEndian
+	)
+
+	write := func(v any) {
+		if err := binary.Write(&buf, le, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(uint16(0x5717))
+	write(byte(compressor.Nop))
+	write(byte(1)) // Тип заголовка файла
+	write(int16(len(path)))
+	write([]byte(path))
+	write(mtime.Unix()) // Время модификации
+	write(mtime.Unix()) // Время доступа
+	write(int64(len(data)))
+	write(int64(len(data)))
+	write(data)
+	write(int64(-1))
+	write(crc32.Checksum(data, crc32.MakeTable(crc32.Koopman)))
+
+	if err := os.WriteFile(arcPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLegacyFormat(t *testing.T) {
+	var (
+		tmp     = t.TempDir()
+		arcPath = filepath.Join(tmp, arcName)
+		out     = filepath.Join(tmp, "out")
+		data    = []byte("legacy data")
+		mtime   = time.Date(2004, 5, 6, 7, 8, 9, 0, time.Local)
+	)
+
+	writeLegacyArc(t, arcPath, "dir/file", data, mtime)
+	checkIntegrity(t, arcPath, 1)
+	extractTo(t, arcPath, out)
+
+	path := filepath.Join(out, "dir", "file")
+	if restored, err := os.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if string(restored) != string(data) {
+		t.Errorf("expected data %q got %q", data, restored)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if !info.ModTime().Equal(mtime) {
+		t.Errorf("expected mtime %s got %s", mtime, info.ModTime())
+	}
+}
+
+func TestCodecRules(t *testing.T) {
+	var (
+		tmp     = t.TempDir()
+		src     = filepath.Join(tmp, "src")
+		arcPath = filepath.Join(tmp, arcName)
+		out     = filepath.Join(tmp, "out")
+		data    = bytes.Repeat([]byte("compressible data "), 100000)
+		files   = map[string][]byte{
+			"media.jpg": data, "text.txt": data, "sub/code.go": data, "other": data,
+		}
+	)
+
+	writeFiles(t, src, files)
+	compressTo(t, arcPath, src, p.Params{
+		Ct: compressor.GZip,
+		Cl: -1,
+		CodecRules: []p.CodecRule{
+			{Pattern: "*.JPG", Ct: compressor.Nop},
+			{Pattern: "*.txt", Ct: compressor.Flate, Cl: 9},
+			{Pattern: "*/sub/*", Ct: compressor.LempelZivWelch, Cl: -1},
+		},
+	})
+	extractTo(t, arcPath, out)
+	checkExtracted(t, out, src, files)
+}
+
+func TestAdaptiveBlocks(t *testing.T) {
+	var (
+		src    = filepath.Join(t.TempDir(), "src")
+		random = make([]byte, 3*1048576+12345)
+		text   = bytes.Repeat([]byte("compressible data "), 100000)
+		files  = map[string][]byte{"random.bin": random, "text.txt": text}
+		sizes  []int64
+	)
+
+	rand.New(rand.NewSource(17)).Read(random)
+	writeFiles(t, src, files)
+
+	for _, mode := range []p.Params{{}, {Adaptive: true}, {Sample: true}} {
+		var (
+			tmp     = t.TempDir()
+			arcPath = filepath.Join(tmp, arcName)
+			out     = filepath.Join(tmp, "out")
+		)
+
+		mode.Ct, mode.Cl = compressor.GZip, 9
+		sizes = append(sizes, compressTo(t, arcPath, src, mode))
+		checkIntegrity(t, arcPath, len(files))
+		extractTo(t, arcPath, out)
+		checkExtracted(t, out, src, files)
+	}
+	// Случайные данные не должны увеличиваться при сжатии
+	for i, mode := range []string{"adapt", "sample"} {
+		if sizes[i+1] >= sizes[0] {
+			t.Errorf("%s: archive size %d, without mode %d", mode, sizes[i+1], sizes[0])
+		}
+	}
+}
+
+// Перехватывает стандартный вывод при выполнении f
+func captureStdout(t *testing.T, f func() error) string {
+	t.Helper()
+
+	out, err := captureRun(t, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return out
+}
+
+// Перехватывает стандартный вывод при выполнении f
+// и возвращает его вместе с ошибкой f
+func captureRun(t *testing.T, f func() error) (string, error) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+
+	out := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- data
+	}()
+
+	err = f()
+	os.Stdout = stdout
+	w.Close()
+
+	return string(<-out), err
+}
+
+// Длина заголовка блока без флага: длина (8) и CRC (4)
+const blockHeaderLen = 12
+
+func TestDigest(t *testing.T) {
+	var (
+		tmp     = t.TempDir()
+		src     = filepath.Join(tmp, "src")
+		arcPath = filepath.Join(tmp, arcName)
+		out     = filepath.Join(tmp, "out")
+		blockA  = bytes.Repeat([]byte{'a'}, 1048576)
+		blockB  = bytes.Repeat([]byte{'b'}, 1048576)
+		dp      = p.Params{ArcPath: arcPath, OutputDir: out, ReplaceAll: true}
+	)
+
+	writeFiles(t, src, map[string][]byte{
+		"blocks": bytes.Join([][]byte{blockA, blockB}, nil),
+		"empty":  nil,
+	})
+
+	for _, digest := range []p.DigestType{p.DigestCRC32C, p.DigestSHA256} {
+		compressTo(t, arcPath, src, p.Params{Ct: compressor.Nop, Digest: digest, ReplaceAll: true})
+
+		archive := openArc(t, dp)
+		if report := captureStdout(t, archive.IntegrityTest); strings.Contains(report, "поврежден") {
+			t.Errorf("digest %d: intact archive reported as damaged:\n%s", digest, report)
+		}
+		if report := captureStdout(t, archive.Decompress); strings.Contains(report, "не совпадает") {
+			t.Errorf("digest %d: intact archive reported as damaged:\n%s", digest, report)
+		}
+	}
+
+	// Перестановка блоков вместе с их длиной и CRC не меняет
+	// CRC сжатых данных, но меняет контрольную сумму содержимого
+	raw, err := os.ReadFile(arcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := bytes.Index(raw, blockA), bytes.Index(raw, blockB)
+	if a < blockHeaderLen || b < blockHeaderLen {
+		t.Fatal("blocks not found in archive")
+	}
+	recA := append([]byte{}, raw[a-blockHeaderLen:a+len(blockA)]...)
+	recB := append([]byte{}, raw[b-blockHeaderLen:b+len(blockB)]...)
+	copy(raw[a-blockHeaderLen:], recB)
+	copy(raw[b-blockHeaderLen:], recA)
+	if err = os.WriteFile(arcPath, raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := openArc(t, dp)
+	if report := captureStdout(t, archive.IntegrityTest); !strings.Contains(report, "blocks: Файл поврежден") {
+		t.Errorf("reordered blocks are not detected by integrity test:\n%s", report)
+	}
+	if report := captureStdout(t, archive.Decompress); !strings.Contains(report, "контрольная сумма содержимого не совпадает") {
+		t.Errorf("reordered blocks are not detected on extraction:\n%s", report)
+	}
+}
+
+func TestDamagedBlocks(t *testing.T) {
+	var (
+		tmp     = t.TempDir()
+		src     = filepath.Join(tmp, "src")
+		arcPath = filepath.Join(tmp, arcName)
+		blockA  = bytes.Repeat([]byte{'a'}, 1048576)
+		blockB  = bytes.Repeat([]byte{'b'}, 1048576)
+		blockC  = bytes.Repeat([]byte{'c'}, 1000)
+	)
+
+	writeFiles(t, src, map[string][]byte{"log": bytes.Join([][]byte{blockA, blockB, blockC}, nil)})
+	compressTo(t, arcPath, src, p.Params{Ct: compressor.Nop})
+
+	// Повреждение одного байта среднего блока
+	raw, err := os.ReadFile(arcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := bytes.Index(raw, blockB)
+	if b < 0 {
+		t.Fatal("block not found in archive")
+	}
+	raw[b+100] = 'x'
+	if err = os.WriteFile(arcPath, raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const damaged = "повреждены байты 1048576-2097151"
+	archive := openArc(t, p.Params{ArcPath: arcPath})
+	if report := captureStdout(t, archive.IntegrityTest); !strings.Contains(report, damaged) {
+		t.Errorf("damaged block is not reported by integrity test:\n%s", report)
+	}
+
+	tests := []struct {
+		name  string
+		mode  p.DamageMode
+		integ bool
+		want  []byte
+	}{
+		{"zero", p.DamageZero, false, bytes.Join([][]byte{blockA, make([]byte, len(blockB)), blockC}, nil)},
+		{"trunc", p.DamageTruncate, false, blockA},
+		{"skip", p.DamageSkip, false, bytes.Join([][]byte{blockA, blockC}, nil)},
+		{"xinteg", p.DamageZero, true, bytes.Join([][]byte{blockA, make([]byte, len(blockB)), blockC}, nil)},
+	}
+
+	for _, tt := range tests {
+		out := filepath.Join(tmp, tt.name)
+		dp := p.Params{
+			ArcPath:    arcPath,
+			OutputDir:  out,
+			Damaged:    tt.mode,
+			XIntegTest: tt.integ,
+			ReplaceAll: true,
+		}
+		if report := captureStdout(t, openArc(t, dp).Decompress); !strings.Contains(report, damaged) {
+			t.Errorf("%s: damaged block is not reported:\n%s", tt.name, report)
+		}
+		checkExtracted(t, out, src, map[string][]byte{"log": tt.want})
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	var (
+		tmp     = t.TempDir()
+		src     = filepath.Join(tmp, "src")
+		arcPath = filepath.Join(tmp, arcName)
+		data    = make([]byte, 3<<20)
+	)
+
+	rand.New(rand.NewSource(1)).Read(data)
+	writeFiles(t, src, map[string][]byte{"data": data})
+	compressTo(t, arcPath, src, p.Params{Ct: compressor.Nop, Recovery: 5})
+
+	corrupt := func(from, to int) {
+		t.Helper()
+		raw, err := os.ReadFile(arcPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := from; i < to; i++ {
+			raw[i] ^= 0x55
+		}
+		if err = os.WriteFile(arcPath, raw, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	integrity := func() string {
+		t.Helper()
+		report, _ := captureRun(t, openArc(t, p.Params{ArcPath: arcPath}).IntegrityTest)
+		return report
+	}
+
+	repair := func() error {
+		t.Helper()
+		_, err := captureRun(t, openArc(t, p.Params{ArcPath: arcPath, Repair: true}).Repair)
+		return err
+	}
+
+	if report := integrity(); !strings.Contains(report, "повреждений нет") {
+		t.Errorf("intact archive reported as damaged:\n%s", report)
+	}
+
+	// Повреждение 40 КБ данных и заголовка архива
+	corrupt(1<<20, 1<<20+40<<10)
+	corrupt(4, 5)
+	if err := repair(); err != nil {
+		t.Fatalf("repair failed: %v", err)
+	}
+	if report := integrity(); !strings.Contains(report, "повреждений нет") ||
+		strings.Contains(report, "поврежден\n") {
+		t.Errorf("repaired archive reported as damaged:\n%s", report)
+	}
+
+	out := filepath.Join(tmp, "out")
+	extractTo(t, arcPath, out)
+	checkExtracted(t, out, src, map[string][]byte{"data": data})
+
+	// Протяженное повреждение превышает избыточность
+	corrupt(1<<20, 2<<20)
+	if report := integrity(); !strings.Contains(report, "нельзя исправить") {
+		t.Errorf("unrepairable damage is not reported:\n%s", report)
+	}
+	if err := repair(); err == nil {
+		t.Error("repair of unrepairable archive succeeded")
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	var (
+		tmp      = t.TempDir()
+		src      = filepath.Join(tmp, "src")
+		arcPath  = filepath.Join(tmp, arcName)
+		passFile = filepath.Join(tmp, "pass")
+		secret   = bytes.Repeat([]byte("secret data "), 100)[:1000]
+	)
+
+	files := map[string][]byte{
+		"x": append([]byte("x"), secret[1:]...),
+		"y": append([]byte("y"), secret[1:]...),
+	}
+	writeFiles(t, src, files)
+	if err := os.WriteFile(passFile, []byte("correct horse\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ARC_TEST_PASS", "correct horse")
+
+	cp := p.Params{Ct: compressor.Nop, Encrypt: true, PassEnv: "ARC_TEST_PASS"}
+	compressTo(t, arcPath, src, cp)
+
+	raw, err := os.ReadFile(arcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, secret[1:100]) {
+		t.Error("archive contains plaintext")
+	}
+
+	// Пароль из файла
+	out := filepath.Join(tmp, "out")
+	dp := p.Params{ArcPath: arcPath, OutputDir: out, PassFile: passFile, ReplaceAll: true}
+	if err = openArc(t, dp).Decompress(); err != nil {
+		t.Fatal(err)
+	}
+	checkExtracted(t, out, src, files)
+
+	t.Setenv("ARC_TEST_PASS", "wrong")
+	if _, err = arc.NewArc(p.Params{ArcPath: arcPath, PassEnv: "ARC_TEST_PASS"}); err != arc.ErrWrongPassphrase {
+		t.Errorf("wrong passphrase: got %v, want %v", err, arc.ErrWrongPassphrase)
+	}
+
+	// Перестановка блоков файлов вместе с длиной и CRC
+	// обнаруживается при расшифровке
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(secret)+28))
+	x := bytes.Index(raw, length[:])
+	y := x + 1 + bytes.Index(raw[x+1:], length[:])
+	if x < 0 || y <= x {
+		t.Fatal("blocks not found in archive")
+	}
+	recLen := 8 + 4 + len(secret) + 28
+	recX := append([]byte{}, raw[x:x+recLen]...)
+	copy(raw[x:], raw[y:y+recLen])
+	copy(raw[y:], recX)
+	if err = os.WriteFile(arcPath, raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := openArc(t, dp)
+	if report := captureStdout(t, archive.IntegrityTest); !strings.Contains(report, "x: Файл поврежден") {
+		t.Errorf("tampering is not detected by integrity test:\n%s", report)
+	}
+	report, err := captureRun(t, archive.Decompress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(report, arc.ErrDecrypt.Error()) {
+		t.Errorf("tampering is not detected on extraction:\n%s", report)
+	}
+
+	// Отброшенный с конца файла блок обнаруживается,
+	// даже если CRC файла исправлен
+	big := filepath.Join(tmp, "big")
+	tail := 1 << 19
+	data := make([]byte, 2<<20+tail)
+	rand.New(rand.NewSource(1)).Read(data)
+	writeFiles(t, big, map[string][]byte{"file": data})
+	t.Setenv("ARC_TEST_PASS", "correct horse")
+	cp.ReplaceAll = true
+	compressTo(t, arcPath, big, cp)
+	if raw, err = os.ReadFile(arcPath); err != nil {
+		t.Fatal(err)
+	}
+
+	binary.LittleEndian.PutUint64(length[:], uint64(tail+28))
+	last := bytes.Index(raw, length[:])
+	if last < 0 {
+		t.Fatal("last block not found in archive")
+	}
+	recLen = 8 + 4 + tail + 28
+	blockCRC := binary.LittleEndian.Uint32(raw[last+8:])
+	fileCRC := binary.LittleEndian.Uint32(raw[last+recLen+8:])
+	binary.LittleEndian.PutUint32(raw[last+recLen+8:], fileCRC^blockCRC)
+	raw = append(raw[:last], raw[last+recLen:]...)
+	if err = os.WriteFile(arcPath, raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive = openArc(t, dp)
+	if report := captureStdout(t, archive.IntegrityTest); !strings.Contains(report, "file: Файл поврежден") {
+		t.Errorf("truncation is not detected by integrity test:\n%s", report)
+	}
+	if report, err = captureRun(t, archive.Decompress); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(report, arc.ErrDecrypt.Error()) {
+		t.Errorf("truncation is not detected on extraction:\n%s", report)
+	}
+
+	// С флагом -xinteg ошибка расшифровки не прерывает
+	// распаковку: файл восстанавливается частично
+	dp.XIntegTest = true
+	if report, err = captureRun(t, openArc(t, dp).Decompress); err != nil {
+		t.Fatalf("xinteg: %v", err)
+	}
+	if !strings.Contains(report, arc.ErrDecrypt.Error()) {
+		t.Errorf("xinteg: truncation is not detected on extraction:\n%s", report)
+	}
+}
+
+func TestHiddenHeaders(t *testing.T) {
+	var (
+		tmp     = t.TempDir()
+		src     = filepath.Join(tmp, "src")
+		arcPath = filepath.Join(tmp, arcName)
+		out     = filepath.Join(tmp, "out")
+		name    = "hidden-name.txt"
+		data    = bytes.Repeat([]byte("hidden data "), 500)
+	)
+
+	writeFiles(t, src, map[string][]byte{name: data})
+	t.Setenv("ARC_TEST_PASS", "correct horse")
+
+	cp := p.Params{
+		Ct:         compressor.GZip,
+		EncHeaders: true,
+		Recovery:   10,
+		PassEnv:    "ARC_TEST_PASS",
+	}
+	compressTo(t, arcPath, src, cp)
+
+	raw, err := os.ReadFile(arcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("hidden-name")) {
+		t.Error("archive contains file name")
+	}
+
+	// Без пароля печатается только признак шифрования
+	t.Setenv("ARC_TEST_EMPTY", "")
+	lp := p.Params{ArcPath: arcPath, PrintList: true, PassEnv: "ARC_TEST_EMPTY"}
+	list, err := captureRun(t, openArc(t, lp).ViewList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(list, name) || !strings.Contains(list, "Архив зашифрован") {
+		t.Errorf("list without passphrase:\n%s", list)
+	}
+
+	lp.PassEnv = "ARC_TEST_PASS"
+	if list, err = captureRun(t, openArc(t, lp).ViewList); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(list, name) {
+		t.Errorf("list with passphrase:\n%s", list)
+	}
+
+	dp := p.Params{ArcPath: arcPath, OutputDir: out, PassEnv: "ARC_TEST_PASS"}
+	report := captureStdout(t, openArc(t, dp).IntegrityTest)
+	if !strings.Contains(report, name+": OK") || !strings.Contains(report, "повреждений нет") {
+		t.Errorf("integrity test:\n%s", report)
+	}
+
+	dp.Extract = []string{filepath.Join(filesystem.Clean(src), name)}
+	if err = openArc(t, dp).Decompress(); err != nil {
+		t.Fatal(err)
+	}
+	checkExtracted(t, out, src, map[string][]byte{name: data})
+
+	// Измененный индекс заменяется полным сканированием
+	cp.Recovery, cp.ReplaceAll = 0, true
+	compressTo(t, arcPath, src, cp)
+	orig, err := os.ReadFile(arcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tamper := func(off int) {
+		t.Helper()
+		raw := append([]byte{}, orig...)
+		raw[off] ^= 0x01
+		if err := os.WriteFile(arcPath, raw, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tamper(len(orig) - sha256.Size - 8) // CRC индекса в завершающей записи
+	if list, err = captureRun(t, openArc(t, lp).ViewList); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(list, "Индекс архива поврежден") || !strings.Contains(list, name) {
+		t.Errorf("list with damaged index:\n%s", list)
+	}
+
+	// Измененный заголовок элемента обнаруживается при его
+	// чтении. Заголовки находятся по открытым заголовкам
+	// такого же архива, в котором перед заголовком не
+	// пишутся его длина и код аутентификации
+	plain := filepath.Join(tmp, "plain.arc")
+	compressTo(t, plain, src, p.Params{Ct: compressor.GZip, Encrypt: true, PassEnv: "ARC_TEST_PASS"})
+	if raw, err = os.ReadFile(plain); err != nil {
+		t.Fatal(err)
+	}
+	path := bytes.Index(raw, []byte(filesystem.Clean(src)))
+	if path < 0 {
+		t.Fatal("header not found in archive")
+	}
+	tamper(path + 4 + sha256.Size)
+
+	dp.Extract = nil
+	dp.ReplaceAll = true
+	archive := openArc(t, dp)
+	if _, err = captureRun(t, archive.IntegrityTest); err == nil ||
+		!strings.Contains(err.Error(), arc.ErrHeadersAuth.Error()) {
+		t.Errorf("integrity test of tampered headers: %v", err)
+	}
+	if _, err = captureRun(t, archive.Decompress); err == nil ||
+		!strings.Contains(err.Error(), arc.ErrHeadersAuth.Error()) {
+		t.Errorf("decompress of tampered headers: %v", err)
+	}
+}
+
+func TestRecipients(t *testing.T) {
+	var (
+		tmp     = t.TempDir()
+		src     = filepath.Join(tmp, "src")
+		arcPath = filepath.Join(tmp, arcName)
+		out     = filepath.Join(tmp, "out")
+		data    = bytes.Repeat([]byte("recipient data "), 500)
+		keys    = make([]string, 3)
+	)
+
+	writeFiles(t, src, map[string][]byte{"file": data})
+	for i := range keys {
+		keys[i] = filepath.Join(tmp, "key"+string(rune('a'+i)))
+		if _, err := captureRun(t, func() error { return arc.GenerateKeys(keys[i]) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := arc.GenerateKeys(keys[0]); err == nil {
+		t.Error("existing key is overwritten")
+	}
+
+	// Для создания архива нужны только открытые ключи
+	compressTo(t, arcPath, src, p.Params{
+		Ct:         compressor.GZip,
+		Recipients: []string{keys[0] + ".pub", keys[1] + ".pub"},
+		EncHeaders: true,
+	})
+
+	dp := p.Params{ArcPath: arcPath, OutputDir: out}
+	if _, err := arc.NewArc(dp); err != arc.ErrNoIdentity {
+		t.Errorf("no identity: got %v, want %v", err, arc.ErrNoIdentity)
+	}
+	dp.Identities = []string{keys[2]}
+	if _, err := arc.NewArc(dp); err != arc.ErrWrongIdentity {
+		t.Errorf("wrong identity: got %v, want %v", err, arc.ErrWrongIdentity)
+	}
+	dp.Identities = []string{keys[0] + ".pub"}
+	if _, err := arc.NewArc(dp); err == nil {
+		t.Error("public key is accepted as identity")
+	}
+
+	lp := p.Params{ArcPath: arcPath, PrintList: true}
+	if list, err := captureRun(t, openArc(t, lp).ViewList); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(list, "Архив зашифрован") {
+		t.Errorf("list without identity:\n%s", list)
+	}
+
+	// Любой из получателей может расшифровать архив
+	for _, key := range keys[:2] {
+		dp.Identities = []string{key}
+		dp.ReplaceAll = true
+		if err := openArc(t, dp).Decompress(); err != nil {
+			t.Fatal(err)
+		}
+		checkExtracted(t, out, src, map[string][]byte{"file": data})
+	}
+}
+
+func TestSignature(t *testing.T) {
+	var (
+		tmp      = t.TempDir()
+		src      = filepath.Join(tmp, "src")
+		arcPath  = filepath.Join(tmp, arcName)
+		unsigned = filepath.Join(tmp, "unsigned.arc")
+		out      = filepath.Join(tmp, "out")
+		data     = bytes.Repeat([]byte("release data "), 500)
+		keys     = []string{filepath.Join(tmp, "a"), filepath.Join(tmp, "b")}
+	)
+
+	writeFiles(t, src, map[string][]byte{"file": data})
+	for _, key := range keys {
+		if _, err := captureRun(t, func() error { return arc.GenerateSignKeys(key) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for path, sign := range map[string]string{arcPath: keys[0], unsigned: ""} {
+		compressTo(t, path, src, p.Params{Ct: compressor.Nop, Sign: sign, Recovery: 5})
+	}
+
+	expect := func(path string, vp p.Params, want error) {
+		t.Helper()
+
+		vp.ArcPath, vp.OutputDir, vp.ReplaceAll = path, out, true
+		archive := openArc(t, vp)
+		run := archive.Decompress
+		if vp.Verify {
+			run = archive.Verify
+		}
+		if _, err := captureRun(t, run); want == nil && err != nil {
+			t.Errorf("%s: %v", filepath.Base(path), err)
+		} else if want != nil && (err == nil || !strings.Contains(err.Error(), want.Error())) {
+			t.Errorf("%s: got %v, want %v", filepath.Base(path), err, want)
+		}
+	}
+
+	expect(arcPath, p.Params{Verify: true}, nil)
+	expect(arcPath, p.Params{Signers: []string{keys[0] + ".pub"}}, nil)
+	expect(arcPath, p.Params{Signers: []string{keys[1] + ".pub"}}, arc.ErrUntrustedSigner)
+	expect(unsigned, p.Params{Verify: true}, arc.ErrNotSigned)
+	expect(unsigned, p.Params{RequireSig: true, Signers: []string{keys[0] + ".pub"}}, arc.ErrNotSigned)
+	expect(unsigned, p.Params{}, nil)
+
+	checkExtracted(t, out, src, map[string][]byte{"file": data})
+
+	// Изменение данных файла нарушает подпись
+	raw, err := os.ReadFile(arcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[bytes.Index(raw, data[:100])+50] ^= 1
+	if err = os.WriteFile(arcPath, raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+	expect(arcPath, p.Params{Verify: true}, arc.ErrBadSignature)
+	expect(arcPath, p.Params{RequireSig: true, Signers: []string{keys[0] + ".pub"}}, arc.ErrBadSignature)
+}
+
+func TestVolumes(t *testing.T) {
+	var (
+		tmp     = t.TempDir()
+		src     = filepath.Join(tmp, "src")
+		arcPath = filepath.Join(tmp, arcName)
+		out     = filepath.Join(tmp, "out")
+		data    = make([]byte, 300000)
+		volSize = int64(64 << 10)
+	)
+
+	rand.New(rand.NewSource(1)).Read(data)
+	writeFiles(t, src, map[string][]byte{"file": data})
+	compressTo(t, arcPath, src, p.Params{Ct: compressor.Nop, VolumeSize: volSize, Recovery: 10})
+
+	vols, err := filepath.Glob(arcPath + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vols) < 5 {
+		t.Fatalf("expected at least 5 volumes, got %v", vols)
+	}
+	if _, err = os.Stat(arcPath); err == nil {
+		t.Error("archive file is created besides volumes")
+	}
+	for _, vol := range vols[:len(vols)-1] {
+		if info, err := os.Stat(vol); err != nil {
+			t.Fatal(err)
+		} else if info.Size() != volSize {
+			t.Errorf("%s: size %d, want %d", filepath.Base(vol), info.Size(), volSize)
+		}
+	}
+
+	// Набор томов открывается по пути архива и по любому тому
+	for _, path := range []string{arcPath, vols[2]} {
+		lp := p.Params{ArcPath: path, PrintList: true}
+		if list, err := captureRun(t, openArc(t, lp).ViewList); err != nil {
+			t.Fatal(err)
+		} else if !strings.Contains(list, "file") {
+			t.Errorf("%s: list:\n%s", filepath.Base(path), list)
+		}
+	}
+
+	dp := p.Params{ArcPath: vols[0], OutputDir: out, ReplaceAll: true}
+	archive := openArc(t, dp)
+	report := captureStdout(t, archive.IntegrityTest)
+	if !strings.Contains(report, "file: OK") || !strings.Contains(report, "повреждений нет") {
+		t.Errorf("integrity test:\n%s", report)
+	}
+	if err = archive.Decompress(); err != nil {
+		t.Fatal(err)
+	}
+	checkExtracted(t, out, src, map[string][]byte{"file": data})
+
+	// Отсутствие тома в середине и в конце набора
+	for _, vol := range []string{vols[2], vols[len(vols)-1]} {
+		if err = os.Rename(vol, vol+".bak"); err != nil {
+			t.Fatal(err)
+		}
+		_, err = arc.NewArc(dp)
+		if err == nil || !strings.Contains(err.Error(), arc.ErrVolumeMissing(vol).Error()) {
+			t.Errorf("missing %s: got %v", filepath.Base(vol), err)
+		}
+		if err = os.Rename(vol+".bak", vol); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSolid(t *testing.T) {
+	var (
+		tmp  = t.TempDir()
+		src  = filepath.Join(tmp, "src")
+		big  = make([]byte, 100000)
+		want = map[string][]byte{"empty": nil}
+	)
+
+	rand.New(rand.NewSource(1)).Read(big)
+	want["big"] = big
+	for i := 0; i < 200; i++ {
+		name := filepath.Join("dir", "file"+strconv.Itoa(i)+".json")
+		want[name] = []byte(`{"id": ` + strconv.Itoa(i) + `, "tags": ["alpha", "beta"]}`)
+	}
+	writeFiles(t, src, want)
+
+	cp := p.Params{Digest: p.DigestSHA256}
+	plain := compressTo(t, filepath.Join(tmp, "plain.arc"), src, cp)
+	cp.SolidSize = 64 << 10
+	solid := filepath.Join(tmp, arcName)
+	if size := compressTo(t, solid, src, cp); size >= plain {
+		t.Errorf("solid archive %d bytes, plain %d bytes", size, plain)
+	}
+
+	out := filepath.Join(tmp, "out")
+	extractTo(t, solid, out)
+	checkExtracted(t, out, src, want)
+
+	// Файл из середины блока распаковывается отдельно
+	single := filepath.Join("dir", "file150.json")
+	out = filepath.Join(tmp, "single")
+	extractTo(t, solid, out, filepath.Join(src, single))
+	checkExtracted(t, out, src, map[string][]byte{single: want[single]})
+	if _, err := os.Stat(filepath.Join(out, filesystem.Clean(src), "dir", "file15.json")); err == nil {
+		t.Error("unselected file of the block is extracted")
+	}
+
+	checkIntegrity(t, solid, len(want))
+}
+
+func TestDedup(t *testing.T) {
+	var (
+		tmp  = t.TempDir()
+		src  = filepath.Join(tmp, "src")
+		base = make([]byte, 600000)
+	)
+
+	rand.New(rand.NewSource(1)).Read(base)
+	inserted := append(append(append([]byte{}, base[:300000]...), "inserted"...), base[300000:]...)
+	want := map[string][]byte{
+		"base":     base,
+		"inserted": inserted,
+		"copy":     base,
+		"empty":    nil,
+	}
+	writeFiles(t, src, want)
+
+	cp := p.Params{Digest: p.DigestSHA256}
+	plain := compressTo(t, filepath.Join(tmp, "plain.arc"), src, cp)
+	cp.Dedup = true
+	dedup := filepath.Join(tmp, arcName)
+	if size := compressTo(t, dedup, src, cp); size*2 >= plain {
+		t.Errorf("dedup archive %d bytes, plain %d bytes", size, plain)
+	}
+
+	out := filepath.Join(tmp, "out")
+	extractTo(t, dedup, out)
+	checkExtracted(t, out, src, want)
+
+	// Файл собирается из фрагментов другого файла
+	out = filepath.Join(tmp, "single")
+	extractTo(t, dedup, out, filepath.Join(src, "inserted"))
+	checkExtracted(t, out, src, map[string][]byte{"inserted": inserted})
+
+	checkIntegrity(t, dedup, len(want))
+}
+
+func TestDupFiles(t *testing.T) {
+	var (
+		tmp     = t.TempDir()
+		src     = filepath.Join(tmp, "src")
+		arcPath = filepath.Join(tmp, arcName)
+		data    = make([]byte, 300000)
+	)
+
+	rand.New(rand.NewSource(1)).Read(data)
+	other := append(append([]byte{}, data[:len(data)-1]...), 'x')
+	want := map[string][]byte{
+		filepath.Join("a", "lib.js"): data,
+		filepath.Join("b", "lib.js"): data,
+		filepath.Join("c", "lib.js"): data,
+		filepath.Join("c", "other"):  other,
+		filepath.Join("c", "empty"):  nil,
+		filepath.Join("d", "empty"):  nil,
+	}
+	writeFiles(t, src, want)
+
+	// Данные повторных файлов не хранятся
+	cp := p.Params{Digest: p.DigestSHA256, DupFiles: true}
+	if size := compressTo(t, arcPath, src, cp); size >= int64(3*len(data)) {
+		t.Errorf("archive %d bytes, file data %d bytes", size, len(data))
+	}
+
+	out := filepath.Join(tmp, "out")
+	extractTo(t, arcPath, out)
+	checkExtracted(t, out, src, want)
+
+	// Повторный файл распаковывается без первого файла
+	single := filepath.Join("c", "lib.js")
+	out = filepath.Join(tmp, "single")
+	extractTo(t, arcPath, out, filepath.Join(src, single))
+	checkExtracted(t, out, src, map[string][]byte{single: data})
+
+	checkIntegrity(t, arcPath, len(want))
+}
@@ -0,0 +1,187 @@
+//go:build !windows
+// +build !windows
+
+package arc_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	p "github.com/gh0st17/archiver/params"
+	"golang.org/x/sys/unix"
+)
+
+func TestOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing owner requires root")
+	}
+
+	var (
+		src  = filepath.Join(t.TempDir(), "src")
+		file = filepath.Join(src, "file")
+	)
+
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Lchown(file, 12345, 23456); err != nil {
+		t.Fatal(err)
+	}
+
+	checkOwner := func(out string, uid, gid int) {
+		t.Helper()
+		info, err := os.Lstat(filepath.Join(out, "file"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stat := info.Sys().(*syscall.Stat_t)
+		if int(stat.Uid) != uid || int(stat.Gid) != gid {
+			t.Errorf("expected owner %d:%d got %d:%d", uid, gid, stat.Uid, stat.Gid)
+		}
+	}
+
+	out := roundTrip(t, src, p.Params{}, p.Params{Owner: p.OwnerNum})
+	checkOwner(out, 12345, 23456)
+
+	out = roundTrip(t, src, p.Params{}, p.Params{Owner: p.OwnerNone})
+	checkOwner(out, os.Geteuid(), os.Getegid())
+
+	out = roundTrip(t, src, p.Params{}, p.Params{
+		UserMap:  map[string]string{"12345": "54321"},
+		GroupMap: map[string]string{"23456": "65432"},
+	})
+	checkOwner(out, 54321, 65432)
+}
+
+func TestHardlinks(t *testing.T) {
+	var (
+		src   = filepath.Join(t.TempDir(), "src")
+		file  = filepath.Join(src, "b", "file")
+		links = []string{"a/link", "c/link"}
+	)
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, link := range links {
+		link = filepath.Join(src, link)
+		if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Link(file, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := roundTrip(t, src, p.Params{}, p.Params{})
+
+	orig, err := os.Stat(filepath.Join(out, "a/link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"b/file", "c/link"} {
+		info, err := os.Stat(filepath.Join(out, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !os.SameFile(orig, info) {
+			t.Errorf("'%s' is not a hard link to 'a/link'", path)
+		}
+	}
+}
+
+func TestSymlinks(t *testing.T) {
+	var (
+		src   = filepath.Join(t.TempDir(), "src")
+		links = map[string]string{
+			"current":      "releases/42",
+			"releases/old": "../releases/42/",
+			"dangling":     "/nonexistent/target",
+		}
+	)
+
+	if err := os.MkdirAll(filepath.Join(src, "releases", "42"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(src, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := roundTrip(t, src, p.Params{}, p.Params{})
+	for link, target := range links {
+		if restored, err := os.Readlink(filepath.Join(out, link)); err != nil {
+			t.Fatal(err)
+		} else if restored != target {
+			t.Errorf("'%s': target '%s', want '%s'", link, restored, target)
+		}
+	}
+
+	// С флагом -symabs сохраняется абсолютный путь
+	// к конечному элементу, испорченные ссылки пропускаются
+	out = roundTrip(t, src, p.Params{SymAbs: true}, p.Params{})
+	want, err := filepath.EvalSymlinks(filepath.Join(src, "releases", "42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored, err := os.Readlink(filepath.Join(out, "current")); err != nil {
+		t.Fatal(err)
+	} else if restored != want {
+		t.Errorf("'current': target '%s', want '%s'", restored, want)
+	}
+	if _, err := os.Lstat(filepath.Join(out, "dangling")); !os.IsNotExist(err) {
+		t.Errorf("'dangling': broken link is archived")
+	}
+}
+
+func TestSpecialFiles(t *testing.T) {
+	var (
+		src  = filepath.Join(t.TempDir(), "src")
+		fifo = filepath.Join(src, "fifo")
+		dev  = filepath.Join(src, "null")
+		root = os.Geteuid() == 0
+	)
+
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.Mkfifo(fifo, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if root {
+		if err := unix.Mknod(dev, unix.S_IFCHR|0666, int(unix.Mkdev(1, 3))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := roundTrip(t, src, p.Params{}, p.Params{})
+
+	info, err := os.Lstat(filepath.Join(out, "fifo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode() != os.ModeNamedPipe|0640 {
+		t.Errorf("fifo: unexpected mode %s", info.Mode())
+	}
+
+	if !root {
+		return
+	}
+
+	if info, err = os.Lstat(filepath.Join(out, "null")); err != nil {
+		t.Fatal(err)
+	}
+	rdev := uint64(info.Sys().(*syscall.Stat_t).Rdev)
+	if info.Mode()&os.ModeCharDevice == 0 || unix.Major(rdev) != 1 || unix.Minor(rdev) != 3 {
+		t.Errorf("null: unexpected mode %s, rdev %d,%d", info.Mode(), unix.Major(rdev), unix.Minor(rdev))
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"sort"
 
 	"github.com/gh0st17/archiver/arc/internal/compress"
+	"github.com/gh0st17/archiver/arc/internal/crypt"
 	"github.com/gh0st17/archiver/arc/internal/generic"
 	"github.com/gh0st17/archiver/arc/internal/header"
+	"github.com/gh0st17/archiver/arc/internal/recovery"
+	"github.com/gh0st17/archiver/arc/internal/volume"
 	"github.com/gh0st17/archiver/errtype"
 )
 
@@ -14,7 +17,7 @@ import (
 func (arc Arc) Compress(paths []string) error {
 	var (
 		headers []header.Header
-		arcFile io.WriteCloser
+		arcFile volume.File
 		err     error
 	)
 
@@ -25,10 +28,11 @@ func (arc Arc) Compress(paths []string) error {
 		)
 	}
 
-	if headers, err = compress.PrepareHeaders(paths); err != nil {
+	if headers, err = compress.PrepareHeaders(paths, arc.RestoreParams); err != nil {
 		return errtype.ErrCompress(err)
 	}
 	sort.Sort(header.ByPathInArc(headers)) // Сортруем без учета регистра
+	headers = compress.FindHardlinks(headers)
 
 	if err = generic.InitCompressors(arc.RestoreParams); err != nil {
 		return errtype.ErrCompress(
@@ -36,11 +40,46 @@ func (arc Arc) Compress(paths []string) error {
 		)
 	}
 
-	if err = compress.ProcessingHeaders(arcFile, headers, arc.verbose); err != nil {
+	start, err := arcFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		arc.closeRemove(arcFile)
+		return errtype.ErrCompress(errtype.Join(ErrSeek, err))
+	}
+
+	// Заголовки шифруются потоком поверх файла,
+	// запись восстановления пишется в сам файл
+	var w io.WriteCloser = arcFile
+	if arc.hideHeaders {
+		if w, err = arc.cipher.Stream(arcFile, start, -1); err != nil {
+			arc.closeRemove(arcFile)
+			return errtype.ErrCompress(errtype.Join(ErrSeek, err))
+		}
+	}
+
+	generic.SetCipher(arc.cipher)
+	generic.SetHeaderCipher(nil)
+	if arc.hideHeaders {
+		generic.SetHeaderCipher(arc.cipher)
+	}
+	if err = compress.ProcessingHeaders(w, start, headers, arc.RestoreParams, arc.verbose); err != nil {
 		arc.closeRemove(arcFile)
 		return errtype.ErrCompress(err)
 	}
 
+	if arc.signKey != nil {
+		if err = crypt.Sign(arcFile, arc.signKey); err != nil {
+			arc.closeRemove(arcFile)
+			return errtype.ErrCompress(err)
+		}
+	}
+
+	if arc.recovery > 0 {
+		if err = recovery.Write(arcFile, arc.recovery); err != nil {
+			arc.closeRemove(arcFile)
+			return errtype.ErrCompress(errtype.Join(ErrWriteRecovery, err))
+		}
+	}
+
 	if err = arcFile.Close(); err != nil {
 		return errtype.ErrCompress(
 			errtype.Join(ErrCloseFile, err),
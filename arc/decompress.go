@@ -1,8 +1,9 @@
 package arc
 
 import (
+	"fmt"
 	"io"
-	"os"
+	"strings"
 
 	"github.com/gh0st17/archiver/arc/internal/decompress"
 	"github.com/gh0st17/archiver/arc/internal/generic"
@@ -12,15 +13,24 @@ import (
 
 // Выполняет распаковку архива.
 //
-// Открывает файл архива, пропускает магическое число и тип
-// компрессора, затем обрабатывает содержимое архива, проходя
+// Если требуется подпись, то сначала проверяется она.
+// Открывает файл архива, пропускает заголовок архива, затем
+// обрабатывает содержимое архива, проходя
 // по заголовкам разного типа. Обнаруженные заголовки
-// обрабатываются соответствующими методами, а после завершения
-// работы освобождаются декомпрессоры.
+// обрабатываются соответствующими методами. Если заданы пути
+// для выборочной распаковки, то обрабатываются только они.
+// После обработки всех заголовков восстанавливаются атрибуты
+// директорий и освобождаются декомпрессоры.
 func (arc Arc) Decompress() error {
-	arcFile, err := os.OpenFile(arc.path, os.O_RDONLY, 0644)
+	if arc.requireSig {
+		if _, err := arc.verifySignature(); err != nil {
+			return errtype.ErrDecompress(err)
+		}
+	}
+
+	arcFile, err := arc.openArc()
 	if err != nil {
-		return errtype.ErrDecompress(errtype.Join(ErrOpenArc, err))
+		return errtype.ErrDecompress(err)
 	}
 	defer arcFile.Close()
 
@@ -28,34 +38,133 @@ func (arc Arc) Decompress() error {
 		return errtype.ErrDecompress(err)
 	}
 
-	// Пропускаем магическое число и тип компрессора
-	if _, err = arcFile.Seek(headerLen, io.SeekStart); err != nil {
-		return errtype.ErrDecompress(errtype.Join(ErrSeek, err))
+	chunks, err := arc.openChunks()
+	if err != nil {
+		return errtype.ErrDecompress(err)
 	}
+	defer chunks.Close()
 
-	if err := generic.ProcessHeaders(arcFile, arc.restoreHandler); err != nil {
+	var dirs []*header.DirItem
+	if len(arc.extract) > 0 {
+		err = arc.restoreSelected(arcFile, arc.restoreHandler(&dirs))
+	} else {
+		err = generic.ProcessHeaders(arcFile, arc.restoreHandler(&dirs))
+	}
+	if err != nil {
 		return errtype.ErrDecompress(err)
 	}
 
+	if err := decompress.RestoreDirsAttrs(dirs, arc.RestoreParams); err != nil {
+		return errtype.ErrDecompress(errtype.Join(ErrRestoreDirs, err))
+	}
+	decompress.PrintXattrSummary()
+
 	// Сброс декомпрессоров перед новым вызовом этой функции
 	generic.ResetDecomp()
 
 	return nil
 }
 
-// Обработчик заголовков архива для распаковки
-func (arc Arc) restoreHandler(typ header.HeaderType, arcFile io.ReadSeeker) (err error) {
-	switch typ {
-	case header.File:
-		err = decompress.RestoreFile(arcFile, arc.RestoreParams, arc.verbose)
-	case header.Symlink:
-		err = decompress.RestoreSym(arcFile, arc.RestoreParams, arc.verbose)
-	default:
-		return ErrHeaderType
+// Распаковывает только элементы, выбранные для выборочной
+// распаковки, и их содержимое. Смещения элементов берутся
+// из индекса архива, без него архив сканируется полностью
+func (arc Arc) restoreSelected(arcFile io.ReadSeeker, handler generic.ProcHeaderHandler) error {
+	entries, err := decompress.ReadEntries(arcFile, arc.start)
+	if err != nil {
+		return errtype.Join(ErrReadHeaders, err)
+	}
+
+	var (
+		found = make([]bool, len(arc.extract))
+		// Solid-блоки, файлы которых уже распакованы
+		solids = map[int64]bool{}
+	)
+	for _, e := range entries {
+		if !arc.selected(e.PathInArc(), found) {
+			continue
+		}
+
+		// Выбранные файлы блока распаковываются вместе
+		if fi, ok := e.Header.(*header.FileItem); ok {
+			if _, inSolid := fi.SolidOffset(); inSolid && solids[e.Offset] {
+				continue
+			} else if inSolid {
+				solids[e.Offset] = true
+			}
+		}
+
+		if _, err = arcFile.Seek(e.Offset, io.SeekStart); err != nil {
+			return errtype.Join(ErrSeek, err)
+		}
+
+		typ, err := generic.ReadType(arcFile)
+		if err != nil {
+			return errtype.Join(ErrReadHeaderType, err)
+		}
+		if err = handler(typ, arcFile); err != nil {
+			return err
+		}
 	}
-	if err != nil && err != io.EOF {
-		return err
+
+	for i, ok := range found {
+		if !ok {
+			fmt.Printf("Элемент '%s' не найден в архиве\n", arc.extract[i])
+		}
 	}
 
 	return nil
 }
+
+// Проверяет, выбран ли элемент с путем path для выборочной
+// распаковки, и отмечает в found совпавшие пути
+func (arc Arc) selected(path string, found []bool) (ok bool) {
+	for i, sel := range arc.extract {
+		if sel == "" || path == sel || strings.HasPrefix(path, sel+"/") {
+			found[i], ok = true, true
+		}
+	}
+
+	return ok
+}
+
+// Проверяет, что элемент с путем path распаковывается:
+// выбран для выборочной распаковки или выборочная
+// распаковка не задана
+func (arc Arc) wanted(path string) bool {
+	return len(arc.extract) == 0 || arc.selected(path, make([]bool, len(arc.extract)))
+}
+
+// Возвращает обработчик заголовков архива для распаковки.
+// Восстановленные директории добавляются в dirs
+func (arc Arc) restoreHandler(dirs *[]*header.DirItem) generic.ProcHeaderHandler {
+	return func(typ header.HeaderType, arcFile io.ReadSeeker) (err error) {
+		switch typ {
+		case header.File:
+			err = decompress.RestoreFile(arcFile, arc.RestoreParams, arc.verbose)
+		case header.Solid:
+			err = decompress.RestoreSolid(arcFile, arc.RestoreParams, arc.verbose, arc.wanted)
+		case header.Chunked:
+			err = decompress.RestoreChunked(arcFile, arc.RestoreParams, arc.verbose)
+		case header.Duplicate:
+			err = decompress.RestoreDuplicate(arcFile, arc.RestoreParams, arc.verbose)
+		case header.Symlink:
+			err = decompress.RestoreSym(arcFile, arc.RestoreParams, arc.verbose)
+		case header.Hardlink:
+			err = decompress.RestoreLink(arcFile, arc.RestoreParams, arc.verbose)
+		case header.Special:
+			err = decompress.RestoreSpecial(arcFile, arc.RestoreParams, arc.verbose)
+		case header.Directory:
+			var di *header.DirItem
+			if di, err = decompress.RestoreDir(arcFile, arc.RestoreParams, arc.verbose); err == nil {
+				*dirs = append(*dirs, di)
+			}
+		default:
+			return ErrHeaderType
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		return nil
+	}
+}
@@ -14,6 +14,8 @@ var (
 	ErrIsDir       = errors.ErrIsDir
 	ErrNotArc      = errors.ErrNotArc
 	ErrUnknownComp = errors.ErrUnknownComp
+	ErrVersion     = errors.ErrVersion
+	ErrFeatures    = errors.ErrFeatures
 )
 
 // Ошибки при сжатии
@@ -27,28 +29,70 @@ var (
 	ErrReadHeaders    = errors.ErrReadHeaders
 	ErrDecompressFile = errors.ErrDecompressFile
 	ErrDecompressSym  = errors.ErrDecompressSym
+	ErrRestoreDirs    = errors.ErrRestoreDirs
 )
 
 // Ошибки проверки целостности
 var (
-	ErrCheckFile = errors.ErrCheckFile
-	ErrCheckCRC  = errors.ErrCheckCRC
-	ErrWrongCRC  = errors.ErrWrongCRC
+	ErrCheckFile   = errors.ErrCheckFile
+	ErrCheckCRC    = errors.ErrCheckCRC
+	ErrWrongCRC    = errors.ErrWrongCRC
+	ErrWrongDigest = errors.ErrWrongDigest
 )
 
 // Ошибки функции чтения
 var (
-	ErrOpenArc        = errors.ErrOpenArc
-	ErrReadMagic      = errors.ErrReadMagic
-	ErrReadFileHeader = errors.ErrReadFileHeader
-	ErrReadSymHeader  = errors.ErrReadSymHeader
-	ErrReadHeaderType = errors.ErrReadHeaderType
-	ErrHeaderType     = errors.ErrHeaderType
+	ErrOpenArc         = errors.ErrOpenArc
+	ErrReadMagic       = errors.ErrReadMagic
+	ErrReadVersion     = errors.ErrReadVersion
+	ErrReadCompType    = errors.ErrReadCompType
+	ErrReadFeatures    = errors.ErrReadFeatures
+	ErrReadFileHeader  = errors.ErrReadFileHeader
+	ErrReadSolidHeader = errors.ErrReadSolidHeader
+	ErrReadSymHeader   = errors.ErrReadSymHeader
+	ErrReadDirHeader   = errors.ErrReadDirHeader
+	ErrReadLinkHeader  = errors.ErrReadLinkHeader
+	ErrReadSpecHeader  = errors.ErrReadSpecHeader
+	ErrReadHeaderType  = errors.ErrReadHeaderType
+	ErrHeaderType      = errors.ErrHeaderType
 )
 
 // Ошибки функции записи
 var (
 	ErrCreateArc     = errors.ErrCreateArc
 	ErrWriteMagic    = errors.ErrWriteMagic
+	ErrWriteVersion  = errors.ErrWriteVersion
 	ErrWriteCompType = errors.ErrWriteCompType
+	ErrWriteFeatures = errors.ErrWriteFeatures
+)
+
+// Ошибки записи восстановления
+var (
+	ErrWriteRecovery = errors.ErrWriteRecovery
+	ErrNoRecovery    = errors.ErrNoRecovery
+	ErrRepair        = errors.ErrRepair
+)
+
+// Ошибки шифрования
+var (
+	ErrNoPassphrase     = errors.ErrNoPassphrase
+	ErrPassMismatch     = errors.ErrPassMismatch
+	ErrReadPassphrase   = errors.ErrReadPassphrase
+	ErrWrongPassphrase  = errors.ErrWrongPassphrase
+	ErrEncryptInit      = errors.ErrEncryptInit
+	ErrDecrypt          = errors.ErrDecrypt
+	ErrHeadersAuth      = errors.ErrHeadersAuth
+	ErrReadCryptParams  = errors.ErrReadCryptParams
+	ErrWriteCryptParams = errors.ErrWriteCryptParams
+	ErrNoIdentity       = errors.ErrNoIdentity
+	ErrWrongIdentity    = errors.ErrWrongIdentity
+	ErrReadKey          = errors.ErrReadKey
+	ErrWriteKey         = errors.ErrWriteKey
+	ErrKeyFormat        = errors.ErrKeyFormat
+	ErrSign             = errors.ErrSign
+	ErrReadSignature    = errors.ErrReadSignature
+	ErrVolumeMissing    = errors.ErrVolumeMissing
+	ErrNotSigned        = errors.ErrNotSigned
+	ErrBadSignature     = errors.ErrBadSignature
+	ErrUntrustedSigner  = errors.ErrUntrustedSigner
 )
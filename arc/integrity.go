@@ -3,7 +3,6 @@ package arc
 import (
 	"fmt"
 	"io"
-	"os"
 
 	"github.com/gh0st17/archiver/arc/internal/decompress"
 	"github.com/gh0st17/archiver/arc/internal/generic"
@@ -11,20 +10,35 @@ import (
 	"github.com/gh0st17/archiver/errtype"
 )
 
-// Проверяет целостность данных в архиве
+// Проверяет целостность данных в архиве. Если архив
+// подписан, то проверяется и подпись. Если у архива
+// есть запись восстановления, то по ней проверяется,
+// можно ли исправить повреждения
 func (arc Arc) IntegrityTest() error {
-	arcFile, err := os.OpenFile(arc.path, os.O_RDONLY, 0644)
+	arcFile, err := arc.openArc()
 	if err != nil {
-		return errtype.ErrIntegrity(errtype.Join(ErrOpenArc, err))
+		return errtype.ErrIntegrity(err)
 	}
 	defer arcFile.Close()
 
-	// Пропускаем магическое число и тип компрессора
-	if _, err = arcFile.Seek(headerLen, io.SeekStart); err != nil {
-		return errtype.ErrIntegrity(errtype.Join(ErrSeek, err))
+	// Измененные заголовки могут быть исправлены
+	// по записи восстановления, поэтому она
+	// проверяется и после ошибки чтения заголовков
+	chunks, err := arc.openChunks()
+	if err == nil {
+		defer chunks.Close()
+		err = generic.ProcessHeaders(arcFile, arc.integrityHeaderHandler)
+	}
+	if arc.format.Has(header.FeatSigned) {
+		if _, err := arc.verifySignature(); err != nil {
+			fmt.Println("Подпись:", err)
+		} else {
+			fmt.Println("Подпись: верна")
+		}
+	}
+	if arc.format.Has(header.FeatRecovery) {
+		arc.checkRecovery()
 	}
-
-	err = generic.ProcessHeaders(arcFile, arc.integrityHeaderHandler)
 	if err != nil {
 		return errtype.ErrIntegrity(err)
 	}
@@ -39,26 +53,66 @@ func (arc Arc) integrityHeaderHandler(typ header.HeaderType, arcFile io.ReadSeek
 		if err = arc.checkFile(arcFile); err != nil {
 			return errtype.ErrIntegrity(errtype.Join(ErrCheckFile, err))
 		}
+	case header.Solid:
+		if err = arc.checkSolid(arcFile); err != nil {
+			return errtype.ErrIntegrity(errtype.Join(ErrCheckFile, err))
+		}
+	case header.Chunked:
+		if err = arc.checkChunked(arcFile); err != nil {
+			return errtype.ErrIntegrity(errtype.Join(ErrCheckFile, err))
+		}
+	case header.Duplicate:
+		if err = arc.checkDuplicate(arcFile); err != nil {
+			return errtype.ErrIntegrity(errtype.Join(ErrCheckFile, err))
+		}
 	case header.Symlink:
 		sym := &header.SymItem{} // Фактически пропускаем до следующего файла
 		if err = sym.Read(arcFile); err != nil && err != io.EOF {
 			return errtype.ErrIntegrity(errtype.Join(ErrReadSymHeader, err))
 		}
+	case header.Hardlink:
+		li := &header.LinkItem{} // Данных у жесткой ссылки нет
+		if err = li.Read(arcFile); err != nil && err != io.EOF {
+			return errtype.ErrIntegrity(errtype.Join(ErrReadLinkHeader, err))
+		}
+	case header.Special:
+		si := &header.SpecialItem{} // Данных у специального файла нет
+		if err = si.Read(arcFile); err != nil && err != io.EOF {
+			return errtype.ErrIntegrity(errtype.Join(ErrReadSpecHeader, err))
+		}
+	case header.Directory:
+		di := &header.DirItem{} // Данных у директории нет
+		if err = di.Read(arcFile); err != nil && err != io.EOF {
+			return errtype.ErrIntegrity(errtype.Join(ErrReadDirHeader, err))
+		}
 	default:
 		return errtype.ErrIntegrity(ErrHeaderType)
 	}
 	return nil
 }
 
-// Распаковывает файл с проверкой CRC каждого блока сжатых данных
-func (arc Arc) checkFile(arcFile io.Reader) (err error) {
+// Проверяет CRC сжатых данных файла. Если у файла есть
+// контрольная сумма содержимого, то файл распаковывается
+// и проверяется также она. Блоки зашифрованного архива
+// проверяются на изменение при расшифровке
+func (arc Arc) checkFile(arcFile io.ReadSeeker) (err error) {
 	fi := &header.FileItem{}
 	if err := fi.Read(arcFile); err != nil && err != io.EOF {
 		return errtype.Join(ErrReadFileHeader, err)
 	}
 
-	if _, err = decompress.CheckCRC(arcFile, arc.Ct); err == ErrWrongCRC {
+	var damages []decompress.Damage
+	if fi.Digest().Type != header.DigestNone {
+		damages, err = decompress.CheckData(arcFile, fi, fi.CompType(arc.Ct))
+	} else {
+		_, damages, err = decompress.CheckCRC(arcFile, fi, fi.CompType(arc.Ct))
+	}
+
+	if err == ErrWrongCRC || err == ErrWrongDigest || err == ErrDecrypt {
 		fmt.Println(fi.PathOnDisk() + ": Файл поврежден")
+		for _, d := range damages {
+			fmt.Println("  " + d.String())
+		}
 	} else if err != nil {
 		return errtype.Join(ErrCheckCRC, err)
 	} else {
@@ -67,3 +121,59 @@ func (arc Arc) checkFile(arcFile io.Reader) (err error) {
 
 	return nil
 }
+
+// Проверяет данные solid-блока и печатает
+// результат проверки каждого его файла
+func (arc Arc) checkSolid(arcFile io.ReadSeeker) error {
+	si := &header.SolidItem{}
+	if err := si.Read(arcFile); err != nil {
+		return errtype.Join(ErrReadSolidHeader, err)
+	}
+
+	checks, err := decompress.CheckSolid(arcFile, si, si.Data().CompType(arc.Ct))
+	if err != nil {
+		return errtype.Join(ErrCheckCRC, err)
+	}
+
+	for _, chk := range checks {
+		printCheck(chk)
+	}
+
+	return nil
+}
+
+// Собирает файл с фрагментами без записи на
+// диск и печатает результат его проверки
+func (arc Arc) checkChunked(arcFile io.ReadSeeker) error {
+	chk, err := decompress.CheckChunked(arcFile, arc.Ct)
+	if err != nil {
+		return errtype.Join(ErrCheckCRC, err)
+	}
+
+	printCheck(chk)
+	return nil
+}
+
+// Проверяет данные файла с тем же содержимым, что
+// и у повторного файла, и печатает результат проверки
+func (arc Arc) checkDuplicate(arcFile io.ReadSeeker) error {
+	chk, err := decompress.CheckDuplicate(arcFile, arc.Ct)
+	if err != nil {
+		return errtype.Join(ErrCheckCRC, err)
+	}
+
+	printCheck(chk)
+	return nil
+}
+
+// Печатает результат проверки файла
+func printCheck(chk decompress.FileCheck) {
+	if chk.Err != nil {
+		fmt.Println(chk.File.PathOnDisk() + ": Файл поврежден")
+		for _, d := range chk.Damages {
+			fmt.Println("  " + d.String())
+		}
+	} else {
+		fmt.Println(chk.File.PathOnDisk() + ": OK")
+	}
+}
@@ -0,0 +1,110 @@
+// Пакет chunk предоставляет разбиение данных на фрагменты,
+// границы которых определяются содержимым (content-defined
+// chunking), и учет уникальных фрагментов для дедупликации
+//
+// Основные функции:
+//   - NewChunker: Создает разбиватель данных на фрагменты
+//   - NewSet: Создает набор уникальных фрагментов архива
+package chunk
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"io"
+)
+
+const (
+	MinSize  = 16 << 10      // Минимальный размер фрагмента
+	AvgSize  = 1 << maskBits // Средний размер фрагмента
+	MaxSize  = 256 << 10     // Максимальный размер фрагмента
+	maskBits = 16
+
+	// Маска старших битов скользящей суммы, которые зависят
+	// от последних 64 байт. Граница фрагмента находится там,
+	// где биты суммы под маской равны нулю
+	mask uint64 = (AvgSize - 1) << (64 - maskBits)
+)
+
+// Случайные значения байтов для скользящей суммы Gear.
+// Таблица постоянна, чтобы одинаковые данные разбивались
+// одинаково во всех архивах
+var gear [256]uint64
+
+// Разбиватель данных на фрагменты. Граница фрагмента
+// определяется скользящей суммой Gear по последним байтам,
+// поэтому вставка или удаление данных смещает только
+// соседние с изменением границы
+type Chunker struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+// Создает разбиватель данных r на фрагменты
+func NewChunker(r io.Reader) *Chunker {
+	return &Chunker{r: bufio.NewReader(r), buf: make([]byte, 0, MaxSize)}
+}
+
+// Возвращает очередной фрагмент данных. Фрагмент
+// действителен до следующего вызова. После последнего
+// фрагмента возвращается io.EOF
+func (c *Chunker) Next() ([]byte, error) {
+	var h uint64
+	c.buf = c.buf[:0]
+
+	for len(c.buf) < MaxSize {
+		b, err := c.r.ReadByte()
+		if err == io.EOF {
+			if len(c.buf) == 0 {
+				return nil, io.EOF
+			}
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		c.buf = append(c.buf, b)
+		h = h<<1 + gear[b]
+		if len(c.buf) >= MinSize && h&mask == 0 {
+			break
+		}
+	}
+
+	return c.buf, nil
+}
+
+// Хеш содержимого фрагмента
+type Hash [sha256.Size]byte
+
+// Набор уникальных фрагментов архива. Фрагменты получают
+// номера по порядку их первого появления в архиве
+type Set struct {
+	ids map[Hash]uint32
+}
+
+// Создает пустой набор фрагментов
+func NewSet() *Set { return &Set{ids: map[Hash]uint32{}} }
+
+// Возвращает номер фрагмента data и признак того, что
+// фрагмент встретился впервые и добавлен в набор
+func (s *Set) Add(data []byte) (id uint32, added bool) {
+	h := Hash(sha256.Sum256(data))
+	if id, ok := s.ids[h]; ok {
+		return id, false
+	}
+
+	id = uint32(len(s.ids))
+	s.ids[h] = id
+	return id, true
+}
+
+func init() {
+	// Генератор SplitMix64 с постоянным начальным значением
+	x := uint64(0x5717)
+	for i := range gear {
+		x += 0x9E3779B97F4A7C15
+		z := x
+		z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+		z = (z ^ z>>27) * 0x94D049BB133111EB
+		gear[i] = z ^ z>>31
+	}
+}
@@ -0,0 +1,117 @@
+package compress
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gh0st17/archiver/arc/internal/chunk"
+	"github.com/gh0st17/archiver/arc/internal/generic"
+	"github.com/gh0st17/archiver/arc/internal/header"
+	"github.com/gh0st17/archiver/errtype"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Обрабатывает заголовок файла при дедупликации. Данные
+// файла разбиваются на фрагменты, и сжимаются только
+// фрагменты, которых еще нет в наборе chunks. После данных
+// пишутся контрольная сумма содержимого и список всех
+// фрагментов файла, по которому файл собирается.
+// off -- смещение элемента в архиве. Если sum != nil,
+// то в него пишется содержимое файла
+func processingChunked(fi *header.FileItem, arcBuf io.Writer, off int64, chunks *chunk.Set, sum io.Writer, rp generic.RestoreParams, verbose bool) error {
+	inFile, err := openData(fi)
+	if err != nil {
+		return err
+	}
+	defer inFile.Close()
+
+	err = generic.WriteHeader(arcBuf, off, func(w io.Writer) error {
+		if err := filesystem.BinaryWrite(w, header.Chunked); err != nil {
+			return err
+		}
+		return fi.WriteBody(w)
+	})
+	if err != nil {
+		return errtype.Join(ErrWriteFileHeader, err)
+	}
+
+	if err = generic.SelectCompressors(*fi.Codec()); err != nil {
+		return errtype.Join(ErrCompressorInit, err)
+	}
+
+	// Данные файла дополняются или обрезаются до размера
+	// из заголовка, как и в solid-блоке
+	size := int64(fi.DataSize())
+	in := io.LimitReader(io.MultiReader(dataReader(inFile, fi), zeroReader{}), size)
+	if in, err = hashReader(in, inFile, fi, sum); err != nil {
+		return err
+	}
+	dr := &dedupReader{
+		c:   chunk.NewChunker(in),
+		set: chunks,
+		dw:  header.NewDigestWriter(fi),
+	}
+
+	// Новые фрагменты сжимаются как данные файла без
+	// контрольной суммы, она вычисляется по всем данным
+	data := header.NewFileItem(&fi.Base, 0)
+	if err = compressFile(data, dr, arcBuf, rp, false); err != nil {
+		return errtype.Join(ErrCompressFile, err)
+	}
+	fi.SetCSize(data.CSize()) // Для индекса архива
+	fi.SetCRC(data.CRC())
+	fi.SetChunks(dr.refs)
+
+	if dr.dw != nil {
+		fi.SetDigest(dr.dw.Digest())
+	}
+	if err = fi.WriteDigestSum(arcBuf); err != nil {
+		return errtype.Join(ErrWriteDigest, err)
+	}
+	if err = fi.WriteChunks(arcBuf); err != nil {
+		return errtype.Join(ErrWriteChunks, err)
+	}
+
+	if verbose {
+		fmt.Println(fi.PathInArc())
+	}
+	return nil
+}
+
+// Читатель новых фрагментов данных файла. Все фрагменты
+// файла записываются в список ссылок и в контрольную
+// сумму содержимого, а читаются только те, которые
+// встретились в архиве впервые
+type dedupReader struct {
+	c       *chunk.Chunker
+	set     *chunk.Set
+	dw      *header.DigestWriter
+	refs    []header.ChunkRef
+	pending []byte // Непрочитанная часть нового фрагмента
+}
+
+// Реализация io.Reader
+func (dr *dedupReader) Read(p []byte) (int, error) {
+	for len(dr.pending) == 0 {
+		data, err := dr.c.Next()
+		if err == io.EOF {
+			return 0, io.EOF
+		} else if err != nil {
+			return 0, errtype.Join(ErrReadUncompressed, err)
+		}
+
+		if dr.dw != nil {
+			dr.dw.Write(data)
+		}
+
+		id, added := dr.set.Add(data)
+		dr.refs = append(dr.refs, header.ChunkRef{ID: id, Size: uint32(len(data))})
+		if added {
+			dr.pending = data
+		}
+	}
+
+	n := copy(p, dr.pending)
+	dr.pending = dr.pending[n:]
+	return n, nil
+}
@@ -2,30 +2,35 @@
 //
 // Основные функции:
 //   - PrepareHeaders: Подготавливает заголовки для сжатия
+//   - FindHardlinks: Заменяет повторные жесткие ссылки
 //   - ProcessingHeaders: Обработка заголовков
 package compress
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
+	"hash"
 	"io"
 	"log"
 	"os"
 	"sync"
 
+	"github.com/gh0st17/archiver/arc/internal/chunk"
 	"github.com/gh0st17/archiver/arc/internal/generic"
 	"github.com/gh0st17/archiver/arc/internal/header"
+	"github.com/gh0st17/archiver/arc/internal/platform"
 	"github.com/gh0st17/archiver/errtype"
 	"github.com/gh0st17/archiver/filesystem"
 )
 
 // Подготавливает заголовки для сжатия
-func PrepareHeaders(paths []string) (headers []header.Header, err error) {
+func PrepareHeaders(paths []string, rp generic.RestoreParams) (headers []header.Header, err error) {
 	// Печать предупреждения о наличии абсолютных путей
 	filesystem.PrintPathsCheck(paths)
 
 	// Собираем элементы по путям paths в заголовки
-	if headers, err = fetchHeaders(paths); err != nil {
+	if headers, err = fetchHeaders(paths, rp); err != nil {
 		return nil, err
 	}
 	headers = header.DropDups(headers) // Удаляем дубликаты
@@ -36,72 +41,274 @@ func PrepareHeaders(paths []string) (headers []header.Header, err error) {
 	return headers, nil
 }
 
-// Обработка заголовков
-func ProcessingHeaders(arcFile io.WriteCloser, headers []header.Header, verbose bool) error {
-	arcBuf := bufio.NewWriter(arcFile)
-	for _, h := range headers { // Перебираем заголовки
-		if fi, ok := h.(*header.FileItem); ok {
-			if err := processingFile(fi, arcBuf, verbose); err != nil {
+// Обработка заголовков. После элементов пишется индекс
+// архива, start -- смещение первого элемента в архиве.
+// При дедупликации данные файлов разбиваются на фрагменты,
+// и в архив пишутся только фрагменты, которых еще нет в
+// архиве. Повторные файлы с тем же содержимым, что и у
+// ранее записанного файла, пишутся ссылками на его
+// данные. Если задан размер solid-блока, то небольшие
+// файлы собираются в solid-блоки, которые пишутся по
+// мере заполнения, а оставшиеся -- перед жесткими
+// ссылками и в конце элементов
+func ProcessingHeaders(arcFile io.WriteCloser, start int64, headers []header.Header, rp generic.RestoreParams, verbose bool) error {
+	var (
+		cw      = &countWriter{w: arcFile, n: start}
+		arcBuf  = bufio.NewWriter(cw)
+		offsets = make([]int64, len(headers))
+		solid   = solidGroups{limit: header.Size(rp.SolidSize)}
+		chunks  *chunk.Set
+	)
+	if rp.Dedup {
+		chunks = chunk.NewSet()
+	}
+
+	var dups *dupFiles
+	if rp.DupFiles {
+		dups = newDupFiles()
+	}
+
+	offset := func() int64 { return cw.n + int64(arcBuf.Buffered()) }
+	flush := func(groups []*solidGroup) error {
+		for _, g := range groups {
+			off := offset()
+			for _, i := range g.idx {
+				offsets[i] = off
+			}
+			if err := processingSolid(g, arcBuf, off, rp, verbose); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i, h := range headers { // Перебираем заголовки
+		// Файл жесткой ссылки должен быть распакован раньше нее
+		if _, ok := h.(*header.LinkItem); ok {
+			if err := flush(solid.takeAll()); err != nil {
+				return err
+			}
+		}
+		offsets[i] = offset()
+
+		// Хеш содержимого файла для поиска повторных файлов
+		var (
+			sum hash.Hash
+			src int
+			dup bool
+		)
+		if fi, ok := h.(*header.FileItem); ok && dups != nil && fi.UcSize() > 0 && !solid.fits(fi) {
+			var err error
+			if src, dup, sum, err = dups.find(fi, i); err != nil {
+				return err
+			}
+		}
+
+		if fi, ok := h.(*header.FileItem); dup {
+			srcFile := headers[src].(*header.FileItem)
+			if err := processingDuplicate(fi, srcFile, offsets[src], arcBuf, offsets[i], verbose); err != nil {
+				return err
+			}
+		} else if ok && solid.fits(fi) {
+			if err := flush(solid.add(fi, i)); err != nil {
+				return err
+			}
+		} else if ok && chunks != nil {
+			if err := processingChunked(fi, arcBuf, offsets[i], chunks, sum, rp, verbose); err != nil {
+				return err
+			}
+		} else if ok {
+			if err := processingFile(fi, arcBuf, offsets[i], sum, rp, verbose); err != nil {
 				return err
 			}
 		} else if di, ok := h.(*header.DirItem); ok {
-			processingDir(di, verbose)
+			if err := processingDir(di, arcBuf, offsets[i], verbose); err != nil {
+				return err
+			}
 		} else if si, ok := h.(*header.SymItem); ok {
-			processingSym(si, arcBuf, verbose)
+			if err := processingSym(si, arcBuf, offsets[i], verbose); err != nil {
+				return err
+			}
+		} else if li, ok := h.(*header.LinkItem); ok {
+			if err := processingLink(li, arcBuf, offsets[i], verbose); err != nil {
+				return err
+			}
+		} else if si, ok := h.(*header.SpecialItem); ok {
+			if err := processingSpecial(si, arcBuf, offsets[i], verbose); err != nil {
+				return err
+			}
+		}
+
+		if sum != nil {
+			dups.add(h.(*header.FileItem), i, sum)
 		}
 	}
-	arcBuf.Flush()
-	return nil
+
+	if err := flush(solid.takeAll()); err != nil {
+		return err
+	}
+
+	if err := writeIndex(arcBuf, offset(), headers, offsets, solid.limit > 0, chunks != nil); err != nil {
+		return errtype.Join(ErrWriteIndex, err)
+	}
+
+	return arcBuf.Flush()
 }
 
-// Обрабатывает заголовок файла
-func processingFile(fi *header.FileItem, arcBuf io.Writer, verbose bool) error {
-	err := fi.Write(arcBuf)
+// Обрабатывает заголовок файла по смещению off. Если
+// sum != nil, то в него пишется содержимое файла
+func processingFile(fi *header.FileItem, arcBuf io.Writer, off int64, sum io.Writer, rp generic.RestoreParams, verbose bool) error {
+	inFile, err := openData(fi)
 	if err != nil {
+		return err
+	}
+	defer inFile.Close()
+
+	if err = generic.WriteHeader(arcBuf, off, fi.Write); err != nil {
 		return errtype.Join(ErrWriteFileHeader, err)
 	}
 
-	if err = compressFile(fi, arcBuf, verbose); err != nil {
+	if err = generic.SelectCompressors(*fi.Codec()); err != nil {
+		return errtype.Join(ErrCompressorInit, err)
+	}
+
+	// Данные файла дополняются или обрезаются до размера
+	// из заголовка, если файл изменился после его чтения
+	size := int64(fi.DataSize())
+	in := io.LimitReader(io.MultiReader(dataReader(inFile, fi), zeroReader{}), size)
+	if in, err = hashReader(in, inFile, fi, sum); err != nil {
+		return err
+	}
+	if err = compressFile(fi, in, arcBuf, rp, verbose); err != nil {
 		return errtype.Join(ErrCompressFile, err)
 	}
 	return nil
 }
 
-// Обрабатывает заголовок директории
-func processingDir(di *header.DirItem, verbose bool) {
+// Открывает файл fi для сжатия и находит дыры
+// разреженного файла, которые нужны до записи
+// заголовка файла
+func openData(fi *header.FileItem) (*os.File, error) {
+	inFile, err := os.Open(fi.PathOnDisk())
+	if err != nil {
+		return nil, errtype.Join(
+			ErrCompressFile, ErrOpenFileCompress(fi.PathOnDisk()), err,
+		)
+	}
+
+	holes, err := platform.Holes(inFile, int64(fi.UcSize()))
+	if err != nil {
+		inFile.Close()
+		return nil, errtype.Join(ErrFindHoles(fi.PathOnDisk()), err)
+	}
+	fi.SetHoles(holes)
+	if _, err = inFile.Seek(0, io.SeekStart); err != nil {
+		inFile.Close()
+		return nil, errtype.Join(ErrCompressFile, err)
+	}
+
+	return inFile, nil
+}
+
+// Возвращает читатель областей данных файла f,
+// пропускающий дыры разреженного файла
+func dataReader(f *os.File, fi *header.FileItem) io.Reader {
+	var (
+		readers []io.Reader
+		off     int64
+	)
+
+	if len(fi.Holes()) == 0 {
+		return f
+	}
+
+	for _, h := range fi.Holes() {
+		if h.Offset > off {
+			readers = append(readers, io.NewSectionReader(f, off, h.Offset-off))
+		}
+		off = h.Offset + h.Length
+	}
+	if size := int64(fi.UcSize()); size > off {
+		readers = append(readers, io.NewSectionReader(f, off, size-off))
+	}
+
+	return io.MultiReader(readers...)
+}
+
+// Обрабатывает заголовок директории по смещению off
+func processingDir(di *header.DirItem, arcBuf io.Writer, off int64, verbose bool) error {
+	if err := generic.WriteHeader(arcBuf, off, di.Write); err != nil {
+		return errtype.Join(ErrWriteDirHeader, err)
+	}
 	if verbose {
 		fmt.Println(di.PathInArc())
 	}
+	return nil
 }
 
-// Обрабатывает заголовок символьной ссылки
-func processingSym(si *header.SymItem, arcBuf io.Writer, verbose bool) error {
-	if err := si.Write(arcBuf); err != nil {
+// Обрабатывает заголовок символьной ссылки по смещению off
+func processingSym(si *header.SymItem, arcBuf io.Writer, off int64, verbose bool) error {
+	if err := generic.WriteHeader(arcBuf, off, si.Write); err != nil {
 		return errtype.Join(ErrWriteSymHeader, err)
 	}
 	if verbose {
-		fmt.Println(si.PathInArc(), "->", si.PathOnDisk())
+		fmt.Println(si.PathInArc(), "->", si.Target())
 	}
 	return nil
 }
 
-// Сжимает файл блоками
-func compressFile(fi header.PathProvider, arcBuf io.Writer, verbose bool) error {
-	inFile, err := os.Open(fi.PathOnDisk())
-	if err != nil {
-		return errtype.Join(ErrOpenFileCompress(fi.PathOnDisk()), err)
+// Обрабатывает заголовок жесткой ссылки по смещению off
+func processingLink(li *header.LinkItem, arcBuf io.Writer, off int64, verbose bool) error {
+	if err := generic.WriteHeader(arcBuf, off, li.Write); err != nil {
+		return errtype.Join(ErrWriteLinkHeader, err)
 	}
-	defer inFile.Close()
-	inBuf := bufio.NewReader(inFile)
+	if verbose {
+		fmt.Println(li.PathInArc(), "=>", li.Target())
+	}
+	return nil
+}
+
+// Обрабатывает заголовок специального файла по смещению off
+func processingSpecial(si *header.SpecialItem, arcBuf io.Writer, off int64, verbose bool) error {
+	if err := generic.WriteHeader(arcBuf, off, si.Write); err != nil {
+		return errtype.Join(ErrWriteSpecHeader, err)
+	}
+	if verbose {
+		fmt.Println(si.PathInArc())
+	}
+	return nil
+}
+
+// Сжимает файл блоками. В режимах отличных от
+// [generic.BlockAlways] перед каждым блоком пишется
+// флаг, указывающий хранится ли блок без сжатия,
+// затем CRC блока для поиска поврежденных блоков.
+// В архиве с шифрованием блок шифруется после сжатия,
+// а длина и CRC относятся к зашифрованному блоку.
+// Если для файла задан тип контрольной суммы содержимого,
+// то она вычисляется по несжатым данным и пишется после CRC
+func compressFile(fi *header.FileItem, in io.Reader, arcBuf io.Writer, rp generic.RestoreParams, verbose bool) (err error) {
+	dw := header.NewDigestWriter(fi)
+	if dw != nil {
+		in = io.TeeReader(in, dw)
+	}
+	inBuf := bufio.NewReader(in)
 
 	var (
-		ncpu           = generic.Ncpu()
-		compressedBufs = generic.CompBuffers()
-		compressors    = generic.Compressors()
-		writeBuf       = generic.WriteBuffer()
+		blocks           = rp.Blocks
+		ncpu             = generic.Ncpu()
+		compressedBufs   = generic.CompBuffers()
+		decompressedBufs = generic.DecompBuffers()
+		compressors      = generic.Compressors()
+		stored           = generic.StoredBlocks()
+		writeBuf         = generic.WriteBuffer()
+		fc               = generic.Cipher().File(fi.PathInArc())
 
 		wrote, read int64
+		cSize       header.Size
 		crc         uint32
+		first       = true
+		skip        bool // Не сжимать оставшиеся блоки файла
 		wg          = sync.WaitGroup{}
 	)
 
@@ -126,27 +333,71 @@ func compressFile(fi header.PathProvider, arcBuf io.Writer, verbose bool) error
 		}
 
 		// Сжимаем буферы
-		if err = compressBuffers(); err != nil {
+		if err = compressBuffers(blocks, skip); err != nil {
 			return errtype.Join(ErrCompress, err)
 		}
 
+		// Если первый блок не сжимается, то
+		// остальные блоки файла не сжимаем
+		if first {
+			skip = blocks == generic.BlockSample && stored[0]
+			first = false
+		}
+
 		if read > 0 {
 			wg.Wait()
 		}
 
-		for i := 0; i < ncpu && compressedBufs[i].Len() > 0; i++ {
-			// Пишем длину сжатого блока
-			length := int64(compressedBufs[i].Len())
+		// Последний блок файла отмечается при шифровании,
+		// чтобы обнаружить отброшенные с конца блоки
+		last := -1
+		if _, peekErr := inBuf.Peek(1); peekErr == io.EOF {
+			last = int((read - 1) / int64(generic.BufferSize))
+		}
+
+		for i := 0; i < ncpu; i++ {
+			block, flag := compressedBufs[i], generic.BlockCompressed
+			if stored[i] {
+				block, flag = decompressedBufs[i], generic.BlockStored
+			}
+			if block.Len() == 0 {
+				break
+			}
+
+			data := block.Bytes()
+			if fc != nil {
+				if data, err = fc.Seal(data, flag, i == last); err != nil {
+					return errtype.Join(ErrEncrypt, err)
+				}
+			}
+
+			// Пишем длину блока
+			length := int64(len(data))
 			if err = filesystem.BinaryWrite(writeBuf, length); err != nil {
 				return errtype.Join(ErrWriteBufLen, err)
 			}
 
-			crc ^= generic.Checksum(compressedBufs[i].Bytes())
+			// Пишем флаг блока
+			if blocks != generic.BlockAlways {
+				if err = filesystem.BinaryWrite(writeBuf, flag); err != nil {
+					return errtype.Join(ErrWriteBlockFlag, err)
+				}
+			}
 
-			// Пишем сжатый блок
-			if wrote, err = compressedBufs[i].WriteTo(writeBuf); err != nil {
+			// Пишем CRC блока
+			sum := generic.Checksum(data)
+			if err = filesystem.BinaryWrite(writeBuf, sum); err != nil {
+				return errtype.Join(ErrWriteBlockCRC, err)
+			}
+
+			crc ^= sum
+			cSize += header.Size(length)
+
+			// Пишем блок
+			if wrote, err = bytes.NewReader(data).WriteTo(writeBuf); err != nil {
 				return errtype.Join(ErrWriteCompressBuf, err)
 			}
+			block.Reset()
 			log.Println("В буфер записи записан блок размера:", wrote)
 			compressors[i].Reset(compressedBufs[i])
 		}
@@ -160,6 +411,16 @@ func compressFile(fi header.PathProvider, arcBuf io.Writer, verbose bool) error
 	if err = writeFileFooter(arcBuf, crc); err != nil {
 		return err
 	}
+	fi.SetCSize(cSize) // Для индекса архива
+	fi.SetCRC(crc)
+
+	if dw != nil {
+		fi.SetDigest(dw.Digest())
+	}
+	if err = fi.WriteDigestSum(arcBuf); err != nil {
+		return errtype.Join(ErrWriteDigest, err)
+	}
+	log.Printf("Записана контрольная сумма %s: %X\n", fi.Digest().Type, fi.Digest().Sum)
 	if verbose {
 		fmt.Println(fi.PathInArc())
 	}
@@ -204,29 +465,47 @@ func loadUncompressedBuf(inBuf io.Reader) (read int64, err error) {
 	return read, nil
 }
 
-// Сжимает данные в буферах несжатых данных
-func compressBuffers() error {
+// Сжимает данные в буферах несжатых данных. Если режим
+// blocks отличен от [generic.BlockAlways], то блоки, которые
+// не уменьшились после сжатия, остаются в буферах несжатых
+// данных и помечаются как хранящиеся без сжатия. При skip
+// все блоки хранятся без сжатия
+func compressBuffers(blocks generic.BlockMode, skip bool) error {
 	var (
 		ncpu             = generic.Ncpu()
+		compressedBufs   = generic.CompBuffers()
 		decompressedBufs = generic.DecompBuffers()
 		compressors      = generic.Compressors()
+		stored           = generic.StoredBlocks()
 
 		errChan = make(chan error, ncpu)
 		wg      sync.WaitGroup
 	)
 
-	for i := 0; i < ncpu && decompressedBufs[i].Len() > 0; i++ {
+	for i := 0; i < ncpu; i++ {
+		stored[i] = skip && decompressedBufs[i].Len() > 0
+	}
+
+	for i := 0; i < ncpu && decompressedBufs[i].Len() > 0 && !skip; i++ {
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
 
-			_, err := decompressedBufs[i].WriteTo(compressors[i])
-			if err != nil {
+			data := decompressedBufs[i].Bytes()
+			if _, err := compressors[i].Write(data); err != nil {
 				errChan <- errtype.Join(ErrWriteCompressor, err)
 				return
 			}
-			if err = compressors[i].Close(); err != nil {
+			if err := compressors[i].Close(); err != nil {
 				errChan <- errtype.Join(ErrCloseCompressor, err)
+				return
+			}
+
+			if blocks != generic.BlockAlways && compressedBufs[i].Len() >= len(data) {
+				stored[i] = true
+				compressedBufs[i].Reset()
+			} else {
+				decompressedBufs[i].Reset()
 			}
 		}(i)
 	}
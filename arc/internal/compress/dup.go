@@ -0,0 +1,140 @@
+package compress
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/gh0st17/archiver/arc/internal/generic"
+	"github.com/gh0st17/archiver/arc/internal/header"
+	"github.com/gh0st17/archiver/errtype"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Ключ содержимого файла
+type dupKey struct {
+	size header.Size
+	sum  [sha256.Size]byte
+}
+
+// Записанные файлы, с которыми сравнивается содержимое
+// следующих файлов при обходе заголовков
+type dupFiles struct {
+	sizes map[header.Size]bool // Размеры записанных файлов
+	first map[dupKey]int       // Индексы первых файлов с содержимым
+}
+
+// Создает пустой набор записанных файлов
+func newDupFiles() *dupFiles {
+	return &dupFiles{sizes: map[header.Size]bool{}, first: map[dupKey]int{}}
+}
+
+// Ищет записанный файл с тем же содержимым, что и файл fi
+// с индексом i, и возвращает его индекс. Файл читается
+// отдельно, только если уже записан файл того же размера,
+// иначе возвращается хеш, который вычисляется при сжатии
+// файла и передается в [dupFiles.add]
+func (d *dupFiles) find(fi *header.FileItem, i int) (src int, dup bool, h hash.Hash, err error) {
+	if !d.sizes[fi.UcSize()] {
+		d.sizes[fi.UcSize()] = true
+		return 0, false, sha256.New(), nil
+	}
+
+	key := dupKey{size: fi.UcSize()}
+	if key.sum, err = hashFile(fi); err != nil {
+		return 0, false, nil, err
+	}
+	if src, dup = d.first[key]; !dup {
+		d.first[key] = i
+	}
+	return src, dup, nil, nil
+}
+
+// Добавляет записанный файл fi с индексом i и
+// хешем содержимого h, вычисленным при сжатии
+func (d *dupFiles) add(fi *header.FileItem, i int, h hash.Hash) {
+	key := dupKey{size: fi.UcSize()}
+	copy(key.sum[:], h.Sum(nil))
+	if _, seen := d.first[key]; !seen {
+		d.first[key] = i
+	}
+}
+
+// Вычисляет хеш SHA-256 содержимого файла fi
+func hashFile(fi *header.FileItem) (sum [sha256.Size]byte, err error) {
+	f, err := os.Open(fi.PathOnDisk())
+	if err != nil {
+		return sum, errtype.Join(ErrOpenFileCompress(fi.PathOnDisk()), err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, contentReader(f, fi)); err != nil {
+		return sum, errtype.Join(ErrHashFile(fi.PathOnDisk()), err)
+	}
+	copy(sum[:], h.Sum(nil))
+
+	return sum, nil
+}
+
+// Возвращает читатель содержимого файла f, дополненного
+// или обрезанного до размера из заголовка fi, как и
+// данные, которые пишутся в архив
+func contentReader(f io.ReaderAt, fi *header.FileItem) io.Reader {
+	size := int64(fi.UcSize())
+	return io.LimitReader(io.MultiReader(io.NewSectionReader(f, 0, size), zeroReader{}), size)
+}
+
+// Возвращает читатель данных in файла inFile, который
+// также пишет содержимое файла в h. Содержимое
+// разреженного файла хешируется отдельным чтением,
+// так как дыры не входят в данные файла
+func hashReader(in io.Reader, inFile *os.File, fi *header.FileItem, h io.Writer) (io.Reader, error) {
+	if h == nil {
+		return in, nil
+	}
+	if len(fi.Holes()) == 0 {
+		return io.TeeReader(in, h), nil
+	}
+
+	if _, err := io.Copy(h, contentReader(inFile, fi)); err != nil {
+		return nil, errtype.Join(ErrHashFile(fi.PathOnDisk()), err)
+	}
+	return in, nil
+}
+
+// Записывает файл fi, содержимое которого совпадает с
+// уже записанным файлом src, как ссылку на элемент src
+// по смещению srcOff. Данные файла не пишутся, а
+// контрольная сумма содержимого берется у src. Весь
+// элемент по смещению off пишется как запись заголовка
+func processingDuplicate(fi, src *header.FileItem, srcOff int64, arcBuf io.Writer, off int64, verbose bool) error {
+	fi.SetDupOffset(srcOff)
+	fi.SetDigest(src.Digest())
+
+	err := generic.WriteHeader(arcBuf, off, func(w io.Writer) error {
+		if err := filesystem.BinaryWrite(w, header.Duplicate); err != nil {
+			return errtype.Join(ErrWriteFileHeader, err)
+		}
+		if err := fi.WriteBody(w); err != nil {
+			return errtype.Join(ErrWriteFileHeader, err)
+		}
+		if err := filesystem.BinaryWrite(w, srcOff); err != nil {
+			return errtype.Join(ErrWriteDupOffset, err)
+		}
+		if err := fi.WriteDigestSum(w); err != nil {
+			return errtype.Join(ErrWriteDigest, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if verbose {
+		fmt.Println(fi.PathInArc())
+	}
+	return nil
+}
@@ -7,19 +7,34 @@ var (
 	ErrNoEntries         = errors.ErrNoEntries
 	ErrWriteFileHeader   = errors.ErrWriteFileHeader
 	ErrWriteSymHeader    = errors.ErrWriteSymHeader
+	ErrWriteDirHeader    = errors.ErrWriteDirHeader
+	ErrWriteLinkHeader   = errors.ErrWriteLinkHeader
+	ErrWriteSpecHeader   = errors.ErrWriteSpecHeader
+	ErrWriteSolidHeader  = errors.ErrWriteSolidHeader
 	ErrCompressFile      = errors.ErrCompressFile
 	ErrReadUncompressed  = errors.ErrReadUncompressed
 	ErrCompress          = errors.ErrCompress
 	ErrWriteBufLen       = errors.ErrWriteBufLen
+	ErrWriteBlockFlag    = errors.ErrWriteBlockFlag
+	ErrWriteBlockCRC     = errors.ErrWriteBlockCRC
 	ErrWriteCompressBuf  = errors.ErrWriteCompressBuf
 	ErrReadUncompressBuf = errors.ErrReadUncompressBuf
 	ErrWriteEOF          = errors.ErrWriteEOF
 	ErrWriteCRC          = errors.ErrWriteCRC
+	ErrWriteDigest       = errors.ErrWriteDigest
+	ErrWriteChunks       = errors.ErrWriteChunks
+	ErrWriteDupOffset    = errors.ErrWriteDupOffset
 	ErrWriteCompressor   = errors.ErrWriteCompressor
 	ErrCloseCompressor   = errors.ErrCloseCompressor
 	ErrFetchDirs         = errors.ErrFetchDirs
+	ErrCompressorInit    = errors.ErrCompressorInit
+	ErrWriteIndex        = errors.ErrWriteIndex
+	ErrEncrypt           = errors.ErrEncrypt
 
 	ErrLongPath = errors.ErrLongPath
 
 	ErrOpenFileCompress = errors.ErrOpenFileCompress
+	ErrFindHoles        = errors.ErrFindHoles
+	ErrReadXattrs       = errors.ErrReadXattrs
+	ErrHashFile         = errors.ErrHashFile
 )
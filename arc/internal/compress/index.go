@@ -0,0 +1,137 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"log"
+
+	"github.com/gh0st17/archiver/arc/internal/generic"
+	"github.com/gh0st17/archiver/arc/internal/header"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Писатель, подсчитывающий количество записанных байт
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Реализация io.Writer
+func (cw *countWriter) Write(p []byte) (n int, err error) {
+	n, err = cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// Сериализуемый заголовок
+type headerWriter interface {
+	Write(io.Writer) error
+}
+
+// Записывает признак конца элементов, индекс архива
+// и завершающую запись, по которой индекс находится.
+// offset -- смещение признака конца элементов в архиве,
+// offsets -- смещения заголовков headers.
+//
+// Каждая запись индекса состоит из смещения заголовка,
+// размера сжатых данных, CRC и самого заголовка, за
+// заголовком файла следует контрольная сумма содержимого.
+// В архиве с solid-блоками за ней следует смещение данных
+// файла в распакованном блоке или -1, если файл сжат
+// отдельно, а смещением заголовка файла в блоке считается
+// смещение заголовка блока. В архиве с дедупликацией
+// далее следует признак файла с фрагментами и, если он
+// установлен, список фрагментов файла.
+// Завершающая запись содержит смещение индекса,
+// CRC индекса и сигнатуру [header.IndexMagic]. В архиве
+// со скрытыми заголовками за ней пишется код
+// аутентификации индекса и завершающей записи
+func writeIndex(w io.Writer, offset int64, headers []header.Header, offsets []int64, solid, dedup bool) (err error) {
+	err = generic.WriteHeader(w, offset, func(w io.Writer) error {
+		return filesystem.BinaryWrite(w, header.End)
+	})
+	if err != nil {
+		return err
+	}
+	indexOffset := offset + generic.HeaderPrefixLen()
+
+	index := bytes.NewBuffer(nil)
+	if err = filesystem.BinaryWrite(index, uint32(len(headers))); err != nil {
+		return err
+	}
+
+	for i, h := range headers {
+		var (
+			cSize header.Size
+			crc   uint32
+		)
+		fi, isFile := h.(*header.FileItem)
+		if isFile {
+			cSize, crc = fi.CSize(), fi.CRC()
+		}
+
+		if err = filesystem.BinaryWrite(index, offsets[i]); err != nil {
+			return err
+		}
+		if err = filesystem.BinaryWrite(index, cSize); err != nil {
+			return err
+		}
+		if err = filesystem.BinaryWrite(index, crc); err != nil {
+			return err
+		}
+		if err = h.(headerWriter).Write(index); err != nil {
+			return err
+		}
+		if isFile {
+			if err = fi.WriteDigestSum(index); err != nil {
+				return err
+			}
+		}
+		if isFile && solid {
+			off, ok := fi.SolidOffset()
+			if !ok {
+				off = -1
+			}
+			if err = filesystem.BinaryWrite(index, off); err != nil {
+				return err
+			}
+		}
+		if isFile && dedup {
+			chunked := fi.Chunks() != nil
+			if err = filesystem.BinaryWrite(index, chunked); err != nil {
+				return err
+			}
+			if chunked {
+				if err = fi.WriteChunks(index); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	crc := generic.Checksum(index.Bytes())
+	if _, err = w.Write(index.Bytes()); err != nil {
+		return err
+	}
+	log.Println("Записан индекс архива, элементов:", len(headers))
+
+	// Завершающая запись
+	trailer := bytes.NewBuffer(nil)
+	if err = filesystem.BinaryWrite(trailer, indexOffset); err != nil {
+		return err
+	}
+	if err = filesystem.BinaryWrite(trailer, crc); err != nil {
+		return err
+	}
+	if err = filesystem.BinaryWrite(trailer, header.IndexMagic); err != nil {
+		return err
+	}
+	if _, err = w.Write(trailer.Bytes()); err != nil {
+		return err
+	}
+
+	if sum := generic.IndexMAC(indexOffset, index.Bytes(), trailer.Bytes()); sum != nil {
+		_, err = w.Write(sum)
+	}
+	return err
+}
@@ -7,6 +7,7 @@ import (
 	fp "path/filepath"
 	"syscall"
 
+	"github.com/gh0st17/archiver/arc/internal/generic"
 	"github.com/gh0st17/archiver/arc/internal/header"
 	"github.com/gh0st17/archiver/arc/internal/platform"
 	"github.com/gh0st17/archiver/errtype"
@@ -14,11 +15,12 @@ import (
 )
 
 // Проверяет чем является path, директорией,
-// символьной ссылкой или файлом, возвращает
+// символьной ссылкой, специальным файлом или
+// обычным файлом, возвращает
 // интерфейс заголовка, указывающий на
 // соответствующий тип
-func fetchPath(path string) (h header.Header, err error) {
-	if len(path) > 1023 {
+func fetchPath(path string, rp generic.RestoreParams) (h header.Header, err error) {
+	if len(path) > platform.MaxPath {
 		return nil, ErrLongPath(path)
 	}
 
@@ -26,14 +28,20 @@ func fetchPath(path string) (h header.Header, err error) {
 	if err != nil {
 		return nil, err
 	}
-	atime, mtime := platform.Timestamp(info)
-
-	b, err := header.NewBase(fp.ToSlash(path), atime, mtime)
+	b, err := header.NewBase(fp.ToSlash(path), info)
 	if err != nil {
 		return nil, err
 	}
 
-	if info.Mode()&os.ModeSymlink != 0 {
+	if rp.Xattrs {
+		attrs, err := platform.Xattrs(path)
+		if err != nil {
+			return nil, errtype.Join(ErrReadXattrs(path), err)
+		}
+		b.SetXattrs(attrs, rp.XattrFilter)
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 && rp.SymAbs {
 		target, err := fp.EvalSymlinks(path)
 		if errors.Is(err, syscall.ENOENT) {
 			fmt.Printf("Символическая ссылка '%s' испорчена\n", path)
@@ -45,32 +53,50 @@ func fetchPath(path string) (h header.Header, err error) {
 		if target, err = fp.Abs(target); err != nil {
 			return nil, err
 		} else {
-			h = header.NewSymItem(path, target)
+			h = header.NewSymItem(b, target)
+		}
+	} else if info.Mode()&os.ModeSymlink != 0 {
+		// Путь назначения сохраняется без изменений
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
 		}
+		h = header.NewSymItem(b, target)
 	} else if info.Mode()&os.ModeDir != 0 {
-		h = header.NewDirItem(b.PathInArc())
+		if b.PathInArc() == "" { // Корень, например '.' или '/'
+			return nil, nil
+		}
+		h = header.NewDirItem(b)
+	} else if si, ok := header.NewSpecialItem(b, info); ok {
+		h = si // Содержимое специальных файлов не читается
+	} else if !info.Mode().IsRegular() {
+		fmt.Printf("Пропускаю '%s': неизвестный тип файла\n", path)
+		return nil, nil
 	} else {
-		h = header.NewFileItem(b, header.Size(info.Size()))
+		fi := header.NewFileItem(b, header.Size(info.Size()))
+		fi.SetCodec(header.SelectCodec(
+			rp.CodecRules, b.PathInArc(), header.Codec{Type: rp.Ct, Level: rp.Cl},
+		))
+		fi.SetDigestType(rp.Digest)
+		if dev, ino, nlink, ok := platform.FileID(info); ok && nlink > 1 {
+			fi.SetFileID(&header.FileID{Dev: dev, Ino: ino})
+		}
+		h = fi
 	}
 
 	return h, nil
 }
 
 // Рекурсивно собирает элементы в директории
-func fetchDir(path string) (headers []header.Header, err error) {
+func fetchDir(path string, rp generic.RestoreParams) (headers []header.Header, err error) {
 	err = fp.WalkDir(path, func(path string, _ os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		header, err := fetchPath(path)
+		header, err := fetchPath(path, rp)
 		if err != nil {
-			if err == ErrLongPath(path) {
-				fmt.Println(err)
-				return nil
-			} else {
-				return err
-			}
+			return err
 		}
 
 		if header != nil {
@@ -87,7 +113,7 @@ func fetchDir(path string) (headers []header.Header, err error) {
 }
 
 // Собирает элементы файловой системы в заголовки
-func fetchHeaders(paths []string) (headers []header.Header, err error) {
+func fetchHeaders(paths []string, rp generic.RestoreParams) (headers []header.Header, err error) {
 	var (
 		dirHeaders []header.Header
 		header     header.Header
@@ -97,7 +123,7 @@ func fetchHeaders(paths []string) (headers []header.Header, err error) {
 		// Добавление директории в заголовок
 		// и ее рекурсивный обход
 		if filesystem.DirExists(path) {
-			if dirHeaders, err = fetchDir(path); err == nil {
+			if dirHeaders, err = fetchDir(path, rp); err == nil {
 				headers = append(headers, dirHeaders...)
 			} else {
 				return nil, errtype.Join(ErrFetchDirs, err)
@@ -105,7 +131,7 @@ func fetchHeaders(paths []string) (headers []header.Header, err error) {
 			continue
 		}
 
-		if header, err = fetchPath(path); err != nil { // Добавалние файла в заголовок
+		if header, err = fetchPath(path, rp); err != nil { // Добавалние файла в заголовок
 			return nil, errtype.Join(ErrFetchDirs, err)
 		} else if header != nil {
 			headers = append(headers, header)
@@ -113,3 +139,26 @@ func fetchHeaders(paths []string) (headers []header.Header, err error) {
 	}
 	return headers, nil
 }
+
+// Заменяет заголовки файлов, которые являются жесткими
+// ссылками на уже встреченный в headers файл, заголовками
+// жестких ссылок. Заголовки должны быть отсортированы
+// в порядке записи в архив
+func FindHardlinks(headers []header.Header) []header.Header {
+	first := map[header.FileID]string{}
+
+	for i, h := range headers {
+		fi, ok := h.(*header.FileItem)
+		if !ok || fi.FileID() == nil {
+			continue
+		}
+
+		if target, seen := first[*fi.FileID()]; seen {
+			headers[i] = header.NewLinkItem(&fi.Base, target)
+		} else {
+			first[*fi.FileID()] = fi.PathInArc()
+		}
+	}
+
+	return headers
+}
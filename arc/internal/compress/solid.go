@@ -0,0 +1,213 @@
+package compress
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gh0st17/archiver/arc/internal/generic"
+	"github.com/gh0st17/archiver/arc/internal/header"
+	"github.com/gh0st17/archiver/errtype"
+)
+
+// Файлы, ожидающие записи в solid-блок. Данные
+// блока сжимаются одним кодеком
+type solidGroup struct {
+	codec header.Codec
+	files []*header.FileItem
+	idx   []int // Индексы файлов в списке заголовков
+	size  header.Size
+}
+
+// Набор групп файлов для solid-блоков, по
+// одной группе на каждый кодек
+type solidGroups struct {
+	limit  header.Size // Предельный размер данных блока
+	groups []*solidGroup
+}
+
+// Проверяет, что файл fi сжимается в solid-блоке.
+// Файлы не меньше размера блока сжимаются отдельно
+func (sg solidGroups) fits(fi *header.FileItem) bool {
+	return sg.limit > 0 && fi.UcSize() < sg.limit
+}
+
+// Добавляет файл fi с индексом i в группу его кодека.
+// Если данные файла не помещаются в блок, то возвращается
+// заполненная группа, которую нужно записать
+func (sg *solidGroups) add(fi *header.FileItem, i int) (full []*solidGroup) {
+	var g *solidGroup
+	for _, gr := range sg.groups {
+		if gr.codec == *fi.Codec() {
+			g = gr
+			break
+		}
+	}
+
+	if g != nil && g.size+fi.UcSize() > sg.limit {
+		prev := *g
+		full = append(full, &prev)
+		*g = solidGroup{}
+	} else if g == nil {
+		g = &solidGroup{}
+		sg.groups = append(sg.groups, g)
+	}
+
+	g.codec = *fi.Codec()
+	g.files = append(g.files, fi)
+	g.idx = append(g.idx, i)
+	g.size += fi.UcSize()
+
+	return full
+}
+
+// Возвращает все непустые группы и очищает набор
+func (sg *solidGroups) takeAll() (all []*solidGroup) {
+	for _, g := range sg.groups {
+		if len(g.files) > 0 {
+			all = append(all, g)
+		}
+	}
+	sg.groups = nil
+
+	return all
+}
+
+// Записывает solid-блок с файлами группы g. Данные файлов
+// сжимаются одним потоком, как данные одного файла, а
+// после них пишутся контрольные суммы содержимого файлов.
+// off -- смещение solid-блока в архиве
+func processingSolid(g *solidGroup, arcBuf io.Writer, off int64, rp generic.RestoreParams, verbose bool) error {
+	// Карты дыр нужны до записи заголовка блока
+	for _, fi := range g.files {
+		if err := findHoles(fi); err != nil {
+			return err
+		}
+	}
+
+	si := header.NewSolidItem(g.files)
+	if err := generic.WriteHeader(arcBuf, off, si.Write); err != nil {
+		return errtype.Join(ErrWriteSolidHeader, err)
+	}
+
+	if err := generic.SelectCompressors(g.codec); err != nil {
+		return errtype.Join(ErrCompressorInit, err)
+	}
+
+	data := si.Data()
+	in := &solidReader{files: g.files}
+	defer in.Close()
+
+	if err := compressFile(data, in, arcBuf, rp, false); err != nil {
+		return errtype.Join(ErrCompressFile, err)
+	}
+	si.SetCSize(data.CSize(), data.CRC())
+
+	if err := si.WriteDigestSums(arcBuf); err != nil {
+		return errtype.Join(ErrWriteDigest, err)
+	}
+
+	if verbose {
+		for _, fi := range g.files {
+			fmt.Println(fi.PathInArc())
+		}
+	}
+	return nil
+}
+
+// Находит дыры разреженного файла fi
+func findHoles(fi *header.FileItem) error {
+	f, err := openData(fi)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// Читатель данных файлов solid-блока. Файлы открываются
+// по очереди, а данные каждого файла дополняются нулями
+// или обрезаются до размера из заголовка, чтобы смещения
+// файлов в блоке не нарушились, если файл изменился во
+// время сжатия
+type solidReader struct {
+	files []*header.FileItem // Оставшиеся файлы
+	f     *os.File
+	r     io.Reader
+	dw    *header.DigestWriter
+}
+
+// Реализация io.Reader
+func (sr *solidReader) Read(p []byte) (int, error) {
+	for {
+		if sr.r == nil {
+			if len(sr.files) == 0 {
+				return 0, io.EOF
+			}
+			if err := sr.next(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := sr.r.Read(p)
+		if err == io.EOF {
+			if err = sr.finish(); err != nil {
+				return n, err
+			}
+			if n == 0 {
+				continue
+			}
+		} else if err != nil {
+			return n, errtype.Join(ErrReadUncompressed, err)
+		}
+
+		return n, nil
+	}
+}
+
+// Открывает очередной файл блока
+func (sr *solidReader) next() (err error) {
+	fi := sr.files[0]
+	if sr.f, err = os.Open(fi.PathOnDisk()); err != nil {
+		return errtype.Join(ErrOpenFileCompress(fi.PathOnDisk()), err)
+	}
+
+	size := int64(fi.DataSize())
+	sr.r = io.LimitReader(io.MultiReader(dataReader(sr.f, fi), zeroReader{}), size)
+	if sr.dw = header.NewDigestWriter(fi); sr.dw != nil {
+		sr.r = io.TeeReader(sr.r, sr.dw)
+	}
+
+	return nil
+}
+
+// Закрывает прочитанный файл блока и
+// сохраняет контрольную сумму его содержимого
+func (sr *solidReader) finish() error {
+	if sr.dw != nil {
+		sr.files[0].SetDigest(sr.dw.Digest())
+	}
+	sr.files = sr.files[1:]
+	sr.r, sr.dw = nil, nil
+
+	return sr.Close()
+}
+
+// Закрывает открытый файл блока
+func (sr *solidReader) Close() (err error) {
+	if sr.f != nil {
+		err = sr.f.Close()
+		sr.f = nil
+	}
+
+	return err
+}
+
+// Читатель, возвращающий нули
+type zeroReader struct{}
+
+// Реализация io.Reader
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
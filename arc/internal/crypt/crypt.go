@@ -0,0 +1,237 @@
+// Пакет crypt предоставляет шифрование блоков данных
+// архива алгоритмом AES-256-GCM с ключом, получаемым из
+// пароля и соли архива функцией PBKDF2-HMAC-SHA256 или
+// зашифрованным для получателей X25519, а также подпись
+// архива ключом Ed25519
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+const (
+	SaltSize   = 16     // Размер соли архива
+	KeySize    = 32     // Размер ключа AES-256
+	Iterations = 600000 // Число итераций PBKDF2
+	NonceSize  = 12     // Размер уникального значения блока
+	// Увеличение размера блока при шифровании:
+	// уникальное значение и код аутентификации
+	Overhead = NonceSize + 16
+
+	checkSize = 16
+)
+
+// Значение для проверки ключа, шифруется с нулевым
+// уникальным значением, которое не используется
+// для блоков данных
+var checkText = []byte("arc key check")
+
+// Параметры шифрования, хранящиеся в заголовке архива
+type Params struct {
+	Salt       [SaltSize]byte
+	Iterations uint32
+	Check      [checkSize]byte // Проверочное значение ключа
+}
+
+// Значение для получения ключа шифрования заголовков
+// из ключа блоков данных
+var streamLabel = []byte("arc header stream")
+
+// Значение для получения ключа кода аутентификации
+// заголовков из ключа блоков данных
+var macLabel = []byte("arc header mac")
+
+// Шифр блоков данных и заголовков архива
+type Cipher struct {
+	aead   cipher.AEAD
+	stream cipher.Block // Шифр потока заголовков
+	macKey []byte       // Ключ кода аутентификации заголовков
+}
+
+// Создает шифр для нового архива со случайной
+// солью и возвращает его параметры
+func Create(pass []byte) (*Cipher, Params, error) {
+	p := Params{Iterations: Iterations}
+	if _, err := rand.Read(p.Salt[:]); err != nil {
+		return nil, p, err
+	}
+
+	c, err := newCipher(pbkdf2(pass, p.Salt[:], int(p.Iterations), KeySize))
+	if err != nil {
+		return nil, p, err
+	}
+	p.Check = c.check()
+
+	return c, p, nil
+}
+
+// Создает шифр существующего архива по его параметрам.
+// Возвращает [ErrWrongPassphrase], если пароль неверный
+func Open(pass []byte, p Params) (*Cipher, error) {
+	c, err := newCipher(pbkdf2(pass, p.Salt[:], int(p.Iterations), KeySize))
+	if err != nil {
+		return nil, err
+	}
+
+	if check := c.check(); !hmac.Equal(check[:], p.Check[:]) {
+		return nil, ErrWrongPassphrase
+	}
+
+	return c, nil
+}
+
+// Создает шифр с ключом данных key
+func newCipher(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := aes.NewCipher(derive(key, streamLabel))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead, stream: stream, macKey: derive(key, macLabel)}, nil
+}
+
+// Получает из ключа key ключ для назначения label
+func derive(key, label []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(label)
+	return mac.Sum(nil)
+}
+
+// Возвращает проверочное значение ключа
+func (c *Cipher) check() (check [checkSize]byte) {
+	nonce := make([]byte, NonceSize)
+	copy(check[:], c.aead.Seal(nil, nonce, nil, checkText))
+	return check
+}
+
+// Признак последнего блока файла в дополнительных данных
+const lastBlock byte = 0x80
+
+// Шифр блоков данных одного файла. Дополнительные
+// аутентифицируемые данные блока включают его номер,
+// флаг, признак последнего блока и путь файла в архиве,
+// поэтому блоки нельзя переставить, перенести в другой
+// файл или отбросить с конца файла
+type FileCipher struct {
+	c      *Cipher
+	path   string
+	index  uint64 // Номер следующего блока
+	failed bool   // Был измененный блок
+}
+
+// Возвращает шифр блоков файла path, для
+// архива без шифрования возвращает nil
+func (c *Cipher) File(path string) *FileCipher {
+	if c == nil {
+		return nil
+	}
+
+	return &FileCipher{c: c, path: path}
+}
+
+// Возвращает дополнительные данные текущего блока
+func (fc *FileCipher) ad(flag byte, last bool) []byte {
+	ad := make([]byte, 9, 9+len(fc.path))
+	ad[0] = flag
+	if last {
+		ad[0] |= lastBlock
+	}
+	binary.LittleEndian.PutUint64(ad[1:], fc.index)
+	return append(ad, fc.path...)
+}
+
+// Шифрует блок с флагом flag, last -- последний ли это
+// блок файла. Зашифрованный блок начинается со случайного
+// уникального значения
+func (fc *FileCipher) Seal(block []byte, flag byte, last bool) ([]byte, error) {
+	out := make([]byte, NonceSize, len(block)+Overhead)
+	if _, err := rand.Read(out); err != nil {
+		return nil, err
+	}
+
+	out = fc.c.aead.Seal(out, out, block, fc.ad(flag, last))
+	fc.index++
+
+	return out, nil
+}
+
+// Расшифровывает блок data с флагом flag на месте и
+// возвращает данные, которые начинаются в data со
+// смещения NonceSize, last -- последний ли это блок
+// файла в архиве. Возвращает [ErrDecrypt], если блок
+// изменен или признак последнего блока не совпадает
+func (fc *FileCipher) Open(data []byte, flag byte, last bool) ([]byte, error) {
+	if len(data) < Overhead {
+		fc.index++
+		fc.failed = true
+		return nil, ErrDecrypt
+	}
+
+	nonce, text := data[:NonceSize], data[NonceSize:]
+	plain, err := fc.c.aead.Open(text[:0], nonce, text, fc.ad(flag, last))
+	fc.index++
+	if err != nil {
+		fc.failed = true
+		return nil, ErrDecrypt
+	}
+
+	return plain, nil
+}
+
+// Проверяет, был ли среди расшифрованных блоков
+// измененный блок
+func (fc *FileCipher) Failed() bool {
+	return fc != nil && fc.failed
+}
+
+// Пропускает поврежденный блок
+func (fc *FileCipher) Skip() {
+	if fc != nil {
+		fc.index++
+	}
+}
+
+// Получает ключ длины keyLen из пароля функцией
+// PBKDF2 с HMAC-SHA256 (RFC 8018)
+func pbkdf2(pass, salt []byte, iter, keyLen int) []byte {
+	var (
+		prf = hmac.New(sha256.New, pass)
+		key = make([]byte, 0, keyLen)
+		buf [4]byte
+	)
+
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf[:], block)
+		prf.Write(buf[:])
+		u := prf.Sum(nil)
+		t := append([]byte{}, u...)
+
+		for i := 1; i < iter; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+
+	return key[:keyLen]
+}
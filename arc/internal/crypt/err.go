@@ -0,0 +1,14 @@
+package crypt
+
+import "github.com/gh0st17/archiver/arc/internal/errors"
+
+var (
+	ErrWrongPassphrase = errors.ErrWrongPassphrase
+	ErrDecrypt         = errors.ErrDecrypt
+	ErrWrongIdentity   = errors.ErrWrongIdentity
+	ErrSign            = errors.ErrSign
+	ErrReadSignature   = errors.ErrReadSignature
+	ErrNotSigned       = errors.ErrNotSigned
+	ErrBadSignature    = errors.ErrBadSignature
+	ErrSeek            = errors.ErrSeek
+)
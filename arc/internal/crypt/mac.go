@@ -0,0 +1,49 @@
+package crypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// Длина кода аутентификации скрытых заголовков
+const MACLen = sha256.Size
+
+// Метки областей, которые аутентифицируются кодом
+const (
+	macHeader byte = iota // Запись заголовка элемента
+	macIndex              // Индекс архива
+)
+
+// Вычисляет код аутентификации HMAC-SHA256 области kind
+// по смещению off в архиве с содержимым parts
+func (c *Cipher) mac(kind byte, off int64, parts ...[]byte) []byte {
+	var prefix [9]byte
+	prefix[0] = kind
+	binary.LittleEndian.PutUint64(prefix[1:], uint64(off))
+
+	h := hmac.New(sha256.New, c.macKey)
+	h.Write(prefix[:])
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return h.Sum(nil)
+}
+
+// Вычисляет код аутентификации записи заголовка элемента
+// типа typ по смещению off. Смещение входит в код,
+// поэтому записи нельзя переставить или скопировать
+func (c *Cipher) HeaderMAC(off int64, typ byte, body []byte) []byte {
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(body)))
+	return c.mac(macHeader, off, []byte{typ}, size[:], body)
+}
+
+// Вычисляет код аутентификации индекса архива
+// по смещению off вместе с завершающей записью
+func (c *Cipher) IndexMAC(off int64, index, trailer []byte) []byte {
+	return c.mac(macIndex, off, index, trailer)
+}
+
+// Проверяет код аутентификации sum
+func CheckMAC(sum, want []byte) bool { return hmac.Equal(sum, want) }
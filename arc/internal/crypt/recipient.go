@@ -0,0 +1,163 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/pem"
+)
+
+const (
+	// Размер зашифрованного ключа данных
+	wrappedKeySize = KeySize + 16
+
+	PublicKeyType  = "ARC X25519 PUBLIC KEY"  // Тип PEM блока открытого ключа
+	PrivateKeyType = "ARC X25519 PRIVATE KEY" // Тип PEM блока закрытого ключа
+)
+
+// Значение для получения ключа, которым шифруется
+// ключ данных для получателя
+var wrapLabel = []byte("arc recipient key")
+
+// Ключ данных архива, зашифрованный для одного
+// получателя. Ключ шифрования ключа данных получается
+// из общего секрета X25519 одноразового ключа
+// отправителя и ключа получателя
+type Stanza struct {
+	Ephemeral [32]byte             // Одноразовый открытый ключ отправителя
+	Key       [wrappedKeySize]byte // Зашифрованный ключ данных
+}
+
+// Создает шифр со случайным ключом данных и
+// шифрует этот ключ для каждого из получателей
+func CreateFor(recipients []*ecdh.PublicKey) (*Cipher, []Stanza, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+
+	stanzas := make([]Stanza, len(recipients))
+	for i, r := range recipients {
+		eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		shared, err := eph.ECDH(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		aead, err := wrapCipher(shared, eph.PublicKey(), r)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		copy(stanzas[i].Ephemeral[:], eph.PublicKey().Bytes())
+		copy(stanzas[i].Key[:], aead.Seal(nil, make([]byte, NonceSize), key, nil))
+	}
+
+	c, err := newCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c, stanzas, nil
+}
+
+// Создает шифр архива по ключу данных, расшифрованному
+// любым из закрытых ключей ids. Возвращает
+// [ErrWrongIdentity], если ни один ключ не подходит
+func OpenFor(ids []*ecdh.PrivateKey, stanzas []Stanza) (*Cipher, error) {
+	for _, id := range ids {
+		for _, s := range stanzas {
+			eph, err := ecdh.X25519().NewPublicKey(s.Ephemeral[:])
+			if err != nil {
+				continue
+			}
+			shared, err := id.ECDH(eph)
+			if err != nil {
+				continue
+			}
+			aead, err := wrapCipher(shared, eph, id.PublicKey())
+			if err != nil {
+				return nil, err
+			}
+
+			if key, err := aead.Open(nil, make([]byte, NonceSize), s.Key[:], nil); err == nil {
+				return newCipher(key)
+			}
+		}
+	}
+
+	return nil, ErrWrongIdentity
+}
+
+// Возвращает шифр ключа данных по общему секрету
+// shared одноразового ключа eph и ключа получателя
+// recipient. Ключ шифра зависит также от этих ключей
+// и используется однократно, поэтому уникальное
+// значение шифра нулевое
+func wrapCipher(shared []byte, eph, recipient *ecdh.PublicKey) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, shared)
+	mac.Write(wrapLabel)
+	mac.Write(eph.Bytes())
+	mac.Write(recipient.Bytes())
+
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Создает пару ключей X25519 и возвращает
+// закрытый и открытый ключи в формате PEM
+func GenerateKey() (identity, recipient []byte, err error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	identity = pem.EncodeToMemory(&pem.Block{Type: PrivateKeyType, Bytes: priv.Bytes()})
+	recipient = pem.EncodeToMemory(&pem.Block{Type: PublicKeyType, Bytes: priv.PublicKey().Bytes()})
+
+	return identity, recipient, nil
+}
+
+// Возвращает открытые ключи из data в формате PEM
+func ParseRecipients(data []byte) (keys []*ecdh.PublicKey) {
+	for _, b := range pemBlocks(data, PublicKeyType) {
+		if key, err := ecdh.X25519().NewPublicKey(b); err == nil {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// Возвращает закрытые ключи из data в формате PEM
+func ParseIdentities(data []byte) (keys []*ecdh.PrivateKey) {
+	for _, b := range pemBlocks(data, PrivateKeyType) {
+		if key, err := ecdh.X25519().NewPrivateKey(b); err == nil {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// Возвращает содержимое PEM блоков типа typ из data
+func pemBlocks(data []byte, typ string) (blocks [][]byte) {
+	for {
+		var b *pem.Block
+		if b, data = pem.Decode(data); b == nil {
+			return blocks
+		}
+		if b.Type == typ {
+			blocks = append(blocks, b.Bytes)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package crypt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"io"
+
+	"github.com/gh0st17/archiver/arc/internal/volume"
+	"github.com/gh0st17/archiver/errtype"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+const (
+	SignMagic uint32 = 0x4e474953 // Сигнатура записи подписи "SIGN"
+	// Длина записи подписи: открытый ключ,
+	// подпись и сигнатура [SignMagic]
+	SignatureLen = ed25519.PublicKeySize + ed25519.SignatureSize + 4
+
+	SignPublicKeyType  = "ARC ED25519 PUBLIC KEY"  // Тип PEM блока открытого ключа подписи
+	SignPrivateKeyType = "ARC ED25519 PRIVATE KEY" // Тип PEM блока закрытого ключа подписи
+)
+
+// Подписывается хеш SHA-512 содержимого архива по
+// схеме Ed25519ph (RFC 8032), контекст отделяет
+// подпись архива от других подписей тем же ключом
+var signOpts = &ed25519.Options{Hash: crypto.SHA512, Context: "arc archive signature"}
+
+// Запись подписи архива
+type signature struct {
+	PublicKey [ed25519.PublicKeySize]byte
+	Signature [ed25519.SignatureSize]byte
+	Magic     uint32
+}
+
+// Подписывает все содержимое f ключом key и записывает
+// подпись в конец f. Подпись охватывает заголовок архива,
+// элементы с их данными и контрольными суммами и индекс
+func Sign(f volume.File, key ed25519.PrivateKey) error {
+	sum, size, err := hashFile(f, -1)
+	if err != nil {
+		return err
+	}
+
+	sig, err := key.Sign(rand.Reader, sum, signOpts)
+	if err != nil {
+		return errtype.Join(ErrSign, err)
+	}
+
+	s := signature{Magic: SignMagic}
+	copy(s.PublicKey[:], key.Public().(ed25519.PublicKey))
+	copy(s.Signature[:], sig)
+
+	if err = filesystem.BinaryWrite(io.NewOffsetWriter(f, size), s); err != nil {
+		return errtype.Join(ErrSign, err)
+	}
+	_, err = f.Seek(0, io.SeekEnd)
+	return err
+}
+
+// Проверяет подпись архива f, запись которой
+// заканчивается на смещении end, и возвращает
+// открытый ключ подписи. Возвращает [ErrNotSigned],
+// если записи подписи нет, и [ErrBadSignature],
+// если подпись неверна
+func Verify(f volume.File, end int64) (ed25519.PublicKey, error) {
+	var s signature
+
+	if end < SignatureLen {
+		return nil, ErrNotSigned
+	}
+	r := io.NewSectionReader(f, end-SignatureLen, SignatureLen)
+	if err := filesystem.BinaryRead(r, &s); err != nil {
+		return nil, errtype.Join(ErrReadSignature, err)
+	}
+	if s.Magic != SignMagic {
+		return nil, ErrNotSigned
+	}
+
+	sum, _, err := hashFile(f, end-SignatureLen)
+	if err != nil {
+		return nil, err
+	}
+
+	key := ed25519.PublicKey(s.PublicKey[:])
+	if ed25519.VerifyWithOptions(key, sum, s.Signature[:], signOpts) != nil {
+		return nil, ErrBadSignature
+	}
+
+	return key, nil
+}
+
+// Возвращает хеш SHA-512 первых size байт f и их
+// количество. Если size < 0, то хешируется весь файл
+func hashFile(f volume.File, size int64) ([]byte, int64, error) {
+	if size < 0 {
+		var err error
+		if size, err = f.Seek(0, io.SeekEnd); err != nil {
+			return nil, 0, errtype.Join(ErrSeek, err)
+		}
+	}
+
+	h := sha512.New()
+	if _, err := io.Copy(h, io.NewSectionReader(f, 0, size)); err != nil {
+		return nil, 0, errtype.Join(ErrReadSignature, err)
+	}
+
+	return h.Sum(nil), size, nil
+}
+
+// Создает пару ключей подписи Ed25519 и возвращает
+// закрытый и открытый ключи в формате PEM
+func GenerateSignKey() (private, public []byte, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	private = pem.EncodeToMemory(&pem.Block{Type: SignPrivateKeyType, Bytes: priv.Seed()})
+	public = pem.EncodeToMemory(&pem.Block{Type: SignPublicKeyType, Bytes: pub})
+
+	return private, public, nil
+}
+
+// Возвращает первый закрытый ключ подписи из data
+// в формате PEM или nil, если ключа нет
+func ParseSignKey(data []byte) ed25519.PrivateKey {
+	for _, b := range pemBlocks(data, SignPrivateKeyType) {
+		if len(b) == ed25519.SeedSize {
+			return ed25519.NewKeyFromSeed(b)
+		}
+	}
+
+	return nil
+}
+
+// Возвращает открытые ключи подписи из data в формате PEM
+func ParseSigners(data []byte) (keys []ed25519.PublicKey) {
+	for _, b := range pemBlocks(data, SignPublicKeyType) {
+		if len(b) == ed25519.PublicKeySize {
+			keys = append(keys, ed25519.PublicKey(b))
+		}
+	}
+
+	return keys
+}
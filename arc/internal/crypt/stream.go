@@ -0,0 +1,92 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/gh0st17/archiver/arc/internal/volume"
+)
+
+// Файл архива со скрытыми заголовками. Байты файла в
+// области [start, end) зашифрованы AES-CTR отдельным
+// ключом, номер блока ключевого потока определяется
+// смещением байта от start, поэтому файл можно читать
+// с произвольного места. Байты вне области, например
+// запись восстановления, читаются и пишутся как есть.
+// Записи заголовков и индекс в зашифрованной области
+// аутентифицируются отдельными кодами, см. [Cipher.HeaderMAC]
+type Stream struct {
+	f          volume.File
+	block      cipher.Block
+	start, end int64
+	pos        int64  // Текущее смещение в файле
+	buf        []byte // Буфер для шифрования записываемых данных
+}
+
+// Возвращает поток файла f, шифрующий область от start
+// до end. Если end < 0, то область продолжается до конца
+// файла. Текущее смещение в f должно быть не меньше start
+func (c *Cipher) Stream(f volume.File, start, end int64) (*Stream, error) {
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if end < 0 {
+		end = math.MaxInt64
+	}
+
+	return &Stream{f: f, block: c.stream, start: start, end: end, pos: pos}, nil
+}
+
+// Применяет ключевой поток к p, которые находятся
+// в файле со смещения off
+func (s *Stream) xor(p []byte, off int64) {
+	lo, hi := max(off, s.start), min(off+int64(len(p)), s.end)
+	if lo >= hi {
+		return
+	}
+
+	var (
+		iv   [aes.BlockSize]byte
+		skip [aes.BlockSize]byte
+		rel  = lo - s.start
+	)
+	binary.BigEndian.PutUint64(iv[8:], uint64(rel/aes.BlockSize))
+	ctr := cipher.NewCTR(s.block, iv[:])
+	ctr.XORKeyStream(skip[:rel%aes.BlockSize], skip[:rel%aes.BlockSize])
+
+	data := p[lo-off : hi-off]
+	ctr.XORKeyStream(data, data)
+}
+
+// Реализация io.Reader
+func (s *Stream) Read(p []byte) (n int, err error) {
+	n, err = s.f.Read(p)
+	s.xor(p[:n], s.pos)
+	s.pos += int64(n)
+	return n, err
+}
+
+// Реализация io.Writer
+func (s *Stream) Write(p []byte) (n int, err error) {
+	s.buf = append(s.buf[:0], p...)
+	s.xor(s.buf, s.pos)
+	n, err = s.f.Write(s.buf)
+	s.pos += int64(n)
+	return n, err
+}
+
+// Реализация io.Seeker
+func (s *Stream) Seek(offset int64, whence int) (int64, error) {
+	pos, err := s.f.Seek(offset, whence)
+	if err == nil {
+		s.pos = pos
+	}
+	return pos, err
+}
+
+// Реализация io.Closer
+func (s *Stream) Close() error { return s.f.Close() }
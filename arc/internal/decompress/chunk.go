@@ -0,0 +1,684 @@
+package decompress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/gh0st17/archiver/arc/internal/generic"
+	"github.com/gh0st17/archiver/arc/internal/header"
+	c "github.com/gh0st17/archiver/compressor"
+	"github.com/gh0st17/archiver/errtype"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Расположение фрагмента в данных элемента архива
+type chunkLoc struct {
+	elem int64 // Смещение элемента с данными фрагмента
+	off  int64 // Смещение фрагмента в распакованных данных элемента
+	size int64
+}
+
+// Данные элемента архива с новыми фрагментами
+type chunkElem struct {
+	path   string // Путь для расшифровки блоков
+	ct     c.Type
+	blocks []int64 // Смещения найденных записей блоков
+	next   int64   // Смещение следующей записи блока
+	end    bool    // Прочитан признак конца данных
+}
+
+// Распакованный блок idx данных элемента по смещению elem
+type cachedBlock struct {
+	elem int64
+	idx  int
+	data []byte
+	err  error
+}
+
+// Количество распакованных блоков в кеше таблицы фрагментов
+const blockCacheSize = 8
+
+// Таблица фрагментов архива с дедупликацией. Данные
+// фрагмента читаются из элемента, где он появился
+// впервые, по отдельному читателю архива, чтобы не
+// сбивать позицию распаковки текущего элемента
+type ChunkStore struct {
+	f      io.ReadSeeker
+	ct     c.Type // Компрессор архива по умолчанию
+	chunks []chunkLoc
+	first  map[int64]uint32 // Номер первого нового фрагмента элемента
+	elems  map[int64]*chunkElem
+
+	total, unique header.Size // Размер данных файлов и уникальных фрагментов
+
+	// Недавно распакованные блоки, последний
+	// использованный блок находится в конце
+	cache []cachedBlock
+}
+
+// Таблица фрагментов открытого архива
+var chunkStore *ChunkStore
+
+// Устанавливает таблицу фрагментов для распаковки
+// и проверки файлов с фрагментами
+func SetChunkStore(cs *ChunkStore) { chunkStore = cs }
+
+// Строит таблицу фрагментов архива по спискам фрагментов
+// файлов из индекса arcFile, а при его отсутствии или
+// повреждении -- проходя по всем элементам arcFile
+// начиная с arcLenH. Данные фрагментов затем читаются
+// из arcFile
+func NewChunkStore(arcFile io.ReadSeeker, arcLenH int64, ct c.Type) (*ChunkStore, error) {
+	// О поврежденном индексе сообщается при чтении заголовков
+	entries, err := readIndex(arcFile, arcLenH)
+	if err != nil {
+		if _, err = arcFile.Seek(arcLenH, io.SeekStart); err != nil {
+			return nil, errtype.Join(ErrSeek, err)
+		}
+		if entries, err = scanEntries(arcFile); err != nil {
+			return nil, err
+		}
+	}
+
+	// Номера фрагментов присваиваются в порядке элементов
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Offset < entries[j].Offset
+	})
+
+	cs := &ChunkStore{
+		f:     arcFile,
+		ct:    ct,
+		first: map[int64]uint32{},
+		elems: map[int64]*chunkElem{},
+	}
+
+	for _, e := range entries {
+		fi, ok := e.Header.(*header.FileItem)
+		if !ok || fi.Chunks() == nil {
+			continue
+		}
+
+		next := uint32(len(cs.chunks))
+		cs.first[e.Offset] = next
+
+		var off int64
+		for _, r := range fi.Chunks() {
+			size := int64(r.Size)
+			if r.ID == next {
+				cs.chunks = append(cs.chunks, chunkLoc{elem: e.Offset, off: off, size: size})
+				cs.unique += header.Size(size)
+				off += size
+				next++
+			}
+			cs.total += header.Size(size)
+		}
+	}
+
+	return cs, nil
+}
+
+// Возвращает размер данных файлов с фрагментами
+// и размер их уникальных фрагментов
+func (cs *ChunkStore) Stat() (total, unique header.Size) {
+	return cs.total, cs.unique
+}
+
+// Закрывает читатель архива таблицы
+func (cs *ChunkStore) Close() error {
+	if cs == nil {
+		return nil
+	}
+
+	if closer, ok := cs.f.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Возвращает номер первого нового фрагмента
+// элемента по смещению elem
+func (cs *ChunkStore) firstID(elem int64) uint32 {
+	if cs == nil {
+		return 0
+	}
+
+	return cs.first[elem]
+}
+
+// Возвращает данные фрагмента id. Если блок с данными
+// фрагмента поврежден, то возвращается [ErrWrongCRC]
+// или [ErrDecrypt]
+func (cs *ChunkStore) chunk(id uint32) ([]byte, error) {
+	if cs == nil || int(id) >= len(cs.chunks) {
+		return nil, ErrChunkMissing(id)
+	}
+
+	var (
+		loc  = cs.chunks[id]
+		end  = loc.off + loc.size
+		bs   = int64(generic.BufferSize)
+		data = make([]byte, 0, loc.size)
+	)
+
+	// Все блоки, кроме последнего, имеют размер BufferSize
+	for off := loc.off; off < end; {
+		idx := off / bs
+		block, err := cs.block(loc.elem, int(idx))
+		if err != nil {
+			return nil, err
+		}
+
+		from, to := off-idx*bs, min(end-idx*bs, int64(len(block)))
+		if from >= to { // Блок короче ожидаемого
+			return nil, ErrWrongCRC
+		}
+		data = append(data, block[from:to]...)
+		off = idx*bs + to
+	}
+
+	return data, nil
+}
+
+// Возвращает распакованный блок idx данных элемента по
+// смещению elem. Блоки хранятся в кеше из [blockCacheSize]
+// блоков, из которого вытесняется давно использованный блок
+func (cs *ChunkStore) block(elem int64, idx int) ([]byte, error) {
+	for i, b := range cs.cache {
+		if b.elem == elem && b.idx == idx {
+			cs.cache = append(append(cs.cache[:i], cs.cache[i+1:]...), b)
+			return b.data, b.err
+		}
+	}
+
+	b := cachedBlock{elem: elem, idx: idx}
+	b.data, b.err = cs.readBlock(elem, idx)
+	if len(cs.cache) == blockCacheSize {
+		cs.cache = append(cs.cache[:0], cs.cache[1:]...)
+	}
+	cs.cache = append(cs.cache, b)
+
+	return b.data, b.err
+}
+
+// Читает, проверяет, расшифровывает и распаковывает
+// блок idx данных элемента по смещению elem
+func (cs *ChunkStore) readBlock(elem int64, idx int) ([]byte, error) {
+	var (
+		withFlags = header.ArcFormat().Has(header.FeatBlockFlags)
+		withCRC   = header.ArcFormat().Has(header.FeatBlockCRC)
+
+		size     int64
+		flag     byte
+		blockCRC uint32
+	)
+
+	e, err := cs.element(elem)
+	if err != nil {
+		return nil, err
+	}
+
+	// Записи блоков находятся по размерам предыдущих.
+	// Находится и следующая запись, чтобы узнать,
+	// последний ли это блок
+	for len(e.blocks) <= idx+1 && !e.end {
+
+		if _, err = cs.f.Seek(e.next, io.SeekStart); err != nil {
+			return nil, errtype.Join(ErrSeek, err)
+		}
+		if err = filesystem.BinaryRead(cs.f, &size); err != nil {
+			return nil, errtype.Join(ErrReadCompLen, err)
+		}
+
+		if size == -1 {
+			e.end = true
+			continue
+		} else if generic.CheckBufferSize(size) {
+			return nil, ErrBufSize(size)
+		}
+
+		e.blocks = append(e.blocks, e.next)
+		e.next += 8 + size
+		if withFlags {
+			e.next++
+		}
+		if withCRC {
+			e.next += 4
+		}
+	}
+
+	if len(e.blocks) <= idx { // Блоков меньше, чем фрагментов
+		return nil, ErrWrongCRC
+	}
+
+	if _, err = cs.f.Seek(e.blocks[idx], io.SeekStart); err != nil {
+		return nil, errtype.Join(ErrSeek, err)
+	}
+	if err = filesystem.BinaryRead(cs.f, &size); err != nil {
+		return nil, errtype.Join(ErrReadCompLen, err)
+	}
+	if withFlags {
+		if err = filesystem.BinaryRead(cs.f, &flag); err != nil {
+			return nil, errtype.Join(ErrReadBlockFlag, err)
+		} else if flag > generic.BlockStored {
+			return nil, ErrBlockFlag(flag)
+		}
+	}
+	if withCRC {
+		if err = filesystem.BinaryRead(cs.f, &blockCRC); err != nil {
+			return nil, errtype.Join(ErrReadBlockCRC, err)
+		}
+	}
+
+	data := make([]byte, size)
+	if _, err = io.ReadFull(cs.f, data); err != nil {
+		return nil, errtype.Join(ErrReadCompBuf, err)
+	}
+	if withCRC && generic.Checksum(data) != blockCRC {
+		return nil, ErrWrongCRC
+	}
+
+	if fc := generic.Cipher().File(e.path); fc != nil {
+		for range idx {
+			fc.Skip()
+		}
+		last := e.end && len(e.blocks) == idx+1
+		if data, err = fc.Open(data, flag, last); err != nil {
+			return nil, ErrDecrypt
+		}
+	}
+
+	if flag == generic.BlockStored {
+		return data, nil
+	}
+
+	dec, err := c.NewReaderDict(e.ct, generic.DictFor(e.ct), bytes.NewReader(data))
+	if err != nil {
+		return nil, errtype.Join(ErrDecompInit, err)
+	}
+	defer dec.Close()
+
+	out := &bytes.Buffer{}
+	if _, err = out.ReadFrom(dec); err != nil && err != io.ErrUnexpectedEOF {
+		return nil, errtype.Join(ErrReadDecomp, err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// Возвращает данные элемента по смещению elem,
+// читая его заголовок при первом обращении
+func (cs *ChunkStore) element(elem int64) (*chunkElem, error) {
+	if e, ok := cs.elems[elem]; ok {
+		return e, nil
+	}
+
+	if _, err := cs.f.Seek(elem, io.SeekStart); err != nil {
+		return nil, errtype.Join(ErrSeek, err)
+	}
+	if typ, err := generic.ReadType(cs.f); err != nil {
+		return nil, errtype.Join(ErrReadHeaderType, err)
+	} else if typ != header.Chunked {
+		return nil, ErrHeaderType
+	}
+
+	fi := &header.FileItem{}
+	if err := fi.Read(cs.f); err != nil && err != io.EOF {
+		return nil, errtype.Join(ErrReadFileHeader, err)
+	}
+
+	next, err := cs.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errtype.Join(ErrSeek, err)
+	}
+
+	e := &chunkElem{path: fi.PathInArc(), ct: fi.CompType(cs.ct), next: next}
+	cs.elems[elem] = e
+	return e, nil
+}
+
+// Восстанавливает файл с фрагментами из архива.
+//
+// Новые фрагменты файла распаковываются из данных
+// элемента, остальные берутся из таблицы фрагментов.
+// Поврежденные фрагменты заполняются нулями, а затем
+// обрабатываются согласно режиму распаковки
+// поврежденных блоков
+func RestoreChunked(arcFile io.ReadSeeker, rp generic.RestoreParams, verbose bool) error {
+	// Тип заголовка уже прочитан
+	elem, err := generic.ElemOffset(arcFile)
+	if err != nil {
+		return errtype.Join(ErrSeek, err)
+	}
+
+	fi, dataPos, err := readChunkedFile(arcFile)
+	if err != nil {
+		return err
+	}
+	end, err := arcFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return errtype.Join(ErrSeek, err)
+	}
+
+	diskPath, err := outFilePath(fi, rp)
+	if err != nil || diskPath == "" {
+		return err
+	}
+	ct := fi.CompType(rp.Ct)
+
+	if rp.Integ { // --xinteg
+		// Файл с поврежденными блоками восстанавливается
+		// частично, иначе поврежденный файл пропускается
+		arcFile.Seek(dataPos, io.SeekStart)
+		if _, damages, err := CheckCRC(arcFile, chunkData(fi, elem), ct); fileDamaged(err) && len(damages) == 0 {
+			fmt.Printf("Пропускаю поврежденный '%s'\n", fi.PathOnDisk())
+			_, err = arcFile.Seek(end, io.SeekStart)
+			return err
+		} else if err != nil && !fileDamaged(err) {
+			return errtype.Join(ErrCheckCRC, err)
+		}
+	}
+
+	if _, err = arcFile.Seek(dataPos, io.SeekStart); err != nil {
+		return errtype.Join(ErrSeek, err)
+	}
+	outFile, err := os.Create(diskPath)
+	if err != nil {
+		return errtype.Join(ErrCreateOutFile, err)
+	}
+	defer outFile.Close()
+
+	// Дыры разреженного файла пропускаются при записи
+	var out io.Writer = outFile
+	if len(fi.Holes()) > 0 {
+		out = &sparseWriter{f: outFile, holes: fi.Holes()}
+	}
+
+	chk, err := checkChunked(fi, elem, arcFile, out, ct)
+	if err != nil {
+		return err
+	}
+
+	// Дыра в конце файла создается изменением его размера
+	if len(fi.Holes()) > 0 {
+		if err = outFile.Truncate(int64(fi.UcSize())); err != nil {
+			return errtype.Join(ErrWriteOutBuf, err)
+		}
+	}
+	if err = outFile.Close(); err != nil {
+		return errtype.Join(ErrWriteOutBuf, err)
+	}
+	if _, err = arcFile.Seek(end, io.SeekStart); err != nil {
+		return errtype.Join(ErrSeek, err)
+	}
+
+	return reportFile(diskPath, chk, rp, verbose)
+}
+
+// Проверяет файл с фрагментами, собирая его данные
+// без записи на диск. Тип заголовка уже прочитан
+func CheckChunked(arcFile io.ReadSeeker, ct c.Type) (FileCheck, error) {
+	elem, err := generic.ElemOffset(arcFile)
+	if err != nil {
+		return FileCheck{}, errtype.Join(ErrSeek, err)
+	}
+
+	fi, dataPos, err := readChunkedFile(arcFile)
+	if err != nil {
+		return FileCheck{}, err
+	}
+	end, err := arcFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return FileCheck{}, errtype.Join(ErrSeek, err)
+	}
+
+	if _, err = arcFile.Seek(dataPos, io.SeekStart); err != nil {
+		return FileCheck{}, errtype.Join(ErrSeek, err)
+	}
+	chk, err := checkChunked(fi, elem, arcFile, io.Discard, fi.CompType(ct))
+	if err != nil {
+		return FileCheck{}, err
+	}
+
+	if _, err = arcFile.Seek(end, io.SeekStart); err != nil {
+		return FileCheck{}, errtype.Join(ErrSeek, err)
+	}
+	return chk, nil
+}
+
+// Собирает данные файла fi с фрагментами из элемента по
+// смещению elem в out и возвращает результат проверки
+// файла. Новые фрагменты распаковываются из arcFile,
+// остальные читаются из таблицы фрагментов
+func checkChunked(fi *header.FileItem, elem int64, arcFile io.ReadSeeker, out io.Writer, ct c.Type) (FileCheck, error) {
+	cw := &chunkWriter{
+		cs:   chunkStore,
+		refs: fi.Chunks(),
+		next: chunkStore.firstID(elem),
+		out:  out,
+	}
+
+	dw := header.NewDigestWriter(fi)
+	if dw != nil {
+		cw.out = io.MultiWriter(out, dw)
+	}
+
+	// Позиции фрагментов в файле не должны сместиться
+	data := chunkData(fi, elem)
+	stream, err := decompressData(data, arcFile, cw, ct, generic.DamageZero)
+	if err != nil && err != ErrDecrypt {
+		return FileCheck{}, err
+	}
+	if cerr := cw.Close(); cerr != nil {
+		return FileCheck{}, cerr
+	}
+
+	chk := FileCheck{File: fi, Damages: cw.fileDamages(fi, stream)}
+	switch {
+	case len(chk.Damages) > 0 && (err == ErrDecrypt || cw.decrypt):
+		chk.Err = ErrDecrypt
+	case len(chk.Damages) > 0:
+		chk.Err = ErrWrongCRC
+	case dw != nil && !dw.Digest().Equal(fi.Digest()):
+		chk.Err = ErrWrongDigest
+	case data.IsDamaged():
+		chk.Err = ErrWrongCRC
+	}
+
+	return chk, nil
+}
+
+// Возвращает заголовок данных элемента по смещению elem
+// с новыми фрагментами файла fi, по которому они
+// распаковываются как данные одного файла
+func chunkData(fi *header.FileItem, elem int64) *header.FileItem {
+	var (
+		next = chunkStore.firstID(elem)
+		size header.Size
+	)
+
+	for _, r := range fi.Chunks() {
+		if r.ID == next {
+			size += header.Size(r.Size)
+			next++
+		}
+	}
+
+	return header.NewFileItem(&fi.Base, size)
+}
+
+// Читает элемент файла с фрагментами из arcFile, пропуская
+// его данные, и возвращает заголовок файла и позицию
+// начала данных. Позиция в arcFile остается после элемента
+func readChunkedFile(arcFile io.ReadSeeker) (fi *header.FileItem, dataPos int64, err error) {
+	fi = &header.FileItem{}
+	if err = fi.Read(arcFile); err != nil && err != io.EOF {
+		return nil, 0, errtype.Join(ErrReadFileHeader, err)
+	}
+	if dataPos, err = arcFile.Seek(0, io.SeekCurrent); err != nil {
+		return nil, 0, errtype.Join(ErrSeek, err)
+	}
+
+	var (
+		dataSize header.Size
+		crc      uint32
+	)
+	if dataSize, err = skipFileData(arcFile, fi, false); err == io.EOF {
+		return nil, 0, err
+	} else if err != nil {
+		return nil, 0, errtype.Join(ErrSkipData, err)
+	}
+	fi.SetCSize(dataSize)
+
+	if err = filesystem.BinaryRead(arcFile, &crc); err != nil {
+		return nil, 0, errtype.Join(ErrReadCRC, err)
+	}
+	fi.SetCRC(crc)
+
+	if err = checkDigest(arcFile, fi, nil); err != nil {
+		return nil, 0, err
+	}
+	if err = fi.ReadChunks(arcFile); err != nil {
+		return nil, 0, errtype.Join(ErrReadChunks, err)
+	}
+
+	return fi, dataPos, nil
+}
+
+// Новый фрагмент в данных элемента
+type chunkSpan struct {
+	stream, data, size int64 // Смещения в данных элемента и файла
+}
+
+// Писатель распакованных данных элемента с фрагментами.
+// Перед каждым новым фрагментом из данных элемента
+// записывает предшествующие ему фрагменты из таблицы
+type chunkWriter struct {
+	cs   *ChunkStore
+	refs []header.ChunkRef
+	next uint32 // Номер очередного нового фрагмента
+	out  io.Writer
+
+	cur    int   // Индекс очередной ссылки
+	left   int64 // Оставшийся размер текущего нового фрагмента
+	off    int64 // Смещение в данных файла
+	stream int64 // Смещение в данных элемента
+
+	spans   []chunkSpan
+	damages []Damage // Поврежденные фрагменты из таблицы
+	decrypt bool     // Фрагмент из таблицы изменен
+}
+
+// Реализация io.Writer
+func (cw *chunkWriter) Write(p []byte) (n int, err error) {
+	n = len(p)
+
+	for len(p) > 0 {
+		if cw.left == 0 {
+			if err = cw.advance(); err != nil {
+				return n - len(p), err
+			}
+			if cw.left == 0 { // Данные за пределами файла
+				break
+			}
+		}
+
+		k := min(int64(len(p)), cw.left)
+		if _, err = cw.out.Write(p[:k]); err != nil {
+			return n - len(p), err
+		}
+		cw.left -= k
+		cw.off += k
+		cw.stream += k
+		p = p[k:]
+	}
+
+	return n, nil
+}
+
+// Записывает фрагменты из таблицы до очередного
+// нового фрагмента и начинает его
+func (cw *chunkWriter) advance() error {
+	for cw.cur < len(cw.refs) {
+		r := cw.refs[cw.cur]
+		cw.cur++
+
+		if r.ID == cw.next {
+			cw.next++
+			cw.left = int64(r.Size)
+			cw.spans = append(cw.spans, chunkSpan{cw.stream, cw.off, cw.left})
+			return nil
+		}
+
+		data, err := cw.cs.chunk(r.ID)
+		if err == ErrWrongCRC || err == ErrDecrypt {
+			cw.decrypt = cw.decrypt || err == ErrDecrypt
+			data = make([]byte, r.Size)
+			cw.damages = append(cw.damages, Damage{From: cw.off, To: cw.off + int64(r.Size)})
+		} else if err != nil {
+			return err
+		}
+
+		if _, err = cw.out.Write(data); err != nil {
+			return err
+		}
+		cw.off += int64(r.Size)
+	}
+
+	return nil
+}
+
+// Завершает сборку файла. Недостающие данные новых
+// фрагментов заполняются нулями, фрагменты из таблицы
+// после последнего нового фрагмента записываются
+func (cw *chunkWriter) Close() error {
+	for {
+		if cw.left > 0 {
+			if _, err := cw.out.Write(make([]byte, cw.left)); err != nil {
+				return err
+			}
+			cw.damages = append(cw.damages, Damage{From: cw.off, To: cw.off + cw.left})
+			cw.off += cw.left
+			cw.stream += cw.left
+			cw.left = 0
+		}
+
+		if cw.cur == len(cw.refs) {
+			return nil
+		}
+		if err := cw.advance(); err != nil {
+			return err
+		}
+	}
+}
+
+// Возвращает поврежденные области файла fi по
+// поврежденным областям данных элемента stream и
+// поврежденным фрагментам из таблицы
+func (cw *chunkWriter) fileDamages(fi *header.FileItem, stream []Damage) []Damage {
+	ranges := append([]Damage(nil), cw.damages...)
+	for _, d := range stream {
+		for _, s := range cw.spans {
+			if from, to := max(d.From, s.stream), min(d.To, s.stream+s.size); from < to {
+				ranges = append(ranges, Damage{From: s.data + from - s.stream, To: s.data + to - s.stream})
+			}
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].From < ranges[j].From })
+
+	var damages []Damage
+	for _, d := range ranges {
+		if n := len(damages); n > 0 && damages[n-1].To >= d.From {
+			damages[n-1].To = max(damages[n-1].To, d.To)
+		} else {
+			damages = append(damages, d)
+		}
+	}
+
+	for i, d := range damages {
+		damages[i] = newDamage(fi, d.From, d.To-d.From)
+	}
+	return damages
+}
@@ -0,0 +1,50 @@
+package decompress
+
+import (
+	"fmt"
+
+	"github.com/gh0st17/archiver/arc/internal/generic"
+	"github.com/gh0st17/archiver/arc/internal/header"
+)
+
+// Поврежденная область файла [From, To)
+type Damage struct {
+	From, To int64
+}
+
+// Создает поврежденную область по смещению off и размеру
+// size блока в данных файла fi. Дыры разреженного файла
+// внутри блока входят в область
+func newDamage(fi *header.FileItem, off, size int64) Damage {
+	d := Damage{From: fi.FileOffset(off)}
+	if size > 0 {
+		d.To = fi.FileOffset(off+size-1) + 1
+	} else {
+		d.To = d.From
+	}
+
+	return d
+}
+
+// Реализация fmt.Stringer
+func (d Damage) String() string {
+	return fmt.Sprintf("повреждены байты %d-%d", d.From, d.To-1)
+}
+
+// Печатает поврежденные области файла path
+// и способ их обработки согласно mode
+func printDamages(path string, damages []Damage, mode generic.DamageMode) {
+	var action string
+	switch mode {
+	case generic.DamageZero:
+		action = "заполнены нулями"
+	case generic.DamageTruncate:
+		action = "файл обрезан"
+	case generic.DamageSkip:
+		action = "пропущены"
+	}
+
+	for _, d := range damages {
+		fmt.Printf("%s: %s, %s\n", path, d, action)
+	}
+}
@@ -3,6 +3,11 @@
 // Основные функции:
 //   - RestoreFile: Восстанавливает файл из архива
 //   - RestoreSym: Восстанавливает символьную ссылку
+//   - RestoreDir: Восстанавливает директорию
+//   - RestoreLink: Восстанавливает жесткую ссылку
+//   - RestoreDirsAttrs: Восстанавливает атрибуты директорий
+//   - PrintXattrSummary: Печатает сводку отказов восстановления
+//     расширенных атрибутов
 package decompress
 
 import (
@@ -14,8 +19,10 @@ import (
 	fp "path/filepath"
 	"sync"
 
+	"github.com/gh0st17/archiver/arc/internal/crypt"
 	"github.com/gh0st17/archiver/arc/internal/generic"
 	"github.com/gh0st17/archiver/arc/internal/header"
+	"github.com/gh0st17/archiver/arc/internal/platform"
 	"github.com/gh0st17/archiver/arc/internal/userinput"
 	c "github.com/gh0st17/archiver/compressor"
 	"github.com/gh0st17/archiver/errtype"
@@ -41,12 +48,17 @@ func RestoreFile(arcFile io.ReadSeeker, rp generic.RestoreParams, verbose bool)
 	}
 
 	outPath := fp.Join(rp.OutputDir, fi.PathOnDisk())
-	if _, err = os.Stat(outPath); err == nil && !*rp.ReplaceAll {
+	diskPath, err := fi.OutPath(rp.OutputDir)
+	if err != nil {
+		return errtype.Join(ErrRestorePath(fi.PathOnDisk()), err)
+	}
+
+	if _, err = os.Stat(diskPath); err == nil && !*rp.ReplaceAll {
 		allFunc := func() {
 			*rp.ReplaceAll = true
 		}
 		negFunc := func() {
-			skipFileData(arcFile, true)
+			skipFileData(arcFile, fi, true)
 		}
 
 		if userinput.ReplacePrompt(outPath, allFunc, negFunc) {
@@ -55,26 +67,40 @@ func RestoreFile(arcFile io.ReadSeeker, rp generic.RestoreParams, verbose bool)
 	}
 
 	if rp.Integ { // --xinteg
+		// Файл с поврежденными блоками восстанавливается
+		// частично, иначе поврежденный файл пропускается
 		pos, _ := arcFile.Seek(0, io.SeekCurrent)
-		if _, err = CheckCRC(arcFile, rp.Ct); err == ErrWrongCRC {
+		if _, damages, err := CheckCRC(arcFile, fi, fi.CompType(rp.Ct)); fileDamaged(err) && len(damages) == 0 {
 			fmt.Printf("Пропускаю поврежденный '%s'\n", fi.PathOnDisk())
 			return nil
-		} else if err != nil {
+		} else if err != nil && !fileDamaged(err) {
 			return errtype.Join(ErrCheckCRC, err)
 		}
 		arcFile.Seek(pos, io.SeekStart)
 	}
 
-	if err = decompressFile(fi, arcFile, outPath, rp.Ct); err != nil {
+	damages, err := decompressFile(fi, arcFile, diskPath, fi.CompType(rp.Ct), rp.Damaged)
+	if err != nil && err != ErrWrongDigest && err != ErrDecrypt {
 		return err
 	}
 
-	if fi.IsDamaged() {
+	if err == ErrDecrypt {
+		fmt.Printf("%s: %v\n", outPath, err)
+	}
+	if len(damages) > 0 {
+		printDamages(outPath, damages, rp.Damaged)
+	} else if err == ErrWrongDigest {
+		fmt.Printf("%s: %v\n", outPath, err)
+	} else if fi.IsDamaged() {
 		fmt.Printf("%s: CRC сумма не совпадает\n", outPath)
 	} else if verbose {
 		fmt.Println(outPath)
 	}
 
+	if err = restoreAttrs(fi.Base, rp); err != nil {
+		return err
+	}
+
 	if err = fi.RestoreTime(rp.OutputDir); err != nil {
 		return errtype.Join(ErrRestoreTime, err)
 	}
@@ -93,48 +119,255 @@ func RestoreSym(arcFile io.Reader, rp generic.RestoreParams, verbose bool) error
 
 	if err = sym.RestorePath(rp.OutputDir); err != nil {
 		return errtype.Join(
-			ErrRestorePath(fp.Join(rp.OutputDir, sym.PathOnDisk())),
+			ErrRestorePath(fp.Join(rp.OutputDir, sym.PathOnDisk())), err,
+		)
+	}
+
+	if err = restoreAttrs(sym.Base, rp); err != nil {
+		return err
+	}
+
+	if err = sym.RestoreTime(rp.OutputDir); err != nil {
+		return errtype.Join(ErrRestoreTime, err)
+	}
+
+	if verbose {
+		fmt.Println(sym.PathInArc(), "->", sym.Target())
+	}
+
+	return nil
+}
+
+// Восстанавливает жесткую ссылку на ранее распакованный
+// файл. Если ссылку создать невозможно, то файл копируется
+func RestoreLink(arcFile io.Reader, rp generic.RestoreParams, verbose bool) error {
+	li := &header.LinkItem{}
+
+	if err := li.Read(arcFile); err != nil {
+		return errtype.Join(ErrReadLinkHeader, err)
+	}
+
+	outPath := fp.Join(rp.OutputDir, li.PathOnDisk())
+	target := fp.Join(rp.OutputDir, li.Target())
+	if diskTarget, err := platform.LongPath(target); err != nil {
+		return errtype.Join(ErrRestorePath(outPath), err)
+	} else if _, err = os.Stat(diskTarget); err != nil {
+		fmt.Printf(
+			"Пропускаю жесткую ссылку '%s': файл '%s' не распакован\n",
+			outPath, target,
+		)
+		return nil
+	}
+
+	if diskPath, err := li.OutPath(rp.OutputDir); err != nil {
+		return errtype.Join(ErrRestorePath(outPath), err)
+	} else if _, err = os.Lstat(diskPath); err == nil && !*rp.ReplaceAll {
+		allFunc := func() {
+			*rp.ReplaceAll = true
+		}
+
+		if userinput.ReplacePrompt(outPath, allFunc, nil) {
+			return nil
+		}
+	}
+
+	copied, err := li.RestorePath(rp.OutputDir)
+	if err != nil {
+		return errtype.Join(ErrRestorePath(outPath), err)
+	}
+
+	// Скопированный файл не разделяет атрибуты с оригиналом
+	if copied {
+		if err = restoreAttrs(li.Base, rp); err != nil {
+			return err
+		}
+		if err = li.RestoreTime(rp.OutputDir); err != nil {
+			return errtype.Join(ErrRestoreTime, err)
+		}
+	}
+
+	if verbose {
+		fmt.Println(outPath, "=>", target)
+	}
+
+	return nil
+}
+
+// Восстанавливает специальный файл. Если создать
+// его не удалось, например, из-за недостатка прав,
+// то выводится предупреждение и файл пропускается
+func RestoreSpecial(arcFile io.Reader, rp generic.RestoreParams, verbose bool) error {
+	si := &header.SpecialItem{}
+
+	if err := si.Read(arcFile); err != nil {
+		return errtype.Join(ErrReadSpecHeader, err)
+	}
+
+	outPath := fp.Join(rp.OutputDir, si.PathOnDisk())
+	if diskPath, err := si.OutPath(rp.OutputDir); err != nil {
+		fmt.Printf("Пропускаю специальный файл '%s': %v\n", outPath, err)
+		return nil
+	} else if _, err = os.Lstat(diskPath); err == nil && !*rp.ReplaceAll {
+		allFunc := func() {
+			*rp.ReplaceAll = true
+		}
+
+		if userinput.ReplacePrompt(outPath, allFunc, nil) {
+			return nil
+		}
+	}
+
+	if err := si.RestorePath(rp.OutputDir); err != nil {
+		fmt.Printf("Пропускаю специальный файл '%s': %v\n", outPath, err)
+		return nil
+	}
+
+	if err := restoreAttrs(si.Base, rp); err != nil {
+		return err
+	}
+
+	if err := si.RestoreTime(rp.OutputDir); err != nil {
+		return errtype.Join(ErrRestoreTime, err)
+	}
+
+	if verbose {
+		fmt.Println(outPath)
+	}
+
+	return nil
+}
+
+// Восстанавливает директорию.
+//
+// Время модификации директории изменится при записи
+// вложенных элементов, а режим доступа может запретить
+// эту запись, поэтому они восстанавливаются позже через
+// [RestoreDirsAttrs], для чего возвращается прочитанный
+// заголовок
+func RestoreDir(arcFile io.Reader, rp generic.RestoreParams, verbose bool) (*header.DirItem, error) {
+	di := &header.DirItem{}
+
+	if err := di.Read(arcFile); err != nil {
+		return nil, errtype.Join(ErrReadDirHeader, err)
+	}
+
+	if err := di.RestorePath(rp.OutputDir); err != nil {
+		return nil, errtype.Join(
+			ErrRestorePath(fp.Join(rp.OutputDir, di.PathOnDisk())), err,
 		)
 	}
 
 	if verbose {
-		fmt.Println(sym.PathInArc(), "->", sym.PathOnDisk())
+		fmt.Println(fp.Join(rp.OutputDir, di.PathOnDisk()))
+	}
+
+	return di, nil
+}
+
+// Восстанавливает режим доступа и время директорий dirs
+// после того, как все вложенные в них элементы были
+// записаны. Директории обходятся в обратном порядке,
+// чтобы вложенные обрабатывались раньше родительских
+func RestoreDirsAttrs(dirs []*header.DirItem, rp generic.RestoreParams) error {
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := restoreAttrs(dirs[i].Base, rp); err != nil {
+			return err
+		}
+
+		if err := dirs[i].RestoreTime(rp.OutputDir); err != nil {
+			return errtype.Join(ErrRestoreTime, err)
+		}
+	}
+
+	return nil
+}
+
+// Восстанавливает владельца, расширенные атрибуты и режим
+// доступа элемента. Владелец восстанавливается первым, так
+// как его смена сбрасывает биты setuid, setgid и атрибут
+// security.capability. Режим восстанавливается последним,
+// так как в режиме только для чтения атрибуты не записать
+func restoreAttrs(b header.Base, rp generic.RestoreParams) error {
+	if err := b.RestoreOwner(rp.OutputDir, rp.Owner); err != nil {
+		return errtype.Join(ErrRestoreOwner, err)
+	}
+
+	restoreXattrs(b, rp)
+
+	if !rp.NoPerm {
+		if err := b.RestoreMode(rp.OutputDir); err != nil {
+			return errtype.Join(ErrRestoreMode, err)
+		}
 	}
 
 	return nil
 }
 
 // Распаковывает файл
-func decompressFile(fi *header.FileItem, arcFile io.ReadSeeker, outPath string, ct c.Type) error {
+func decompressFile(fi *header.FileItem, arcFile io.ReadSeeker, outPath string, ct c.Type, mode generic.DamageMode) ([]Damage, error) {
 	outFile, err := os.Create(outPath)
 	if err != nil {
-		return errtype.Join(ErrCreateOutFile, err)
+		return nil, errtype.Join(ErrCreateOutFile, err)
 	}
 	defer outFile.Close()
 
-	// Если размер файла равен 0, то пропускаем запись
-	if fi.UcSize() == 0 {
-		if pos, err := arcFile.Seek(12, io.SeekCurrent); err != nil {
-			return errtype.Join(ErrSeek, err)
-		} else {
-			log.Println("Нулевой размер, перемещаю на позицию:", pos)
-			return nil
+	// Дыры разреженного файла пропускаются при записи
+	var out io.Writer = outFile
+	if len(fi.Holes()) > 0 {
+		out = &sparseWriter{f: outFile, holes: fi.Holes()}
+	}
+
+	damages, err := decompressData(fi, arcFile, out, ct, mode)
+	if err != nil && err != ErrWrongDigest && err != ErrDecrypt {
+		return nil, err
+	}
+
+	if mode == generic.DamageTruncate && len(damages) > 0 {
+		// Файл обрезается по первому поврежденному блоку
+		if err := outFile.Truncate(damages[0].From); err != nil {
+			return nil, errtype.Join(ErrWriteOutBuf, err)
+		}
+	} else if len(fi.Holes()) > 0 {
+		// Дыра в конце файла создается изменением его размера
+		if err := outFile.Truncate(int64(fi.UcSize())); err != nil {
+			return nil, errtype.Join(ErrWriteOutBuf, err)
 		}
 	}
 
+	return damages, err
+}
+
+// Распаковывает данные файла fi из arcFile в out. Если
+// CRC не совпадает, то fi помечается как поврежденный.
+// Блоки с несовпадающей CRC обрабатываются согласно mode
+// и возвращаются в виде поврежденных областей файла.
+// Если в архиве есть контрольная сумма содержимого
+// и она не совпадает, то возвращается [ErrWrongDigest]
+func decompressData(fi *header.FileItem, arcFile io.ReadSeeker, out io.Writer, ct c.Type, mode generic.DamageMode) (damages []Damage, err error) {
+	generic.SelectDecompressors(ct)
+	generic.ResetBuffers()
+
 	var (
 		ncpu             = generic.Ncpu()
 		decompressedBufs = generic.DecompBuffers()
+		damaged          = generic.DamagedBlocks()
 		writeBuf         = generic.WriteBuffer()
 
 		wrote, read int64
+		dataOff     int64 // Смещение блока в данных файла
+		truncated   bool  // Файл обрезан по поврежденному блоку
 		calcCRC     uint32
 		fileCRC     uint32
 		eof         error
+		dw          = header.NewDigestWriter(fi)
+		fc          = generic.Cipher().File(fi.PathInArc())
 		wg          = sync.WaitGroup{}
 	)
 
-	outBuf := bufio.NewWriter(outFile)
+	if dw != nil {
+		out = io.MultiWriter(out, dw)
+	}
+	outBuf := bufio.NewWriter(out)
 
 	flush := func() {
 		wg.Wait()
@@ -146,22 +379,42 @@ func decompressFile(fi *header.FileItem, arcFile io.ReadSeeker, outPath string,
 	}
 
 	for eof != io.EOF {
-		read, eof = loadCompressedBuf(arcFile, &calcCRC, ct, false)
+		read, eof = loadCompressedBuf(arcFile, &calcCRC, ct, false, fc)
 		if eof != nil && eof != io.EOF {
-			return errtype.Join(ErrReadCompressed, eof)
+			return nil, errtype.Join(ErrReadCompressed, eof)
 		}
 
 		if read > 0 {
 			if err = decompressBuffers(); err != nil {
-				return errtype.Join(ErrDecompress, err)
+				return nil, errtype.Join(ErrDecompress, err)
 			}
 
 			wg.Wait()
-			for i := 0; i < ncpu && decompressedBufs[i].Len() > 0; i++ {
-				if wrote, err = decompressedBufs[i].WriteTo(writeBuf); err != nil {
-					return errtype.Join(ErrWriteOutBuf, err)
+			for i := 0; i < ncpu && (damaged[i] || decompressedBufs[i].Len() > 0); i++ {
+				size := int64(decompressedBufs[i].Len())
+
+				if damaged[i] {
+					// Все блоки, кроме последнего, имеют размер BufferSize
+					size = max(0, min(int64(generic.BufferSize), int64(fi.DataSize())-dataOff))
+					if !truncated {
+						damages = append(damages, newDamage(fi, dataOff, size))
+					}
+
+					switch mode {
+					case generic.DamageZero:
+						writeBuf.Write(make([]byte, size))
+					case generic.DamageTruncate:
+						truncated = true
+					}
+				} else if truncated {
+					decompressedBufs[i].Reset()
+				} else if wrote, err = decompressedBufs[i].WriteTo(writeBuf); err != nil {
+					return nil, errtype.Join(ErrWriteOutBuf, err)
+				} else {
+					log.Println("В буфер записи записан блок размера:", wrote)
 				}
-				log.Println("В буфер записи записан блок размера:", wrote)
+
+				dataOff += size
 			}
 		}
 
@@ -170,10 +423,36 @@ func decompressFile(fi *header.FileItem, arcFile io.ReadSeeker, outPath string,
 	wg.Wait()
 
 	if err = filesystem.BinaryRead(arcFile, &fileCRC); err != nil {
-		return errtype.Join(ErrReadCRC, err)
+		return nil, errtype.Join(ErrReadCRC, err)
+	}
+	// Размер данных отличается от размера из заголовка,
+	// если блоки с конца файла отброшены
+	sizeDiffers := dataOff != int64(fi.DataSize())
+	fi.SetDamaged(calcCRC != fileCRC || len(damages) > 0 || sizeDiffers)
+	if err = outBuf.Flush(); err != nil {
+		return nil, errtype.Join(ErrWriteOutBuf, err)
+	}
+
+	if err = checkDigest(arcFile, fi, dw); fc.Failed() || fc != nil && sizeDiffers {
+		err = ErrDecrypt
+	}
+	return damages, err
+}
+
+// Читает контрольную сумму содержимого файла fi, если
+// она есть в архиве, и при dw != nil сравнивает ее
+// с вычисленной dw
+func checkDigest(arcFile io.Reader, fi *header.FileItem, dw *header.DigestWriter) error {
+	if !header.ArcFormat().Has(header.FeatDigest) {
+		return nil
+	}
+
+	if err := fi.ReadDigestSum(arcFile); err != nil {
+		return errtype.Join(ErrReadDigest, err)
+	}
+	if dw != nil && !dw.Digest().Equal(fi.Digest()) {
+		return ErrWrongDigest
 	}
-	fi.SetDamaged(calcCRC != fileCRC)
-	outBuf.Flush()
 
 	return nil
 }
@@ -186,17 +465,31 @@ func decompressFile(fi *header.FileItem, arcFile io.ReadSeeker, outPath string,
 //
 // Для определения длины файла без распаковки используется
 // countOnly == true, благодаря чему инициализация или сброс
-// декомпрессоров пропускается
-func loadCompressedBuf(arcBuf io.Reader, crc *uint32, ct c.Type, countOnly bool) (read int64, err error) {
+// декомпрессоров пропускается.
+//
+// Если fc не nil, то блоки расшифровываются до распаковки,
+// измененные блоки не распаковываются и помечаются как
+// поврежденные
+func loadCompressedBuf(arcBuf io.ReadSeeker, crc *uint32, ct c.Type, countOnly bool, fc *crypt.FileCipher) (read int64, err error) {
 	var (
 		ncpu           = generic.Ncpu()
 		compressedBufs = generic.CompBuffers()
 		decompressors  = generic.Decompressors()
-		dict           = generic.Dict()
+		stored         = generic.StoredBlocks()
+		damaged        = generic.DamagedBlocks()
+		dict           = generic.DictFor(ct)
+		withFlags      = header.ArcFormat().Has(header.FeatBlockFlags)
+		withCRC        = header.ArcFormat().Has(header.FeatBlockCRC)
 
 		n, bufferSize int64
+		flag          byte
+		blockCRC      uint32
 	)
 
+	for i := 0; i < ncpu; i++ {
+		damaged[i] = false
+	}
+
 	for i := 0; i < ncpu; i++ {
 		if err = filesystem.BinaryRead(arcBuf, &bufferSize); err != nil {
 			return 0, errtype.Join(ErrReadCompLen, err)
@@ -209,14 +502,51 @@ func loadCompressedBuf(arcBuf io.Reader, crc *uint32, ct c.Type, countOnly bool)
 			return 0, errtype.Join(ErrBufSize(bufferSize), err)
 		}
 
+		if withFlags {
+			if err = filesystem.BinaryRead(arcBuf, &flag); err != nil {
+				return 0, errtype.Join(ErrReadBlockFlag, err)
+			} else if flag > generic.BlockStored {
+				return 0, ErrBlockFlag(flag)
+			}
+		}
+		stored[i] = flag == generic.BlockStored
+
+		if withCRC {
+			if err = filesystem.BinaryRead(arcBuf, &blockCRC); err != nil {
+				return 0, errtype.Join(ErrReadBlockCRC, err)
+			}
+		}
+
 		if n, err = io.CopyN(compressedBufs[i], arcBuf, bufferSize); err != nil {
 			return 0, errtype.Join(ErrReadCompBuf, err)
 		}
 		log.Println("Прочитан блок сжатых данных размера:", bufferSize)
-		*crc ^= generic.Checksum(compressedBufs[i].Bytes())
+		sum := generic.Checksum(compressedBufs[i].Bytes())
+		*crc ^= sum
 		read += n
 
-		if countOnly {
+		// Поврежденный блок не распаковывается
+		if withCRC && sum != blockCRC {
+			log.Println("CRC блока не совпадает")
+			damaged[i] = true
+			compressedBufs[i].Reset()
+			fc.Skip()
+		} else if fc != nil {
+			last, err := lastBlock(arcBuf)
+			if err != nil {
+				return 0, errtype.Join(ErrReadCompLen, err)
+			}
+			if plain, err := fc.Open(compressedBufs[i].Bytes(), flag, last); err != nil {
+				log.Println("Блок данных изменен")
+				damaged[i] = true
+				compressedBufs[i].Reset()
+			} else {
+				compressedBufs[i].Next(crypt.NonceSize)
+				compressedBufs[i].Truncate(len(plain))
+			}
+		}
+
+		if countOnly || stored[i] || damaged[i] {
 			continue
 		}
 
@@ -232,6 +562,21 @@ func loadCompressedBuf(arcBuf io.Reader, crc *uint32, ct c.Type, countOnly bool)
 	return read, nil
 }
 
+// Проверяет, что за прочитанным блоком в arcBuf следует
+// признак конца файла. Позиция в arcBuf не меняется
+func lastBlock(arcBuf io.ReadSeeker) (bool, error) {
+	var next int64
+
+	if err := filesystem.BinaryRead(arcBuf, &next); err != nil {
+		return false, err
+	}
+	if _, err := arcBuf.Seek(-8, io.SeekCurrent); err != nil {
+		return false, errtype.Join(ErrSeek, err)
+	}
+
+	return next == -1, nil
+}
+
 // Распаковывает данные в буферах сжатых данных
 func decompressBuffers() error {
 	var (
@@ -239,12 +584,23 @@ func decompressBuffers() error {
 		compressedBufs   = generic.CompBuffers()
 		decompressedBufs = generic.DecompBuffers()
 		decompressors    = generic.Decompressors()
+		stored           = generic.StoredBlocks()
+		damaged          = generic.DamagedBlocks()
 
 		errChan = make(chan error, ncpu)
 		wg      sync.WaitGroup
 	)
 
-	for i := 0; i < ncpu && compressedBufs[i].Len() > 0; i++ {
+	for i := 0; i < ncpu && (damaged[i] || compressedBufs[i].Len() > 0); i++ {
+		if damaged[i] {
+			continue
+		} else if stored[i] { // Блок хранится без сжатия
+			if _, err := compressedBufs[i].WriteTo(decompressedBufs[i]); err != nil {
+				return errtype.Join(ErrReadDecomp, err)
+			}
+			continue
+		}
+
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
@@ -269,38 +625,82 @@ func decompressBuffers() error {
 	return nil
 }
 
-// Считывает данные сжатого файла из arcFile, проверяет
-// контрольную сумму и возвращает количество прочитанных байт
-func CheckCRC(arcFile io.Reader, ct c.Type) (read header.Size, err error) {
+// Считывает данные сжатого файла fi из arcFile, проверяет
+// контрольную сумму и возвращает количество прочитанных
+// байт. Если в архиве есть CRC блоков, то возвращаются
+// также поврежденные области файла
+func CheckCRC(arcFile io.ReadSeeker, fi *header.FileItem, ct c.Type) (read header.Size, damages []Damage, err error) {
 	var (
 		ncpu           = generic.Ncpu()
 		compressedBufs = generic.CompBuffers()
+		damaged        = generic.DamagedBlocks()
+		fc             = generic.Cipher().File(fi.PathInArc())
 
 		n       int64
+		dataOff int64 // Смещение блока в данных файла
 		eof     error
 		calcCRC uint32
 		fileCRC uint32
 	)
 
+	generic.ResetBuffers()
 	for eof != io.EOF {
-		if n, eof = loadCompressedBuf(arcFile, &calcCRC, ct, true); eof != nil && eof != io.EOF {
-			return 0, errtype.Join(ErrReadCompressed, eof)
+		if n, eof = loadCompressedBuf(arcFile, &calcCRC, ct, true, fc); eof != nil && eof != io.EOF {
+			return 0, nil, errtype.Join(ErrReadCompressed, eof)
 		}
 
 		read += header.Size(n)
 
-		for i := 0; i < ncpu && compressedBufs[i].Len() > 0; i++ {
+		for i := 0; i < ncpu && (damaged[i] || compressedBufs[i].Len() > 0); i++ {
+			// Все блоки, кроме последнего, имеют размер BufferSize
+			size := max(0, min(int64(generic.BufferSize), int64(fi.DataSize())-dataOff))
+			if damaged[i] {
+				damages = append(damages, newDamage(fi, dataOff, size))
+			}
 			compressedBufs[i].Reset()
+			dataOff += size
 		}
 	}
 
 	if err = filesystem.BinaryRead(arcFile, &fileCRC); err != nil {
-		return 0, errtype.Join(ErrReadCRC, err)
+		return 0, nil, errtype.Join(ErrReadCRC, err)
 	}
 
-	if calcCRC != fileCRC {
-		return read, ErrWrongCRC
+	if err = checkDigest(arcFile, fi, nil); err != nil {
+		return 0, nil, err
 	}
 
-	return read, nil
+	// Данных меньше, чем указано в заголовке,
+	// если блоки с конца файла отброшены
+	short := dataOff < int64(fi.DataSize())
+	if fc.Failed() || fc != nil && short {
+		return read, damages, ErrDecrypt
+	}
+
+	if calcCRC != fileCRC || len(damages) > 0 || short {
+		return read, damages, ErrWrongCRC
+	}
+
+	return read, nil, nil
+}
+
+// Сообщает, является ли err повреждением данных одного
+// файла, после которого распаковка архива продолжается
+func fileDamaged(err error) bool {
+	return err == ErrWrongCRC || err == ErrDecrypt || err == ErrWrongDigest
+}
+
+// Распаковывает данные файла fi из arcFile без записи на
+// диск, проверяя CRC и контрольную сумму содержимого.
+// Возвращает поврежденные области файла
+func CheckData(arcFile io.ReadSeeker, fi *header.FileItem, ct c.Type) ([]Damage, error) {
+	damages, err := decompressData(fi, arcFile, io.Discard, ct, generic.DamageZero)
+	if err != nil {
+		return damages, err
+	}
+
+	if fi.IsDamaged() {
+		return damages, ErrWrongCRC
+	}
+	return nil, nil
 }
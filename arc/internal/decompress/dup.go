@@ -0,0 +1,196 @@
+package decompress
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gh0st17/archiver/arc/internal/generic"
+	"github.com/gh0st17/archiver/arc/internal/header"
+	c "github.com/gh0st17/archiver/compressor"
+	"github.com/gh0st17/archiver/errtype"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Восстанавливает повторный файл из архива. Данные
+// распаковываются из элемента файла с тем же содержимым,
+// поэтому файл восстанавливается и при выборочной
+// распаковке без исходного файла
+func RestoreDuplicate(arcFile io.ReadSeeker, rp generic.RestoreParams, verbose bool) error {
+	fi, err := readDupFile(arcFile)
+	if err != nil {
+		return err
+	}
+	end, err := arcFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return errtype.Join(ErrSeek, err)
+	}
+
+	diskPath, err := outFilePath(fi, rp)
+	if err != nil || diskPath == "" {
+		return err
+	}
+	src, _ := fi.DupOffset()
+
+	if rp.Integ { // --xinteg
+		// Файл с поврежденными блоками восстанавливается
+		// частично, иначе поврежденный файл пропускается
+		if chk, err := checkSource(arcFile, src, nil, rp.Ct); err != nil {
+			return errtype.Join(ErrCheckCRC, err)
+		} else if fileDamaged(chk.Err) && len(chk.Damages) == 0 {
+			fmt.Printf("Пропускаю поврежденный '%s'\n", fi.PathOnDisk())
+			_, err = arcFile.Seek(end, io.SeekStart)
+			return err
+		}
+	}
+
+	outFile, err := os.Create(diskPath)
+	if err != nil {
+		return errtype.Join(ErrCreateOutFile, err)
+	}
+	defer outFile.Close()
+
+	chk, err := checkSource(arcFile, src, outFile, rp.Ct)
+	if err != nil {
+		return err
+	}
+	if err = outFile.Close(); err != nil {
+		return errtype.Join(ErrWriteOutBuf, err)
+	}
+	if _, err = arcFile.Seek(end, io.SeekStart); err != nil {
+		return errtype.Join(ErrSeek, err)
+	}
+
+	chk.File = fi
+	return reportFile(diskPath, chk, rp, verbose)
+}
+
+// Проверяет повторный файл, распаковывая данные
+// файла с тем же содержимым без записи на диск
+func CheckDuplicate(arcFile io.ReadSeeker, ct c.Type) (FileCheck, error) {
+	fi, err := readDupFile(arcFile)
+	if err != nil {
+		return FileCheck{}, err
+	}
+	end, err := arcFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return FileCheck{}, errtype.Join(ErrSeek, err)
+	}
+
+	src, _ := fi.DupOffset()
+	chk, err := checkSource(arcFile, src, nil, ct)
+	if err != nil {
+		return FileCheck{}, err
+	}
+	if _, err = arcFile.Seek(end, io.SeekStart); err != nil {
+		return FileCheck{}, errtype.Join(ErrSeek, err)
+	}
+
+	chk.File = fi
+	return chk, nil
+}
+
+// Распаковывает данные элемента файла по смещению src
+// в outFile или, если outFile == nil, только проверяет
+// их. Поврежденные блоки заполняются нулями
+func checkSource(arcFile io.ReadSeeker, src int64, outFile *os.File, ct c.Type) (FileCheck, error) {
+	if _, err := arcFile.Seek(src, io.SeekStart); err != nil {
+		return FileCheck{}, errtype.Join(ErrSeek, err)
+	}
+	typ, err := generic.ReadType(arcFile)
+	if err != nil {
+		return FileCheck{}, errtype.Join(ErrReadHeaderType, err)
+	}
+
+	var (
+		fi      *header.FileItem
+		dataPos int64
+	)
+	switch typ {
+	case header.File:
+		fi = &header.FileItem{}
+		if err = fi.Read(arcFile); err != nil && err != io.EOF {
+			return FileCheck{}, errtype.Join(ErrReadFileHeader, err)
+		}
+	case header.Chunked:
+		if fi, dataPos, err = readChunkedFile(arcFile); err != nil {
+			return FileCheck{}, err
+		}
+		if _, err = arcFile.Seek(dataPos, io.SeekStart); err != nil {
+			return FileCheck{}, errtype.Join(ErrSeek, err)
+		}
+	default:
+		return FileCheck{}, ErrHeaderType
+	}
+
+	// Дыры разреженного файла пропускаются при записи
+	var out io.Writer = io.Discard
+	if outFile != nil && len(fi.Holes()) > 0 {
+		out = &sparseWriter{f: outFile, holes: fi.Holes()}
+	} else if outFile != nil {
+		out = outFile
+	}
+
+	var chk FileCheck
+	if typ == header.Chunked {
+		chk, err = checkChunked(fi, src, arcFile, out, fi.CompType(ct))
+	} else {
+		chk, err = checkPlain(fi, arcFile, out, fi.CompType(ct))
+	}
+	if err != nil {
+		return FileCheck{}, err
+	}
+
+	// Дыра в конце файла создается изменением его размера
+	if outFile != nil && len(fi.Holes()) > 0 {
+		if err = outFile.Truncate(int64(fi.UcSize())); err != nil {
+			return FileCheck{}, errtype.Join(ErrWriteOutBuf, err)
+		}
+	}
+
+	return chk, nil
+}
+
+// Распаковывает данные отдельно сжатого файла fi
+// в out и возвращает результат проверки файла
+func checkPlain(fi *header.FileItem, arcFile io.ReadSeeker, out io.Writer, ct c.Type) (FileCheck, error) {
+	damages, err := decompressData(fi, arcFile, out, ct, generic.DamageZero)
+	if err != nil && err != ErrWrongDigest && err != ErrDecrypt {
+		return FileCheck{}, err
+	}
+
+	chk := FileCheck{File: fi, Damages: damages}
+	switch {
+	case len(damages) > 0 && err == ErrDecrypt:
+		chk.Err = ErrDecrypt
+	case len(damages) > 0:
+		chk.Err = ErrWrongCRC
+	case err == ErrWrongDigest:
+		chk.Err = ErrWrongDigest
+	case fi.IsDamaged():
+		chk.Err = ErrWrongCRC
+	}
+
+	return chk, nil
+}
+
+// Читает заголовок повторного файла, смещение элемента
+// файла с тем же содержимым и контрольную сумму содержимого
+func readDupFile(arcFile io.Reader) (*header.FileItem, error) {
+	fi := &header.FileItem{}
+	if err := fi.Read(arcFile); err != nil && err != io.EOF {
+		return nil, errtype.Join(ErrReadFileHeader, err)
+	}
+
+	var src int64
+	if err := filesystem.BinaryRead(arcFile, &src); err != nil {
+		return nil, errtype.Join(ErrReadDupOffset, err)
+	}
+	fi.SetDupOffset(src)
+
+	if err := checkDigest(arcFile, fi, nil); err != nil {
+		return nil, err
+	}
+
+	return fi, nil
+}
@@ -16,18 +16,36 @@ var (
 	ErrReadDecomp    = errors.ErrReadDecomp
 	ErrRestorePath   = errors.ErrRestorePath
 	ErrRestoreTime   = errors.ErrRestoreTime
+	ErrRestoreMode   = errors.ErrRestoreMode
+	ErrRestoreOwner  = errors.ErrRestoreOwner
 	ErrBufSize       = errors.ErrBufSize
+	ErrReadBlockFlag = errors.ErrReadBlockFlag
+	ErrReadBlockCRC  = errors.ErrReadBlockCRC
+	ErrBlockFlag     = errors.ErrBlockFlag
 	ErrCheckCRC      = errors.ErrCheckCRC
+	ErrChunkMissing  = errors.ErrChunkMissing
 )
 
 // Ошибки функции чтения
 var (
-	ErrReadCompressed = errors.ErrReadCompressed
-	ErrReadFileHeader = errors.ErrReadFileHeader
-	ErrReadSymHeader  = errors.ErrReadSymHeader
-	ErrReadCRC        = errors.ErrReadCRC
-	ErrSkipData       = errors.ErrSkipData
-	ErrReadHeaderType = errors.ErrReadHeaderType
-	ErrHeaderType     = errors.ErrHeaderType
-	ErrWrongCRC       = errors.ErrWrongCRC
+	ErrReadCompressed  = errors.ErrReadCompressed
+	ErrReadFileHeader  = errors.ErrReadFileHeader
+	ErrReadSolidHeader = errors.ErrReadSolidHeader
+	ErrReadSymHeader   = errors.ErrReadSymHeader
+	ErrReadDirHeader   = errors.ErrReadDirHeader
+	ErrReadLinkHeader  = errors.ErrReadLinkHeader
+	ErrReadSpecHeader  = errors.ErrReadSpecHeader
+	ErrReadCRC         = errors.ErrReadCRC
+	ErrReadDigest      = errors.ErrReadDigest
+	ErrReadChunks      = errors.ErrReadChunks
+	ErrReadDupOffset   = errors.ErrReadDupOffset
+	ErrSkipData        = errors.ErrSkipData
+	ErrReadHeaderType  = errors.ErrReadHeaderType
+	ErrHeaderType      = errors.ErrHeaderType
+	ErrWrongCRC        = errors.ErrWrongCRC
+	ErrWrongDigest     = errors.ErrWrongDigest
+	ErrReadIndex       = errors.ErrReadIndex
+	ErrNoIndex         = errors.ErrNoIndex
+	ErrIndexDamaged    = errors.ErrIndexDamaged
+	ErrDecrypt         = errors.ErrDecrypt
 )
@@ -0,0 +1,227 @@
+package decompress
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/gh0st17/archiver/arc/internal/crypt"
+	"github.com/gh0st17/archiver/arc/internal/generic"
+	"github.com/gh0st17/archiver/arc/internal/header"
+	"github.com/gh0st17/archiver/arc/internal/recovery"
+	"github.com/gh0st17/archiver/errtype"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Десериализуемый заголовок
+type headerReader interface {
+	header.Header
+	Read(io.Reader) error
+}
+
+// Возвращает пустой заголовок типа typ
+func newHeader(typ header.HeaderType) (headerReader, error) {
+	switch typ {
+	case header.File:
+		return &header.FileItem{}, nil
+	case header.Symlink:
+		return &header.SymItem{}, nil
+	case header.Directory:
+		return &header.DirItem{}, nil
+	case header.Hardlink:
+		return &header.LinkItem{}, nil
+	case header.Special:
+		return &header.SpecialItem{}, nil
+	default:
+		return nil, ErrHeaderType
+	}
+}
+
+// Читает индекс архива по завершающей записи в конце
+// arcFile, которая предшествует коду аутентификации
+// скрытого индекса, записи подписи и записи
+// восстановления, если они есть. Смещения элементов
+// должны находиться между arcLenH и началом индекса.
+// Возвращает [ErrNoIndex],
+// если завершающей записи нет, и [ErrIndexDamaged],
+// если индекс поврежден или изменен
+func readIndex(arcFile io.ReadSeeker, arcLenH int64) ([]header.IndexEntry, error) {
+	var (
+		indexOffset int64
+		indexCRC    uint32
+		magic       uint32
+		trailer     = make([]byte, header.TrailerLen)
+		sum         = make([]byte, crypt.MACLen)
+	)
+
+	if !header.ArcFormat().Has(header.FeatIndex) {
+		return nil, ErrNoIndex
+	}
+
+	dataEnd, err := arcFile.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, errtype.Join(ErrSeek, err)
+	}
+	if header.ArcFormat().Has(header.FeatRecovery) {
+		if dataEnd, err = recovery.End(arcFile); err != nil {
+			return nil, ErrNoIndex
+		}
+	}
+	if header.ArcFormat().Has(header.FeatSigned) {
+		dataEnd -= crypt.SignatureLen
+	}
+	if header.ArcFormat().Has(header.FeatHiddenHeaders) {
+		dataEnd -= crypt.MACLen
+	}
+
+	end, err := arcFile.Seek(dataEnd-header.TrailerLen, io.SeekStart)
+	if err != nil || end < arcLenH {
+		return nil, ErrNoIndex
+	}
+
+	if _, err = io.ReadFull(arcFile, trailer); err != nil {
+		return nil, errtype.Join(ErrReadIndex, err)
+	}
+	if header.ArcFormat().Has(header.FeatHiddenHeaders) {
+		if _, err = io.ReadFull(arcFile, sum); err != nil {
+			return nil, errtype.Join(ErrReadIndex, err)
+		}
+	}
+
+	tr := bytes.NewReader(trailer)
+	if err = filesystem.BinaryRead(tr, &indexOffset); err != nil {
+		return nil, errtype.Join(ErrReadIndex, err)
+	}
+	if err = filesystem.BinaryRead(tr, &indexCRC); err != nil {
+		return nil, errtype.Join(ErrReadIndex, err)
+	}
+	if err = filesystem.BinaryRead(tr, &magic); err != nil {
+		return nil, errtype.Join(ErrReadIndex, err)
+	}
+
+	if magic != header.IndexMagic {
+		return nil, ErrNoIndex
+	}
+	if indexOffset <= arcLenH || indexOffset > end {
+		return nil, ErrIndexDamaged
+	}
+
+	if _, err = arcFile.Seek(indexOffset, io.SeekStart); err != nil {
+		return nil, errtype.Join(ErrSeek, err)
+	}
+	index := make([]byte, end-indexOffset)
+	if _, err = io.ReadFull(arcFile, index); err != nil {
+		return nil, errtype.Join(ErrReadIndex, err)
+	}
+	if generic.Checksum(index) != indexCRC {
+		return nil, ErrIndexDamaged
+	}
+	if mac := generic.IndexMAC(indexOffset, index, trailer); mac != nil && !crypt.CheckMAC(mac, sum) {
+		return nil, ErrIndexDamaged
+	}
+
+	entries, err := parseIndex(bytes.NewReader(index), arcLenH, indexOffset)
+	if err != nil {
+		return nil, errtype.Join(ErrIndexDamaged, err)
+	}
+
+	return entries, nil
+}
+
+// Разбирает записи индекса из r
+func parseIndex(r io.Reader, arcLenH, indexOffset int64) (entries []header.IndexEntry, err error) {
+	var count uint32
+
+	if err = filesystem.BinaryRead(r, &count); err != nil {
+		return nil, err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		var (
+			offset int64
+			cSize  header.Size
+			crc    uint32
+			typ    header.HeaderType
+			h      headerReader
+		)
+
+		if err = filesystem.BinaryRead(r, &offset); err != nil {
+			return nil, err
+		}
+		if err = filesystem.BinaryRead(r, &cSize); err != nil {
+			return nil, err
+		}
+		if err = filesystem.BinaryRead(r, &crc); err != nil {
+			return nil, err
+		}
+		if err = filesystem.BinaryRead(r, &typ); err != nil {
+			return nil, err
+		}
+
+		if offset < arcLenH || offset >= indexOffset {
+			return nil, ErrIndexDamaged
+		}
+
+		if h, err = newHeader(typ); err != nil {
+			return nil, err
+		}
+		if err = h.Read(r); err != nil {
+			return nil, err
+		}
+
+		if fi, ok := h.(*header.FileItem); ok {
+			fi.SetCSize(cSize)
+			fi.SetCRC(crc)
+			if err = checkDigest(r, fi, nil); err != nil {
+				return nil, err
+			}
+			if err = readSolidOffset(r, fi); err != nil {
+				return nil, err
+			}
+			if err = readIndexChunks(r, fi); err != nil {
+				return nil, err
+			}
+		}
+
+		entries = append(entries, header.IndexEntry{Offset: offset, Header: h})
+	}
+
+	return entries, nil
+}
+
+// Читает смещение данных файла fi в solid-блоке,
+// если в архиве есть solid-блоки
+func readSolidOffset(r io.Reader, fi *header.FileItem) error {
+	if !header.ArcFormat().Has(header.FeatSolid) {
+		return nil
+	}
+
+	var off int64
+	if err := filesystem.BinaryRead(r, &off); err != nil {
+		return err
+	}
+	if off >= 0 {
+		fi.SetSolidOffset(off)
+	} else if off != -1 {
+		return ErrIndexDamaged
+	}
+
+	return nil
+}
+
+// Читает список фрагментов файла fi с фрагментами,
+// если в архиве есть дедупликация
+func readIndexChunks(r io.Reader, fi *header.FileItem) error {
+	if !header.ArcFormat().Has(header.FeatDedup) {
+		return nil
+	}
+
+	var chunked bool
+	if err := filesystem.BinaryRead(r, &chunked); err != nil {
+		return err
+	}
+	if chunked {
+		return fi.ReadChunks(r)
+	}
+
+	return nil
+}
@@ -1,9 +1,9 @@
 package decompress
 
 import (
+	"fmt"
 	"io"
 	"log"
-	fp "path/filepath"
 	"sort"
 
 	"github.com/gh0st17/archiver/arc/internal/generic"
@@ -12,17 +12,86 @@ import (
 	"github.com/gh0st17/archiver/filesystem"
 )
 
-// Читает заголовки из архива, определяет смещение данных
+// Читает заголовки из архива и сортирует их по пути
 func ReadHeaders(arcFile io.ReadSeeker, arcLenH int64) ([]header.Header, error) {
-	var headers []header.Header
+	entries, err := ReadEntries(arcFile, arcLenH)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make([]header.Header, len(entries))
+	for i, e := range entries {
+		headers[i] = e.Header
+	}
+	sort.Sort(header.ByPathInArc(headers))
+
+	return headers, nil
+}
+
+// Читает заголовки элементов архива вместе с их смещениями.
+// Заголовки берутся из индекса архива, а при его отсутствии
+// или повреждении определяются полным сканированием архива,
+// начиная с arcLenH. Позиция в arcFile сохраняется
+func ReadEntries(arcFile io.ReadSeeker, arcLenH int64) ([]header.IndexEntry, error) {
+	// Сохраняем позицию каретки
+	pos, err := arcFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errtype.Join(ErrSeek, err)
+	}
+	// Восстанавливаем позицию каретки
+	defer arcFile.Seek(pos, io.SeekStart)
+
+	entries, err := readIndex(arcFile, arcLenH)
+	if err == nil {
+		return entries, nil
+	} else if err != ErrNoIndex {
+		fmt.Println("Индекс архива поврежден, выполняю полное сканирование")
+		log.Println("Ошибка чтения индекса:", err)
+	}
+
+	if _, err = arcFile.Seek(arcLenH, io.SeekStart); err != nil {
+		return nil, errtype.Join(ErrSeek, err)
+	}
+
+	return scanEntries(arcFile)
+}
+
+// Определяет заголовки и их смещения, проходя
+// по всем элементам архива, включая их данные
+func scanEntries(arcFile io.ReadSeeker) ([]header.IndexEntry, error) {
+	var entries []header.IndexEntry
 
 	handler := func(typ header.HeaderType, arcFile io.ReadSeeker) (err error) {
 		var h header.Header
+
+		// Тип заголовка уже прочитан
+		offset, err := generic.ElemOffset(arcFile)
+		if err != nil {
+			return errtype.Join(ErrSeek, err)
+		}
+
 		switch typ {
 		case header.File:
 			h, err = readFileHeader(arcFile)
 		case header.Symlink:
 			h, err = readSymHeader(arcFile)
+		case header.Directory:
+			h, err = readDirHeader(arcFile)
+		case header.Hardlink:
+			h, err = readLinkHeader(arcFile)
+		case header.Special:
+			h, err = readSpecialHeader(arcFile)
+		case header.Solid:
+			// Файлы блока имеют смещение заголовка блока
+			var files []*header.FileItem
+			files, err = readSolidHeader(arcFile)
+			for _, fi := range files {
+				entries = append(entries, header.IndexEntry{Offset: offset, Header: fi})
+			}
+		case header.Chunked:
+			h, _, err = readChunkedFile(arcFile)
+		case header.Duplicate:
+			h, err = readDupFile(arcFile)
 		default:
 			return ErrHeaderType
 		}
@@ -31,24 +100,16 @@ func ReadHeaders(arcFile io.ReadSeeker, arcLenH int64) ([]header.Header, error)
 			return errtype.Join(ErrReadHeaders, err)
 		}
 		if h != nil {
-			headers = append(headers, h)
+			entries = append(entries, header.IndexEntry{Offset: offset, Header: h})
 		}
 		return nil
 	}
 
-	// Сохраняем позицию каретки
-	pos, err := arcFile.Seek(0, io.SeekCurrent)
-	if err = generic.ProcessHeaders(arcFile, handler); err != nil {
+	if err := generic.ProcessHeaders(arcFile, handler); err != nil {
 		return nil, errtype.Join(ErrReadHeaderType, err)
 	}
-	// Восстанавливаем позицию каретки
-	arcFile.Seek(pos, io.SeekStart)
-
-	dirs := insertDirs(headers)
-	headers = append(headers, dirs...)
-	sort.Sort(header.ByPathInArc(headers))
 
-	return headers, nil
+	return entries, nil
 }
 
 // Читает заголовок файла из arcFile и возвращает его
@@ -68,7 +129,7 @@ func readFileHeader(arcFile io.ReadSeeker) (*header.FileItem, error) {
 
 	pos, _ = arcFile.Seek(0, io.SeekCurrent)
 	log.Println("Читаю размер сжатых данных с позиции:", pos)
-	if dataSize, err = skipFileData(arcFile, false); err == io.EOF {
+	if dataSize, err = skipFileData(arcFile, file, false); err == io.EOF {
 		return nil, err
 	} else if err != nil {
 		return nil, errtype.Join(ErrSkipData, err)
@@ -80,9 +141,45 @@ func readFileHeader(arcFile io.ReadSeeker) (*header.FileItem, error) {
 	}
 	file.SetCRC(crc)
 
+	if err = checkDigest(arcFile, file, nil); err != nil {
+		return nil, err
+	}
+
 	return file, nil
 }
 
+// Читает заголовок solid-блока из arcFile, пропускает
+// данные блока и возвращает заголовки его файлов
+func readSolidHeader(arcFile io.ReadSeeker) ([]*header.FileItem, error) {
+	var (
+		si  = &header.SolidItem{}
+		crc uint32
+	)
+
+	pos, _ := arcFile.Seek(0, io.SeekCurrent)
+	log.Println("Читаю заголовок solid-блока с позиции:", pos)
+	if err := si.Read(arcFile); err != nil {
+		return nil, errtype.Join(ErrReadSolidHeader, err)
+	}
+
+	dataSize, err := skipFileData(arcFile, si.Data(), false)
+	if err == io.EOF {
+		return nil, err
+	} else if err != nil {
+		return nil, errtype.Join(ErrSkipData, err)
+	}
+	if err = filesystem.BinaryRead(arcFile, &crc); err != nil {
+		return nil, errtype.Join(ErrReadCRC, err)
+	}
+	si.SetCSize(dataSize, crc)
+
+	if err = si.ReadDigestSums(arcFile); err != nil {
+		return nil, errtype.Join(ErrReadDigest, err)
+	}
+
+	return si.Files(), nil
+}
+
 // Читает заголовок символьной ссылки из arcFile и возвращает его
 func readSymHeader(arcFile io.ReadSeeker) (sym *header.SymItem, err error) {
 	sym = &header.SymItem{}
@@ -97,42 +194,52 @@ func readSymHeader(arcFile io.ReadSeeker) (sym *header.SymItem, err error) {
 	return sym, nil
 }
 
-// Просматривает срез с заголовками headers, выделяет
-// из них уникальные пути к директориям и возвращает их
-func insertDirs(headers []header.Header) []header.Header {
-	var (
-		dirs  []header.Header
-		seen  = map[string]struct{}{}
-		parts []string
-		path  string
-	)
+// Читает заголовок директории из arcFile и возвращает его
+func readDirHeader(arcFile io.ReadSeeker) (dir *header.DirItem, err error) {
+	dir = &header.DirItem{}
+	pos, _ := arcFile.Seek(0, io.SeekCurrent)
+	log.Println("Читаю заголовок директории с позиции:", pos)
+	if err = dir.Read(arcFile); err == io.EOF {
+		return nil, err
+	} else if err != nil {
+		return nil, errtype.Join(ErrReadDirHeader, err)
+	}
 
-	for _, h := range headers {
-		if _, ok := h.(*header.SymItem); ok {
-			continue // Пропускаем символьные ссылки
-		}
+	return dir, nil
+}
 
-		path = fp.Dir(h.PathInArc())
-		if _, ok := seen[path]; ok {
-			continue // Такой путь уже есть
-		}
+// Читает заголовок жесткой ссылки из arcFile и возвращает его
+func readLinkHeader(arcFile io.ReadSeeker) (link *header.LinkItem, err error) {
+	link = &header.LinkItem{}
+	pos, _ := arcFile.Seek(0, io.SeekCurrent)
+	log.Println("Читаю заголовок жесткой ссылки с позиции:", pos)
+	if err = link.Read(arcFile); err == io.EOF {
+		return nil, err
+	} else if err != nil {
+		return nil, errtype.Join(ErrReadLinkHeader, err)
+	}
 
-		parts = filesystem.SplitPath(path)
-		path = ""
-		for _, p := range parts {
-			path = fp.Join(path, p)
-			if _, ok := seen[path]; !ok {
-				seen[path] = struct{}{} // Такого пути нет
-				dirs = append(dirs, header.NewDirItem(fp.ToSlash(path)))
-			}
-		}
+	return link, nil
+}
+
+// Читает заголовок специального файла из arcFile и возвращает его
+func readSpecialHeader(arcFile io.ReadSeeker) (spec *header.SpecialItem, err error) {
+	spec = &header.SpecialItem{}
+	pos, _ := arcFile.Seek(0, io.SeekCurrent)
+	log.Println("Читаю заголовок специального файла с позиции:", pos)
+	if err = spec.Read(arcFile); err == io.EOF {
+		return nil, err
+	} else if err != nil {
+		return nil, errtype.Join(ErrReadSpecHeader, err)
 	}
 
-	return dirs
+	return spec, nil
 }
 
-// Пропускает файл в читателе файла архива
-func skipFileData(arcFile io.ReadSeeker, skipCRC bool) (read header.Size, err error) {
+// Пропускает данные файла fi в читателе файла архива.
+// Если skipCRC == true, то пропускаются также CRC и
+// контрольная сумма содержимого
+func skipFileData(arcFile io.ReadSeeker, fi *header.FileItem, skipCRC bool) (read header.Size, err error) {
 	var bufferSize int64
 
 	for {
@@ -149,6 +256,12 @@ func skipFileData(arcFile io.ReadSeeker, skipCRC bool) (read header.Size, err er
 		}
 
 		read += header.Size(bufferSize)
+		if header.ArcFormat().Has(header.FeatBlockFlags) {
+			bufferSize++ // Флаг блока
+		}
+		if header.ArcFormat().Has(header.FeatBlockCRC) {
+			bufferSize += 4 // CRC блока
+		}
 
 		if _, err = arcFile.Seek(bufferSize, io.SeekCurrent); err != nil {
 			return 0, errtype.Join(ErrSeek, err)
@@ -156,7 +269,8 @@ func skipFileData(arcFile io.ReadSeeker, skipCRC bool) (read header.Size, err er
 	}
 
 	if skipCRC {
-		if _, err = arcFile.Seek(4, io.SeekCurrent); err != nil {
+		footer := int64(4 + fi.Digest().Type.Size())
+		if _, err = arcFile.Seek(footer, io.SeekCurrent); err != nil {
 			return 0, errtype.Join(ErrSeek, err)
 		}
 	}
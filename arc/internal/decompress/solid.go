@@ -0,0 +1,425 @@
+package decompress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	fp "path/filepath"
+
+	"github.com/gh0st17/archiver/arc/internal/generic"
+	"github.com/gh0st17/archiver/arc/internal/header"
+	"github.com/gh0st17/archiver/arc/internal/userinput"
+	c "github.com/gh0st17/archiver/compressor"
+	"github.com/gh0st17/archiver/errtype"
+)
+
+// Результат проверки файла solid-блока
+type FileCheck struct {
+	File    *header.FileItem
+	Damages []Damage // Поврежденные области файла
+	// [ErrWrongCRC], [ErrWrongDigest], [ErrDecrypt] или nil
+	Err error
+}
+
+// Восстанавливает файлы solid-блока из архива.
+//
+// Распаковываются только файлы, для путей которых want
+// возвращает true, но данные блока распаковываются
+// целиком. Поврежденные блоки данных заполняются нулями,
+// чтобы не сместить данные следующих файлов, а затем
+// обрабатываются в каждом файле согласно режиму
+// распаковки поврежденных блоков
+func RestoreSolid(arcFile io.ReadSeeker, rp generic.RestoreParams, verbose bool, want func(string) bool) error {
+	si := &header.SolidItem{}
+	if err := si.Read(arcFile); err != nil {
+		return errtype.Join(ErrReadSolidHeader, err)
+	}
+
+	var (
+		files = si.Files()
+		data  = si.Data()
+		ct    = data.CompType(rp.Ct)
+		paths = make([]string, len(files))
+	)
+
+	if rp.Integ { // --xinteg
+		// Блок с поврежденными блоками данных восстанавливается
+		// частично, иначе все файлы блока пропускаются
+		pos, _ := arcFile.Seek(0, io.SeekCurrent)
+		if _, damages, err := CheckCRC(arcFile, data, ct); fileDamaged(err) && len(damages) == 0 {
+			for _, fi := range files {
+				if want(fi.PathInArc()) {
+					fmt.Printf("Пропускаю поврежденный '%s'\n", fi.PathOnDisk())
+				}
+			}
+			if err = si.ReadDigestSums(arcFile); err != nil {
+				return errtype.Join(ErrReadDigest, err)
+			}
+			return nil
+		} else if err != nil && !fileDamaged(err) {
+			return errtype.Join(ErrCheckCRC, err)
+		}
+		arcFile.Seek(pos, io.SeekStart)
+	}
+
+	for i, fi := range files {
+		if !want(fi.PathInArc()) {
+			continue
+		}
+
+		var err error
+		if paths[i], err = outFilePath(fi, rp); err != nil {
+			return err
+		}
+	}
+
+	sw := &solidWriter{files: files, paths: paths}
+	checks, err := checkSolid(arcFile, si, sw, ct)
+	if err != nil {
+		return err
+	}
+
+	for i, chk := range checks {
+		if paths[i] == "" {
+			continue
+		}
+		if err = reportFile(paths[i], chk, rp, verbose); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Проверяет CRC данных solid-блока si и контрольные
+// суммы содержимого его файлов. Если у файлов блока
+// нет контрольных сумм содержимого, то данные блока
+// не распаковываются
+func CheckSolid(arcFile io.ReadSeeker, si *header.SolidItem, ct c.Type) ([]FileCheck, error) {
+	files := si.Files()
+	for _, fi := range files {
+		if fi.Digest().Type != header.DigestNone {
+			return checkSolid(arcFile, si, &solidWriter{files: files}, ct)
+		}
+	}
+
+	_, damages, err := CheckCRC(arcFile, si.Data(), ct)
+	if err != nil && err != ErrWrongCRC && err != ErrDecrypt {
+		return nil, err
+	}
+	if rerr := si.ReadDigestSums(arcFile); rerr != nil {
+		return nil, errtype.Join(ErrReadDigest, rerr)
+	}
+
+	return solidChecks(si, damages, err, err == ErrWrongCRC, nil), nil
+}
+
+// Распаковывает данные solid-блока si в писатель sw,
+// читает контрольные суммы содержимого файлов блока
+// и возвращает результаты проверки файлов
+func checkSolid(arcFile io.ReadSeeker, si *header.SolidItem, sw *solidWriter, ct c.Type) ([]FileCheck, error) {
+	data := si.Data()
+
+	// Позиции данных файлов не должны сместиться
+	damages, err := decompressData(data, arcFile, sw, ct, generic.DamageZero)
+	if err != nil && err != ErrDecrypt {
+		sw.abort()
+		return nil, err
+	}
+	if cerr := sw.Close(); cerr != nil {
+		return nil, cerr
+	}
+
+	if rerr := si.ReadDigestSums(arcFile); rerr != nil {
+		return nil, errtype.Join(ErrReadDigest, rerr)
+	}
+
+	return solidChecks(si, damages, err, data.IsDamaged(), sw.digests()), nil
+}
+
+// Определяет результаты проверки файлов solid-блока si
+// по поврежденным областям данных блока damages, ошибке
+// распаковки блока blockErr, признаку несовпадения CRC
+// блока crcBad и вычисленным контрольным суммам содержимого
+func solidChecks(si *header.SolidItem, damages []Damage, blockErr error, crcBad bool, digests []*header.Digest) []FileCheck {
+	checks := make([]FileCheck, len(si.Files()))
+
+	for i, fi := range si.Files() {
+		off, _ := fi.SolidOffset()
+		end := off + int64(fi.DataSize())
+		checks[i].File = fi
+
+		for _, d := range damages {
+			if from, to := max(d.From, off), min(d.To, end); from < to {
+				checks[i].Damages = append(checks[i].Damages, newDamage(fi, from-off, to-from))
+			}
+		}
+
+		switch {
+		case len(checks[i].Damages) > 0 && blockErr == ErrDecrypt:
+			checks[i].Err = ErrDecrypt
+		case len(checks[i].Damages) > 0:
+			checks[i].Err = ErrWrongCRC
+		case digests != nil && digests[i] != nil && !digests[i].Equal(fi.Digest()):
+			checks[i].Err = ErrWrongDigest
+		case crcBad && len(damages) == 0:
+			checks[i].Err = ErrWrongCRC
+		}
+	}
+
+	return checks
+}
+
+// Печатает результат распаковки файла solid-блока или
+// файла с фрагментами по пути diskPath, обрабатывает его
+// поврежденные области и восстанавливает атрибуты файла
+func reportFile(diskPath string, chk FileCheck, rp generic.RestoreParams, verbose bool) error {
+	fi := chk.File
+	outPath := fp.Join(rp.OutputDir, fi.PathOnDisk())
+
+	if chk.Err == ErrDecrypt {
+		fmt.Printf("%s: %v\n", outPath, chk.Err)
+	}
+	if len(chk.Damages) > 0 {
+		if err := cutDamages(diskPath, chk.Damages, rp.Damaged); err != nil {
+			return errtype.Join(ErrWriteOutBuf, err)
+		}
+		printDamages(outPath, chk.Damages, rp.Damaged)
+	} else if chk.Err == ErrWrongDigest {
+		fmt.Printf("%s: %v\n", outPath, chk.Err)
+	} else if chk.Err == ErrWrongCRC {
+		fmt.Printf("%s: CRC сумма не совпадает\n", outPath)
+	} else if verbose {
+		fmt.Println(outPath)
+	}
+
+	if err := restoreAttrs(fi.Base, rp); err != nil {
+		return err
+	}
+
+	if err := fi.RestoreTime(rp.OutputDir); err != nil {
+		return errtype.Join(ErrRestoreTime, err)
+	}
+
+	return nil
+}
+
+// Обрабатывает поврежденные области файла path, которые
+// при распаковке были заполнены нулями, согласно режиму mode
+func cutDamages(path string, damages []Damage, mode generic.DamageMode) error {
+	switch mode {
+	case generic.DamageTruncate:
+		return os.Truncate(path, damages[0].From)
+	case generic.DamageSkip:
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var (
+			kept []byte
+			off  int64
+		)
+		for _, d := range damages {
+			kept = append(kept, content[off:min(d.From, int64(len(content)))]...)
+			off = min(d.To, int64(len(content)))
+		}
+		kept = append(kept, content[off:]...)
+
+		return os.WriteFile(path, kept, 0644)
+	}
+
+	return nil
+}
+
+// Определяет путь распаковки файла fi и создает его
+// директорию. Если файл уже существует и
+// его замена отклонена, то возвращается пустой путь
+func outFilePath(fi *header.FileItem, rp generic.RestoreParams) (string, error) {
+	if err := fi.RestorePath(rp.OutputDir); err != nil {
+		return "", errtype.Join(ErrRestorePath(fi.PathOnDisk()), err)
+	}
+
+	outPath := fp.Join(rp.OutputDir, fi.PathOnDisk())
+	diskPath, err := fi.OutPath(rp.OutputDir)
+	if err != nil {
+		return "", errtype.Join(ErrRestorePath(fi.PathOnDisk()), err)
+	}
+
+	if _, err = os.Stat(diskPath); err == nil && !*rp.ReplaceAll {
+		allFunc := func() {
+			*rp.ReplaceAll = true
+		}
+
+		if userinput.ReplacePrompt(outPath, allFunc, nil) {
+			return "", nil
+		}
+	}
+
+	return diskPath, nil
+}
+
+// Писатель распакованных данных solid-блока. Разделяет
+// данные по файлам блока согласно размерам их данных и
+// вычисляет контрольные суммы их содержимого. Файлы
+// открываются по очереди, чтобы большой блок не исчерпал
+// число открытых файлов
+type solidWriter struct {
+	files []*header.FileItem
+	// Пути распаковки файлов, пустой путь -- данные файла
+	// только проверяются. При paths == nil проверяются
+	// все файлы блока
+	paths []string
+	dws   []*header.DigestWriter
+	sums  []*header.Digest
+
+	cur  int   // Индекс текущего файла
+	left int64 // Оставшийся размер данных текущего файла
+	f    *os.File
+	out  io.Writer
+}
+
+// Реализация io.Writer
+func (sw *solidWriter) Write(p []byte) (n int, err error) {
+	n = len(p)
+
+	for len(p) > 0 {
+		if sw.out == nil {
+			if err = sw.next(); err != nil {
+				return n - len(p), err
+			}
+			if sw.out == nil { // Данные за пределами блока
+				break
+			}
+		}
+
+		chunk := p[:min(int64(len(p)), sw.left)]
+		if _, err = sw.out.Write(chunk); err != nil {
+			return n - len(p), err
+		}
+		sw.left -= int64(len(chunk))
+		p = p[len(chunk):]
+
+		if sw.left == 0 {
+			if err = sw.finish(); err != nil {
+				return n - len(p), err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// Открывает очередной файл блока с данными. Файлы
+// без данных открываются и сразу завершаются
+func (sw *solidWriter) next() error {
+	for sw.cur < len(sw.files) {
+		if err := sw.open(); err != nil {
+			return err
+		}
+		if sw.left > 0 {
+			return nil
+		}
+		if err := sw.finish(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Открывает текущий файл блока
+func (sw *solidWriter) open() (err error) {
+	if sw.dws == nil {
+		sw.dws = make([]*header.DigestWriter, len(sw.files))
+		sw.sums = make([]*header.Digest, len(sw.files))
+	}
+
+	fi := sw.files[sw.cur]
+	sw.left = int64(fi.DataSize())
+	sw.out = io.Discard
+
+	if sw.paths != nil && sw.paths[sw.cur] == "" {
+		return nil
+	}
+
+	var writers []io.Writer
+	if sw.paths != nil {
+		if sw.f, err = os.Create(sw.paths[sw.cur]); err != nil {
+			return errtype.Join(ErrCreateOutFile, err)
+		}
+
+		// Дыры разреженного файла пропускаются при записи
+		writers = append(writers, sw.f)
+		if len(fi.Holes()) > 0 {
+			writers[0] = &sparseWriter{f: sw.f, holes: fi.Holes()}
+		}
+	}
+
+	if sw.dws[sw.cur] = header.NewDigestWriter(fi); sw.dws[sw.cur] != nil {
+		writers = append(writers, sw.dws[sw.cur])
+	}
+	if len(writers) > 0 {
+		sw.out = io.MultiWriter(writers...)
+	}
+
+	return nil
+}
+
+// Завершает текущий файл блока
+func (sw *solidWriter) finish() (err error) {
+	fi := sw.files[sw.cur]
+	if dw := sw.dws[sw.cur]; dw != nil {
+		sum := dw.Digest()
+		sw.sums[sw.cur] = &sum
+	}
+
+	if sw.f != nil {
+		// Дыра в конце файла создается изменением его размера
+		if len(fi.Holes()) > 0 {
+			err = sw.f.Truncate(int64(fi.UcSize()))
+		}
+		if cerr := sw.f.Close(); err == nil && cerr != nil {
+			err = cerr
+		}
+		sw.f = nil
+	}
+
+	sw.out = nil
+	sw.cur++
+
+	if err != nil {
+		return errtype.Join(ErrWriteOutBuf, err)
+	}
+	return nil
+}
+
+// Завершает запись блока. Файлы, данные которых не
+// были записаны, например пустые файлы в конце блока,
+// также создаются
+func (sw *solidWriter) Close() error {
+	for sw.cur < len(sw.files) {
+		if sw.out == nil {
+			if err := sw.open(); err != nil {
+				return err
+			}
+		}
+		if err := sw.finish(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Закрывает текущий файл блока после ошибки распаковки
+func (sw *solidWriter) abort() {
+	if sw.f != nil {
+		sw.f.Close()
+		sw.f = nil
+	}
+}
+
+// Возвращает вычисленные контрольные суммы
+// содержимого файлов блока
+func (sw *solidWriter) digests() []*header.Digest { return sw.sums }
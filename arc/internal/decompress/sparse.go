@@ -0,0 +1,41 @@
+package decompress
+
+import (
+	"os"
+
+	"github.com/gh0st17/archiver/arc/internal/header"
+)
+
+// Писатель разреженного файла. Последовательно записывает
+// области данных, перемещаясь через дыры без записи нулей
+type sparseWriter struct {
+	f     *os.File
+	holes []header.Hole // Оставшиеся дыры
+	off   int64         // Текущая позиция в файле
+}
+
+// Реализация io.Writer
+func (sw *sparseWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		// Перепрыгиваем дыру, начинающуюся с текущей позиции
+		for len(sw.holes) > 0 && sw.holes[0].Offset <= sw.off {
+			sw.off = max(sw.off, sw.holes[0].Offset+sw.holes[0].Length)
+			sw.holes = sw.holes[1:]
+		}
+
+		chunk := p
+		if len(sw.holes) > 0 && sw.off+int64(len(chunk)) > sw.holes[0].Offset {
+			chunk = chunk[:sw.holes[0].Offset-sw.off]
+		}
+
+		w, err := sw.f.WriteAt(chunk, sw.off)
+		n += w
+		sw.off += int64(w)
+		if err != nil {
+			return n, err
+		}
+		p = p[w:]
+	}
+
+	return n, nil
+}
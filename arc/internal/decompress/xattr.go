@@ -0,0 +1,46 @@
+package decompress
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gh0st17/archiver/arc/internal/generic"
+	"github.com/gh0st17/archiver/arc/internal/header"
+)
+
+// Количество отказов восстановления расширенных атрибутов
+// по пространству имен и тексту ошибки
+var xattrFails = map[string]int{}
+
+// Восстанавливает расширенные атрибуты элемента,
+// запоминая отказы для итоговой сводки
+func restoreXattrs(b header.Base, rp generic.RestoreParams) {
+	for _, e := range b.RestoreXattrs(rp.OutputDir, rp.XattrFilter) {
+		xattrFails[e.Namespace()+": "+e.Err.Error()]++
+	}
+}
+
+// Печатает сводку отказов восстановления расширенных
+// атрибутов и сбрасывает ее
+func PrintXattrSummary() {
+	if len(xattrFails) == 0 {
+		return
+	}
+
+	var (
+		keys  []string
+		total int
+	)
+	for k, n := range xattrFails {
+		keys = append(keys, k)
+		total += n
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("Не удалось восстановить расширенные атрибуты (%d):\n", total)
+	for _, k := range keys {
+		fmt.Printf("  %s -- %d\n", k, xattrFails[k])
+	}
+
+	xattrFails = map[string]int{}
+}
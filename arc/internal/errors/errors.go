@@ -17,6 +17,14 @@ func ErrNotArc(path string) error {
 	return fmt.Errorf("'%s' не архив Arc", path)
 }
 
+func ErrVersion(version byte) error {
+	return fmt.Errorf("неподдерживаемая версия (%d) формата архива", version)
+}
+
+func ErrFeatures(features uint32) error {
+	return fmt.Errorf("архив использует неизвестные возможности (%#x)", features)
+}
+
 // Общие ошибки
 var (
 	ErrUnknownComp   = c.ErrUnknownComp
@@ -38,23 +46,42 @@ var (
 	ErrWriteArcHeaders   = fmt.Errorf("ошибка записи заголовка архива")
 	ErrWriteFileHeader   = fmt.Errorf("ошибка записи заголовка файла")
 	ErrWriteSymHeader    = fmt.Errorf("ошибка записи заголовка символической ссылки")
+	ErrWriteDirHeader    = fmt.Errorf("ошибка записи заголовка директории")
+	ErrWriteLinkHeader   = fmt.Errorf("ошибка записи заголовка жесткой ссылки")
+	ErrWriteSpecHeader   = fmt.Errorf("ошибка записи заголовка специального файла")
+	ErrWriteSolidHeader  = fmt.Errorf("ошибка записи заголовка solid-блока")
 	ErrCompressFile      = fmt.Errorf("ошибка сжатия файла")
 	ErrReadUncompressed  = fmt.Errorf("ошибка чтения несжатых блоков")
 	ErrCompress          = fmt.Errorf("ошибка сжатия буфферов")
 	ErrWriteBufLen       = fmt.Errorf("ошибка записи длины блока")
+	ErrWriteBlockFlag    = fmt.Errorf("ошибка записи флага блока")
+	ErrWriteBlockCRC     = fmt.Errorf("ошибка записи CRC блока")
 	ErrWriteCompressBuf  = fmt.Errorf("ошибка чтения из буфера сжатых данных")
 	ErrReadUncompressBuf = fmt.Errorf("ошибка чтения в несжатый буфер")
 	ErrWriteEOF          = fmt.Errorf("ошибка записи EOF (-1)")
 	ErrWriteCRC          = fmt.Errorf("ошибка записи CRC")
+	ErrWriteDigest       = fmt.Errorf("ошибка записи контрольной суммы содержимого")
+	ErrWriteChunks       = fmt.Errorf("ошибка записи списка фрагментов")
+	ErrWriteDupOffset    = fmt.Errorf("ошибка записи смещения файла с тем же содержимым")
 	ErrWriteCompressor   = fmt.Errorf("ошибка записи в компрессор")
 	ErrCloseCompressor   = fmt.Errorf("ошибка закрытия компрессора")
 	ErrFetchDirs         = fmt.Errorf("не могу получить директории")
+	ErrWriteIndex        = fmt.Errorf("ошибка записи индекса архива")
 
 	ErrLongPath = header.ErrLongPath
 
 	ErrOpenFileCompress = func(path string) error {
 		return fmt.Errorf("не могу открыть входной файл '%s' для сжатия", path)
 	}
+	ErrFindHoles = func(path string) error {
+		return fmt.Errorf("не могу найти дыры в файле '%s'", path)
+	}
+	ErrReadXattrs = func(path string) error {
+		return fmt.Errorf("не могу прочитать расширенные атрибуты '%s'", path)
+	}
+	ErrHashFile = func(path string) error {
+		return fmt.Errorf("не могу вычислить хеш содержимого '%s'", path)
+	}
 )
 
 // Ошибки при распаковке
@@ -66,10 +93,15 @@ var (
 	ErrDecompress     = fmt.Errorf("ошибка распаковки буферов")
 	ErrWriteOutBuf    = fmt.Errorf("ошибка записи в буфер выхода")
 	ErrReadCompLen    = fmt.Errorf("ошибка чтения размера блока")
+	ErrReadBlockFlag  = fmt.Errorf("ошибка чтения флага блока")
+	ErrReadBlockCRC   = fmt.Errorf("ошибка чтения CRC блока")
 	ErrReadCompBuf    = fmt.Errorf("ошибка чтения блока")
 	ErrDecompInit     = fmt.Errorf("ошибка иницализации декомпрессора")
 	ErrReadDecomp     = fmt.Errorf("ошибка чтения декомпрессора")
 	ErrRestoreTime    = fmt.Errorf("ошибка восставновления времени")
+	ErrRestoreMode    = fmt.Errorf("ошибка восстановления режима доступа")
+	ErrRestoreOwner   = fmt.Errorf("ошибка восстановления владельца")
+	ErrRestoreDirs    = fmt.Errorf("ошибка восстановления атрибутов директорий")
 
 	ErrRestorePath = func(path string) error {
 		return fmt.Errorf("не могу создать путь для '%s'", path)
@@ -78,6 +110,12 @@ var (
 	ErrBufSize = func(bufferSize int64) error {
 		return fmt.Errorf("некорректный размер (%d) блока сжатых данных", bufferSize)
 	}
+	ErrBlockFlag = func(flag byte) error {
+		return fmt.Errorf("некорректный флаг (%d) блока сжатых данных", flag)
+	}
+	ErrChunkMissing = func(id uint32) error {
+		return fmt.Errorf("фрагмент %d отсутствует в архиве", id)
+	}
 )
 
 // Ошибки проверки целостности
@@ -85,27 +123,101 @@ var (
 	ErrCheckFile = fmt.Errorf("ошибка проверки файла")
 	ErrCheckCRC  = fmt.Errorf("ошибка проверки CRC")
 	ErrWrongCRC  = fmt.Errorf("CRC сумма не совпадает")
+
+	ErrWrongDigest = fmt.Errorf("контрольная сумма содержимого не совпадает")
 )
 
 // Ошибки функции чтения
 var (
-	ErrOpenArc        = fmt.Errorf("не могу открыть файл архива")
-	ErrReadMagic      = fmt.Errorf("ошибка чтения сигнатуры")
-	ErrReadCompressed = fmt.Errorf("ошибка чтения сжатых блоков")
-	ErrReadFileHeader = fmt.Errorf("ошибка чтения заголовка файла")
-	ErrReadSymHeader  = fmt.Errorf("ошибка чтения заголовка символьной ссылки")
-	ErrReadCompSize   = fmt.Errorf("ошибка чтения размера сжатых данных")
-	ErrReadCRC        = fmt.Errorf("ошибка чтения CRC")
-	ErrSkipData       = fmt.Errorf("ошибка пропуска блока сжатых данных")
-	ErrReadHeaderType = fmt.Errorf("ошибка чтения типа")
-	ErrHeaderType     = fmt.Errorf("неизвестный тип")
-	ErrReadDict       = fmt.Errorf("ошибка чтения словаря")
+	ErrOpenArc         = fmt.Errorf("не могу открыть файл архива")
+	ErrReadMagic       = fmt.Errorf("ошибка чтения сигнатуры")
+	ErrReadVersion     = fmt.Errorf("ошибка чтения версии формата")
+	ErrReadCompType    = fmt.Errorf("ошибка чтения типа компрессора")
+	ErrReadFeatures    = fmt.Errorf("ошибка чтения флагов возможностей")
+	ErrReadCompressed  = fmt.Errorf("ошибка чтения сжатых блоков")
+	ErrReadFileHeader  = fmt.Errorf("ошибка чтения заголовка файла")
+	ErrReadSymHeader   = fmt.Errorf("ошибка чтения заголовка символьной ссылки")
+	ErrReadDirHeader   = fmt.Errorf("ошибка чтения заголовка директории")
+	ErrReadLinkHeader  = fmt.Errorf("ошибка чтения заголовка жесткой ссылки")
+	ErrReadSpecHeader  = fmt.Errorf("ошибка чтения заголовка специального файла")
+	ErrReadSolidHeader = fmt.Errorf("ошибка чтения заголовка solid-блока")
+	ErrReadCompSize    = fmt.Errorf("ошибка чтения размера сжатых данных")
+	ErrReadCRC         = fmt.Errorf("ошибка чтения CRC")
+	ErrReadDigest      = fmt.Errorf("ошибка чтения контрольной суммы содержимого")
+	ErrReadChunks      = fmt.Errorf("ошибка чтения списка фрагментов")
+	ErrReadDupOffset   = fmt.Errorf("ошибка чтения смещения файла с тем же содержимым")
+	ErrSkipData        = fmt.Errorf("ошибка пропуска блока сжатых данных")
+	ErrReadHeaderType  = fmt.Errorf("ошибка чтения типа")
+	ErrHeaderType      = fmt.Errorf("неизвестный тип")
+	ErrReadDict        = fmt.Errorf("ошибка чтения словаря")
+	ErrReadIndex       = fmt.Errorf("ошибка чтения индекса архива")
+	ErrNoIndex         = fmt.Errorf("индекс архива отсутствует")
+	ErrIndexDamaged    = fmt.Errorf("индекс архива поврежден")
 )
 
 // Ошибки функции записи
 var (
 	ErrCreateArc     = fmt.Errorf("не могу создать файл архива")
 	ErrWriteMagic    = fmt.Errorf("ошибка записи сигнатуры")
+	ErrWriteVersion  = fmt.Errorf("ошибка записи версии формата")
 	ErrWriteCompType = fmt.Errorf("ошибка записи типа компрессора")
+	ErrWriteFeatures = fmt.Errorf("ошибка записи флагов возможностей")
 	ErrFlushWrBuf    = fmt.Errorf("ошибка сброса буфера записи на диск")
 )
+
+// Ошибки записи восстановления
+var (
+	ErrWriteRecovery   = fmt.Errorf("ошибка записи данных восстановления")
+	ErrReadRecovery    = fmt.Errorf("ошибка чтения данных восстановления")
+	ErrNoRecovery      = fmt.Errorf("запись восстановления отсутствует")
+	ErrRecoveryDamaged = fmt.Errorf("запись восстановления повреждена")
+	ErrUnrepairable    = fmt.Errorf("повреждения нельзя исправить")
+	ErrRepair          = fmt.Errorf("ошибка восстановления архива")
+)
+
+// Ошибки шифрования
+var (
+	ErrNoPassphrase     = fmt.Errorf("пароль не задан")
+	ErrPassMismatch     = fmt.Errorf("пароли не совпадают")
+	ErrReadPassphrase   = fmt.Errorf("ошибка чтения пароля")
+	ErrWrongPassphrase  = fmt.Errorf("неверный пароль")
+	ErrEncryptInit      = fmt.Errorf("ошибка инициализации шифра")
+	ErrEncrypt          = fmt.Errorf("ошибка шифрования блока")
+	ErrDecrypt          = fmt.Errorf("блок данных изменен или поврежден")
+	ErrHeadersAuth      = fmt.Errorf("заголовки архива изменены или повреждены")
+	ErrReadCryptParams  = fmt.Errorf("ошибка чтения параметров шифрования")
+	ErrWriteCryptParams = fmt.Errorf("ошибка записи параметров шифрования")
+	ErrNoIdentity       = fmt.Errorf("ключ получателя не задан")
+	ErrWrongIdentity    = fmt.Errorf("ключ не подходит ни к одному получателю архива")
+	ErrReadKey          = fmt.Errorf("ошибка чтения ключа")
+	ErrWriteKey         = fmt.Errorf("ошибка записи ключа")
+	ErrKeyFormat        = func(path string) error {
+		return fmt.Errorf("'%s' не содержит ключей в ожидаемом формате", path)
+	}
+)
+
+// Ошибки подписи
+var (
+	ErrSign            = fmt.Errorf("ошибка подписи архива")
+	ErrReadSignature   = fmt.Errorf("ошибка чтения подписи архива")
+	ErrNotSigned       = fmt.Errorf("архив не подписан")
+	ErrBadSignature    = fmt.Errorf("подпись архива неверна")
+	ErrUntrustedSigner = fmt.Errorf("архив подписан недоверенным ключом")
+)
+
+// Ошибки томов
+var (
+	ErrSeekVolume    = fmt.Errorf("некорректное смещение в наборе томов")
+	ErrVolumeMissing = func(path string) error {
+		return fmt.Errorf("отсутствует том '%s'", path)
+	}
+	ErrVolumeHeader = func(path string) error {
+		return fmt.Errorf("'%s' не является томом архива", path)
+	}
+	ErrVolumeSet = func(path string) error {
+		return fmt.Errorf("том '%s' не относится к набору томов архива", path)
+	}
+	ErrVolumeSize = func(path string) error {
+		return fmt.Errorf("том '%s' обрезан", path)
+	}
+)
@@ -3,6 +3,7 @@ package generic
 import "github.com/gh0st17/archiver/arc/internal/errors"
 
 var (
-	ErrFlushWrBuf = errors.ErrFlushWrBuf
-	ErrReadDict   = errors.ErrReadDict
+	ErrFlushWrBuf  = errors.ErrFlushWrBuf
+	ErrReadDict    = errors.ErrReadDict
+	ErrHeadersAuth = errors.ErrHeadersAuth
 )
@@ -10,22 +10,68 @@ import (
 	"os"
 	"runtime"
 
+	"github.com/gh0st17/archiver/arc/internal/crypt"
 	"github.com/gh0st17/archiver/arc/internal/header"
 	c "github.com/gh0st17/archiver/compressor"
 	"github.com/gh0st17/archiver/errtype"
-	"github.com/gh0st17/archiver/filesystem"
 )
 
 type RestoreParams struct {
 	OutputDir string
 	DictPath  string
 	Integ     bool
-	Ct        c.Type  // Тип компрессора
-	Cl        c.Level // Уровень сжатия
+	NoPerm    bool // Не восстанавливать режим доступа
+	Owner     header.OwnerParams
+	Xattrs    bool // Сохранять расширенные атрибуты
+	SymAbs    bool // Сохранять абсолютные пути назначения ссылок
+	// Фильтр расширенных атрибутов при сжатии и распаковке
+	XattrFilter header.XattrFilter
+	Ct          c.Type  // Тип компрессора
+	Cl          c.Level // Уровень сжатия
+	// Правила выбора кодека для файлов при сжатии
+	CodecRules []header.CodecRule
+	// Режим записи блоков данных при сжатии
+	Blocks BlockMode
+	// Тип контрольной суммы содержимого файлов при сжатии
+	Digest header.DigestType
+	// Размер данных solid-блока при сжатии, 0 -- файлы
+	// сжимаются по отдельности
+	SolidSize int64
+	// Дедупликация фрагментов данных файлов при сжатии
+	Dedup bool
+	// Хранение данных файлов с одинаковым содержимым
+	// один раз при сжатии
+	DupFiles bool
+	// Обработка поврежденных блоков при распаковке
+	Damaged DamageMode
 	// Флаг замены файлов без подтверждения
 	ReplaceAll *bool
 }
 
+// Режим записи блоков данных файла
+type BlockMode byte
+
+const (
+	BlockAlways   BlockMode = iota // Всегда записывать сжатые блоки
+	BlockAdaptive                  // Хранить блок без сжатия, если сжатие его не уменьшает
+	BlockSample                    // Как BlockAdaptive, но по первому блоку решать для всего файла
+)
+
+// Обработка поврежденных блоков данных при распаковке
+type DamageMode byte
+
+const (
+	DamageZero     DamageMode = iota // Заполнять поврежденные блоки нулями
+	DamageTruncate                   // Обрезать файл по первому поврежденному блоку
+	DamageSkip                       // Пропускать поврежденные блоки
+)
+
+// Флаги блока данных
+const (
+	BlockCompressed byte = iota // Блок сжат
+	BlockStored                 // Блок хранится без сжатия
+)
+
 // Базовый размер буфера
 // для операции ввода вывода
 const BufferSize int = 1048576 // 1М
@@ -43,6 +89,19 @@ var (
 	decompressors    = make([]*c.Reader, ncpu)
 	writeBuf         *bytes.Buffer
 	dict             []byte
+	// Признаки блоков, хранящихся без сжатия
+	storedBlocks = make([]bool, ncpu)
+	// Признаки блоков с несовпадающей CRC
+	damagedBlocks = make([]bool, ncpu)
+	// Наборы компрессоров и декомпрессоров для
+	// каждого используемого кодека
+	compPools   = map[header.Codec][]*c.Writer{}
+	decompPools = map[c.Type][]*c.Reader{}
+	// Шифр блоков данных, nil для архива без шифрования
+	blockCipher *crypt.Cipher
+	// Шифр скрытых заголовков, nil для архива
+	// с открытыми заголовками
+	headerCipher *crypt.Cipher
 )
 
 func Ncpu() int                      { return ncpu }
@@ -50,10 +109,17 @@ func CompBuffers() []*bytes.Buffer   { return compressedBufs }
 func DecompBuffers() []*bytes.Buffer { return decompressedBufs }
 func Compressors() []*c.Writer       { return compressors }
 func Decompressors() []*c.Reader     { return decompressors }
+func StoredBlocks() []bool           { return storedBlocks }
+func DamagedBlocks() []bool          { return damagedBlocks }
 
 func WriteBuffer() *bytes.Buffer { return writeBuf }
 func Dict() []byte               { return dict }
 
+func Cipher() *crypt.Cipher     { return blockCipher }
+func SetCipher(c *crypt.Cipher) { blockCipher = c }
+
+func SetHeaderCipher(c *crypt.Cipher) { headerCipher = c }
+
 func Checksum(data []byte) uint32 { return crc32.Checksum(data, crct) }
 
 // Сбрасывает буфер данных для записи в w
@@ -75,17 +141,53 @@ func CheckBufferSize(bufferSize int64) bool {
 	return bufferSize < 0 || bufferSize>>1 > bufferSize
 }
 
-// Инициализирует компрессоры
+// Инициализирует компрессоры кодека архива
 func InitCompressors(rp RestoreParams) (err error) {
 	if err = LoadDict(rp); err != nil {
 		return err
 	}
 
-	for i := 0; i < ncpu; i++ { // Инициализация компрессоров
-		compressors[i], err = c.NewWriterDict(rp.Ct, dict, compressedBufs[i], rp.Cl)
-		if err != nil {
-			return err
+	compPools = map[header.Codec][]*c.Writer{}
+	ResetBuffers()
+	return SelectCompressors(header.Codec{Type: rp.Ct, Level: rp.Cl})
+}
+
+// Делает текущими компрессоры кодека codec,
+// создавая их при первом использовании
+func SelectCompressors(codec header.Codec) (err error) {
+	pool, ok := compPools[codec]
+	if !ok {
+		pool = make([]*c.Writer, ncpu)
+		for i := 0; i < ncpu; i++ {
+			pool[i], err = c.NewWriterDict(codec.Type, DictFor(codec.Type), compressedBufs[i], codec.Level)
+			if err != nil {
+				return err
+			}
 		}
+		compPools[codec] = pool
+	}
+
+	compressors = pool
+	return nil
+}
+
+// Делает текущими декомпрессоры типа ct. Декомпрессоры
+// создаются при первой загрузке в них данных
+func SelectDecompressors(ct c.Type) {
+	pool, ok := decompPools[ct]
+	if !ok {
+		pool = make([]*c.Reader, ncpu)
+		decompPools[ct] = pool
+	}
+
+	decompressors = pool
+}
+
+// Возвращает словарь для компрессора типа ct,
+// словарь используют только ZLib и Flate
+func DictFor(ct c.Type) []byte {
+	if ct == c.ZLib || ct == c.Flate {
+		return dict
 	}
 
 	return nil
@@ -102,11 +204,20 @@ func LoadDict(rp RestoreParams) (err error) {
 	return nil
 }
 
-// Сбрасывает декомпрессоры
-func ResetDecomp() {
+// Очищает буферы, которые могли остаться
+// заполненными после прерванной ошибкой обработки
+func ResetBuffers() {
 	for i := 0; i < ncpu; i++ {
-		decompressors[i] = nil
+		compressedBufs[i].Reset()
+		decompressedBufs[i].Reset()
 	}
+	writeBuf.Reset()
+}
+
+// Сбрасывает декомпрессоры
+func ResetDecomp() {
+	decompPools = map[c.Type][]*c.Reader{}
+	decompressors = make([]*c.Reader, ncpu)
 }
 
 // Прототип функции-обработчика заголовков
@@ -114,12 +225,12 @@ type ProcHeaderHandler = func(header.HeaderType, io.ReadSeeker) error
 
 // Универсальная функция обработки заголовков из arcFile
 func ProcessHeaders(arcFile io.ReadSeeker, handler ProcHeaderHandler) error {
-	var typ header.HeaderType
-
 	for {
-		err := filesystem.BinaryRead(arcFile, &typ) // Читаем тип заголовка
+		typ, err := ReadType(arcFile) // Читаем тип заголовка
 		if err == io.EOF {
 			return nil
+		} else if err == nil && typ == header.End { // Далее индекс архива
+			return nil
 		} else if err != nil {
 			return err
 		}
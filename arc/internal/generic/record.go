@@ -0,0 +1,111 @@
+package generic
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/gh0st17/archiver/arc/internal/crypt"
+	"github.com/gh0st17/archiver/arc/internal/header"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Максимальная длина скрытого заголовка элемента
+const maxHeaderLen = 64 << 20
+
+// Возвращает длину записи заголовка элемента до самого
+// заголовка: тип, а в архиве со скрытыми заголовками
+// еще длина заголовка и код аутентификации записи
+func HeaderPrefixLen() int64 {
+	if headerCipher == nil {
+		return 1
+	}
+	return 1 + 4 + crypt.MACLen
+}
+
+// Пишет в w заголовок элемента по смещению off, который
+// write сериализует вместе с типом. В архиве со скрытыми
+// заголовками после типа пишутся длина заголовка и код
+// аутентификации, охватывающий смещение, тип и заголовок
+func WriteHeader(w io.Writer, off int64, write func(io.Writer) error) error {
+	if headerCipher == nil {
+		return write(w)
+	}
+
+	rec := bytes.NewBuffer(nil)
+	if err := write(rec); err != nil {
+		return err
+	}
+	typ, body := rec.Bytes()[0], rec.Bytes()[1:]
+
+	if err := filesystem.BinaryWrite(w, typ); err != nil {
+		return err
+	}
+	if err := filesystem.BinaryWrite(w, uint32(len(body))); err != nil {
+		return err
+	}
+	if _, err := w.Write(headerCipher.HeaderMAC(off, typ, body)); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// Читает тип заголовка элемента из arcFile. В архиве со
+// скрытыми заголовками проверяется код аутентификации
+// записи заголовка, после чего позиция в arcFile
+// возвращается к началу заголовка. Возвращает
+// [ErrHeadersAuth], если запись изменена
+func ReadType(arcFile io.ReadSeeker) (typ header.HeaderType, err error) {
+	if headerCipher == nil {
+		err = filesystem.BinaryRead(arcFile, &typ)
+		return typ, err
+	}
+
+	off, err := arcFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if err = filesystem.BinaryRead(arcFile, &typ); err != nil {
+		return 0, err
+	}
+
+	var (
+		size uint32
+		sum  = make([]byte, crypt.MACLen)
+	)
+	if err = filesystem.BinaryRead(arcFile, &size); err != nil || size > maxHeaderLen {
+		return 0, ErrHeadersAuth
+	}
+	if _, err = io.ReadFull(arcFile, sum); err != nil {
+		return 0, ErrHeadersAuth
+	}
+	body := make([]byte, size)
+	if _, err = io.ReadFull(arcFile, body); err != nil {
+		return 0, ErrHeadersAuth
+	}
+	if !crypt.CheckMAC(headerCipher.HeaderMAC(off, byte(typ), body), sum) {
+		return 0, ErrHeadersAuth
+	}
+
+	if _, err = arcFile.Seek(-int64(size), io.SeekCurrent); err != nil {
+		return 0, err
+	}
+	return typ, nil
+}
+
+// Возвращает смещение элемента, тип заголовка
+// которого уже прочитан из arcFile
+func ElemOffset(arcFile io.Seeker) (int64, error) {
+	pos, err := arcFile.Seek(0, io.SeekCurrent)
+	return pos - HeaderPrefixLen(), err
+}
+
+// Вычисляет код аутентификации индекса index по смещению
+// off вместе с завершающей записью trailer. В архиве с
+// открытыми заголовками возвращает nil
+func IndexMAC(off int64, index, trailer []byte) []byte {
+	if headerCipher == nil {
+		return nil
+	}
+	return headerCipher.IndexMAC(off, index, trailer)
+}
@@ -5,8 +5,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"time"
 
+	"github.com/gh0st17/archiver/arc/internal/platform"
 	"github.com/gh0st17/archiver/filesystem"
 )
 
@@ -15,11 +15,6 @@ type PathProvider interface {
 	PathInArc() string  // Путь к элементу в архиве
 }
 
-type timeAttr struct {
-	atim time.Time // Последнее время доступа к элементу
-	mtim time.Time // Последнее время измения элемента
-}
-
 type basePaths struct {
 	pathOnDisk string // Путь к элементу на диске
 	pathInArc  string // Путь к элементу в архиве
@@ -28,16 +23,40 @@ type basePaths struct {
 func (b basePaths) PathOnDisk() string { return b.pathOnDisk }
 func (b basePaths) PathInArc() string  { return b.pathInArc }
 
+// Возвращает путь для восстановления элемента в outDir,
+// доступный даже при превышении [platform.MaxPath]
+func (b basePaths) OutPath(outDir string) (string, error) {
+	return platform.LongPath(filepath.Join(outDir, b.pathOnDisk))
+}
+
+// Максимальная длина пути в архиве. Архивы без
+// [FeatLongPaths] хранят пути не длиннее 1023 байт
+const (
+	MaxPathLen   = 32767
+	shortPathLen = 1023
+)
+
 // Дериализует путь из r
 func readPath(r io.Reader) (_ string, err error) {
-	var length int16
-
-	if err = filesystem.BinaryRead(r, &length); err != nil {
-		return "", err
+	var length int64
+
+	if arcFormat.Has(FeatLongPaths) {
+		var l uint32
+		if err = filesystem.BinaryRead(r, &l); err != nil {
+			return "", err
+		}
+		length = int64(l)
+	} else {
+		var l int16
+		if err = filesystem.BinaryRead(r, &l); err != nil {
+			return "", err
+		}
+		length = int64(l)
 	}
 
-	if length < 1 || length > 1023 {
-		return "", ErrPathLength(int64(length))
+	if length < 1 || length > MaxPathLen ||
+		!arcFormat.Has(FeatLongPaths) && length > shortPathLen {
+		return "", ErrPathLength(length)
 	}
 
 	pathBytes := make([]byte, length)
@@ -50,11 +69,15 @@ func readPath(r io.Reader) (_ string, err error) {
 
 // Сериализует путь path в w
 func writePath(w io.Writer, path string) (err error) {
+	if len(path) < 1 || len(path) > MaxPathLen {
+		return ErrPathLength(int64(len(path)))
+	}
+
 	// Пишем длину строки имени файла или директории
-	if err = filesystem.BinaryWrite(w, int16(len(path))); err != nil {
+	if err = filesystem.BinaryWrite(w, uint32(len(path))); err != nil {
 		return err
 	}
-	log.Println("arc.header.writePath: Записана длина пути:", int16(len(path)))
+	log.Println("arc.header.writePath: Записана длина пути:", len(path))
 
 	// Пишем имя файла или директории
 	if err = filesystem.BinaryWrite(w, []byte(path)); err != nil {
@@ -68,51 +91,87 @@ func writePath(w io.Writer, path string) (err error) {
 type Base struct {
 	basePaths
 	timeAttr
+	ownerAttr
+	mode   os.FileMode // Режим доступа и тип элемента
+	xattrs []Xattr     // Расширенные атрибуты
+	legacy bool        // Заголовок формата 1.0.x
 }
 
-// Создает новый [header.Base]
-func NewBase(pathOnDisk string, atim, mtim time.Time) (*Base, error) {
-	if len(pathOnDisk) > 1023 {
+// Биты режима, восстанавливаемые при распаковке
+const permMask = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// Создает новый [header.Base] из информации info об элементе
+func NewBase(pathOnDisk string, info os.FileInfo) (*Base, error) {
+	if len(pathOnDisk) > platform.MaxPath {
 		return nil, ErrLongPath(pathOnDisk)
 	}
 
 	pathInArc := filesystem.Clean(pathOnDisk)
+	uid, gid := platform.Owner(info)
 
 	return &Base{
 		basePaths{pathOnDisk, pathInArc},
-		timeAttr{atim, mtim},
+		newTimeAttr(platform.Timestamp(pathOnDisk, info)),
+		newOwnerAttr(uid, gid),
+		info.Mode(),
+		nil,
+		false,
 	}, nil
 }
 
+// Возвращает режим доступа и тип элемента
+func (b Base) Mode() os.FileMode { return b.mode }
+
 // Десериализует в себя данные из r
 func (b *Base) Read(r io.Reader) error {
 	var (
-		err      error
-		path     string
-		unixMtim int64
-		unixAtim int64
+		err    error
+		path   string
+		times  timeAttr
+		mode   uint32
+		owner  ownerAttr
+		xattrs []Xattr
 	)
 
+	if isLegacy() {
+		return b.readLegacy(r)
+	}
+
 	// Читаем имя файла
 	if path, err = readPath(r); err != nil {
 		return err
 	}
 
-	// Читаем время модификации
-	if err = filesystem.BinaryRead(r, &unixMtim); err != nil {
+	// Читаем временные метки
+	if err = times.read(r); err != nil {
+		return err
+	}
+
+	// Читаем режим доступа
+	if err = filesystem.BinaryRead(r, &mode); err != nil {
 		return err
 	}
 
-	// Читаем время доступа
-	if err = filesystem.BinaryRead(r, &unixAtim); err != nil {
+	// Читаем владельца и группу
+	if err = owner.read(r); err != nil {
 		return err
 	}
 
-	mtim, atim := time.Unix(unixMtim, 0), time.Unix(unixAtim, 0)
-	newBase, _ := NewBase(path, mtim, atim)
-	*b = *newBase
+	// Читаем расширенные атрибуты
+	if xattrs, err = readXattrs(r); err != nil {
+		return err
+	}
+
+	*b = Base{
+		basePaths{path, filesystem.Clean(path)},
+		times,
+		owner,
+		os.FileMode(mode),
+		xattrs,
+		false,
+	}
 
-	return err
+	return nil
 }
 
 // Сериализует данные полей в писатель w
@@ -122,26 +181,63 @@ func (b *Base) Write(w io.Writer) (err error) {
 		return err
 	}
 
-	atime, mtime := b.atim.Unix(), b.mtim.Unix()
+	// Пишем временные метки
+	if err = b.timeAttr.write(w); err != nil {
+		return err
+	}
+
+	// Пишем режим доступа
+	if err = filesystem.BinaryWrite(w, uint32(b.mode)); err != nil {
+		return err
+	}
 
-	// Пишем время модификации
-	if err = filesystem.BinaryWrite(w, mtime); err != nil {
+	// Пишем владельца и группу
+	if err = b.ownerAttr.write(w); err != nil {
 		return err
 	}
 
-	// Пишем имя время доступа
-	if err = filesystem.BinaryWrite(w, atime); err != nil {
+	// Пишем расширенные атрибуты
+	if err = writeXattrs(w, b.xattrs); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// Восстанавливает время доступа и модификации
+// с точностью до наносекунд
 func (b Base) RestoreTime(outDir string) error {
-	outDir = filepath.Join(outDir, b.pathOnDisk)
-	if err := os.Chtimes(outDir, b.atim, b.mtim); err != nil {
+	if b.legacy && b.mtim.IsZero() { // Время не сохранялось
+		return nil
+	}
+
+	outDir, err := b.OutPath(outDir)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	if b.mode&os.ModeSymlink != 0 {
+		return platform.Lutimes(outDir, b.atim, b.mtim)
+	}
+
+	return os.Chtimes(outDir, b.atim, b.mtim)
+}
+
+// Восстанавливает режим доступа, включая биты
+// setuid, setgid и sticky
+func (b Base) RestoreMode(outDir string) error {
+	if b.legacy { // Режим доступа не сохранялся
+		return nil
+	}
+
+	outDir, err := b.OutPath(outDir)
+	if err != nil {
+		return err
+	}
+
+	if b.mode&os.ModeSymlink != 0 {
+		return platform.Lchmod(outDir, b.mode&permMask)
+	}
+
+	return os.Chmod(outDir, b.mode&permMask)
 }
@@ -0,0 +1,67 @@
+package header
+
+import (
+	"io"
+
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Ссылка на фрагмент данных файла при дедупликации.
+// Номера фрагментов присваиваются по порядку их первого
+// появления в архиве, данные фрагмента хранятся в файле,
+// где он появился впервые
+type ChunkRef struct {
+	ID   uint32 // Номер фрагмента в архиве
+	Size uint32 // Размер фрагмента
+}
+
+// Возвращает фрагменты данных файла
+func (fi FileItem) Chunks() []ChunkRef { return fi.chunks }
+
+// Устанавливает фрагменты данных файла
+func (fi *FileItem) SetChunks(chunks []ChunkRef) { fi.chunks = chunks }
+
+// Десериализует список фрагментов данных файла из r.
+// Сумма размеров фрагментов должна совпадать с размером
+// данных файла
+func (fi *FileItem) ReadChunks(r io.Reader) (err error) {
+	var (
+		count uint32
+		total Size
+	)
+
+	if err = filesystem.BinaryRead(r, &count); err != nil {
+		return err
+	}
+	if Size(count) > fi.DataSize() {
+		return ErrChunks
+	}
+
+	fi.chunks = make([]ChunkRef, count)
+	for i := range fi.chunks {
+		if err = filesystem.BinaryRead(r, &fi.chunks[i]); err != nil {
+			return err
+		}
+		total += Size(fi.chunks[i].Size)
+	}
+	if total != fi.DataSize() {
+		return ErrChunks
+	}
+
+	return nil
+}
+
+// Сериализует список фрагментов данных файла в w
+func (fi FileItem) WriteChunks(w io.Writer) (err error) {
+	if err = filesystem.BinaryWrite(w, uint32(len(fi.chunks))); err != nil {
+		return err
+	}
+
+	for _, c := range fi.chunks {
+		if err = filesystem.BinaryWrite(w, c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
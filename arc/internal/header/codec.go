@@ -0,0 +1,104 @@
+package header
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+
+	c "github.com/gh0st17/archiver/compressor"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Кодек сжатия данных файла
+type Codec struct {
+	Type  c.Type  // Тип компрессора
+	Level c.Level // Уровень сжатия
+}
+
+// Правило выбора кодека для файлов, имя или путь
+// которых соответствует шаблону
+type CodecRule struct {
+	Pattern string // Шаблон имени или пути файла
+	Codec
+}
+
+// Проверяет соответствие пути в архиве шаблону правила.
+// Шаблон без '/' сравнивается с именем файла, регистр
+// не учитывается
+func (cr CodecRule) Match(pathInArc string) bool {
+	var (
+		pattern = strings.ToLower(cr.Pattern)
+		path    = strings.ToLower(pathInArc)
+	)
+
+	if !strings.Contains(pattern, "/") {
+		path = filepath.Base(path)
+	}
+
+	ok, _ := filepath.Match(pattern, path)
+	return ok
+}
+
+// Возвращает кодек первого подходящего для
+// pathInArc правила или def, если такого нет
+func SelectCodec(rules []CodecRule, pathInArc string, def Codec) Codec {
+	for _, rule := range rules {
+		if rule.Match(pathInArc) {
+			return rule.Codec
+		}
+	}
+
+	return def
+}
+
+// Возвращает кодек файла, nil для архивов
+// без кодеков файлов
+func (fi FileItem) Codec() *Codec { return fi.codec }
+
+// Устанавливает кодек файла
+func (fi *FileItem) SetCodec(codec Codec) { fi.codec = &codec }
+
+// Возвращает тип компрессора файла. Для архивов
+// без кодеков файлов возвращает тип компрессора
+// архива def
+func (fi FileItem) CompType(def c.Type) c.Type {
+	if fi.codec == nil {
+		return def
+	}
+
+	return fi.codec.Type
+}
+
+// Десериализует кодек из r
+func readCodec(r io.Reader) (*Codec, error) {
+	var (
+		ct c.Type
+		cl int8
+	)
+
+	if err := filesystem.BinaryRead(r, &ct); err != nil {
+		return nil, err
+	}
+	if err := filesystem.BinaryRead(r, &cl); err != nil {
+		return nil, err
+	}
+
+	if ct > c.Flate {
+		return nil, c.ErrUnknownComp
+	}
+
+	return &Codec{ct, c.Level(cl)}, nil
+}
+
+// Сериализует кодек codec в w
+func writeCodec(w io.Writer, codec *Codec) error {
+	if codec == nil {
+		return ErrNoCodec
+	}
+
+	if err := filesystem.BinaryWrite(w, codec.Type); err != nil {
+		return err
+	}
+
+	return filesystem.BinaryWrite(w, int8(codec.Level))
+}
@@ -0,0 +1,140 @@
+package header
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Тип контрольной суммы содержимого файла
+type DigestType byte
+
+const (
+	DigestNone   DigestType = iota // Без контрольной суммы
+	DigestCRC32C                   // CRC32 с полиномом Кастаньоли
+	DigestSHA256                   // SHA-256
+)
+
+// Реализация fmt.Stringer
+func (dt DigestType) String() string {
+	switch dt {
+	case DigestNone:
+		return "none"
+	case DigestCRC32C:
+		return "crc32c"
+	case DigestSHA256:
+		return "sha256"
+	default:
+		return "unknown"
+	}
+}
+
+// Возвращает размер контрольной суммы в байтах
+func (dt DigestType) Size() int {
+	switch dt {
+	case DigestCRC32C:
+		return crc32.Size
+	case DigestSHA256:
+		return sha256.Size
+	default:
+		return 0
+	}
+}
+
+// Создает вычислитель контрольной суммы,
+// для DigestNone возвращает nil
+func (dt DigestType) New() hash.Hash {
+	switch dt {
+	case DigestCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case DigestSHA256:
+		return sha256.New()
+	default:
+		return nil
+	}
+}
+
+// Контрольная сумма содержимого файла
+type Digest struct {
+	Type DigestType
+	Sum  []byte
+}
+
+// Сравнивает контрольные суммы
+func (d Digest) Equal(other Digest) bool {
+	return d.Type == other.Type && bytes.Equal(d.Sum, other.Sum)
+}
+
+// Реализация fmt.Stringer
+func (d Digest) String() string {
+	return fmt.Sprintf("  %s: %s", d.Type, hex.EncodeToString(d.Sum))
+}
+
+// Десериализует тип контрольной суммы из r
+func readDigestType(r io.Reader) (dt DigestType, err error) {
+	if err = filesystem.BinaryRead(r, &dt); err != nil {
+		return dt, err
+	}
+	if dt > DigestSHA256 {
+		return dt, ErrDigestType(dt)
+	}
+
+	return dt, nil
+}
+
+// Десериализует контрольную сумму содержимого из r.
+// Размер суммы определяется типом из заголовка файла
+func (fi *FileItem) ReadDigestSum(r io.Reader) error {
+	fi.digest.Sum = make([]byte, fi.digest.Type.Size())
+	_, err := io.ReadFull(r, fi.digest.Sum)
+	return err
+}
+
+// Сериализует контрольную сумму содержимого в w
+func (fi FileItem) WriteDigestSum(w io.Writer) error {
+	if len(fi.digest.Sum) != fi.digest.Type.Size() {
+		return ErrDigestSize(len(fi.digest.Sum))
+	}
+
+	return filesystem.BinaryWrite(w, fi.digest.Sum)
+}
+
+// Писатель, вычисляющий контрольную сумму содержимого
+// файла. Для разреженного файла содержимым считаются
+// его области данных, за которыми следует карта дыр,
+// поэтому большие дыры не замедляют вычисление
+type DigestWriter struct {
+	h     hash.Hash
+	typ   DigestType
+	holes []Hole
+}
+
+// Создает писатель контрольной суммы файла fi, если
+// для него задан тип контрольной суммы, иначе nil
+func NewDigestWriter(fi *FileItem) *DigestWriter {
+	if fi.digest.Type == DigestNone {
+		return nil
+	}
+
+	return &DigestWriter{h: fi.digest.Type.New(), typ: fi.digest.Type, holes: fi.holes}
+}
+
+// Реализация io.Writer
+func (dw *DigestWriter) Write(p []byte) (int, error) { return dw.h.Write(p) }
+
+// Возвращает контрольную сумму записанного содержимого
+func (dw *DigestWriter) Digest() Digest {
+	for _, h := range dw.holes {
+		filesystem.BinaryWrite(dw.h, h.Offset)
+		filesystem.BinaryWrite(dw.h, h.Length)
+	}
+	dw.holes = nil
+
+	return Digest{Type: dw.typ, Sum: dw.h.Sum(nil)}
+}
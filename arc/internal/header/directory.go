@@ -1,22 +1,57 @@
 package header
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gh0st17/archiver/filesystem"
+)
 
 // Описание директории
 type DirItem struct {
-	basePaths
+	Base
 }
 
 // Создает заголовок директории [header.DirItem]
-func NewDirItem(pathInArc string) *DirItem {
-	return &DirItem{basePaths{pathInArc, pathInArc}}
+func NewDirItem(base *Base) *DirItem {
+	return &DirItem{Base: *base}
+}
+
+// Десериализует заголовок директории из r
+func (di *DirItem) Read(r io.Reader) error {
+	return di.Base.Read(r)
+}
+
+// Сериализует заголовок директории в w
+func (di *DirItem) Write(w io.Writer) (err error) {
+	if err = filesystem.BinaryWrite(w, Directory); err != nil {
+		return err
+	}
+
+	return di.Base.Write(w)
+}
+
+// Создает директорию
+func (di DirItem) RestorePath(outDir string) error {
+	outDir, err := di.OutPath(outDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // Реализация fmt.Stringer
 func (di DirItem) String() string {
 	filename := prefix(di.pathInArc, nameWidth)
+	mtime := di.mtim.Format(dateFormat)
 
 	return fmt.Sprintf(
-		"%-*s", nameWidth, filename,
+		"%-*s  %6s  %6s  %7s  %s",
+		nameWidth, filename, "-", "-", "-", mtime,
 	)
 }
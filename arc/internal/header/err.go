@@ -3,6 +3,8 @@ package header
 import (
 	"fmt"
 	"path/filepath"
+
+	"github.com/gh0st17/archiver/arc/internal/platform"
 )
 
 var (
@@ -10,10 +12,32 @@ var (
 		return fmt.Errorf("некорректная длина (%d) пути элемента", length)
 	}
 
+	ErrXattrSize = func(name string, size uint32) error {
+		return fmt.Errorf("некорректный размер (%d) атрибута '%s'", size, name)
+	}
+
+	ErrHole = func(offset, length int64) error {
+		return fmt.Errorf("некорректная дыра (%d, %d) разреженного файла", offset, length)
+	}
+
+	ErrNoCodec = fmt.Errorf("кодек файла не задан")
+
+	ErrSolidFile  = fmt.Errorf("элемент solid-блока не является файлом")
+	ErrSolidCount = fmt.Errorf("solid-блок не содержит файлов")
+	ErrChunks     = fmt.Errorf("некорректный список фрагментов файла")
+
+	ErrDigestType = func(dt DigestType) error {
+		return fmt.Errorf("неизвестный тип (%d) контрольной суммы", dt)
+	}
+
+	ErrDigestSize = func(size int) error {
+		return fmt.Errorf("некорректный размер (%d) контрольной суммы", size)
+	}
+
 	ErrLongPath = func(path string) error {
 		return fmt.Errorf(
-			"длина пути к '%s' первышает максимально допустимую (1023)",
-			filepath.Base(path),
+			"длина пути к '%s' первышает максимально допустимую (%d)",
+			filepath.Base(path), platform.MaxPath,
 		)
 	}
 )
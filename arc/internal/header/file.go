@@ -16,6 +16,17 @@ type FileItem struct {
 	ucSize, cSize Size
 	crc           uint32
 	damaged       bool
+	id            *FileID // Идентификатор файла на диске
+	holes         []Hole  // Дыры разреженного файла
+	codec         *Codec  // Кодек сжатия данных
+	digest        Digest  // Контрольная сумма содержимого
+	// Смещение данных файла в распакованном solid-блоке
+	solidOff int64
+	solid    bool
+	chunks   []ChunkRef // Фрагменты данных при дедупликации
+	// Смещение элемента файла с тем же содержимым
+	dupOff int64
+	dup    bool
 }
 
 // Возвращает размер данных в несжатом виде
@@ -42,6 +53,36 @@ func (fi *FileItem) SetCRC(crc uint32) { fi.crc = crc }
 // Устанавливает флаг наличия повреждении
 func (fi *FileItem) SetDamaged(damaged bool) { fi.damaged = damaged }
 
+// Возвращает контрольную сумму содержимого
+func (fi FileItem) Digest() Digest { return fi.digest }
+
+// Устанавливает контрольную сумму содержимого
+func (fi *FileItem) SetDigest(d Digest) { fi.digest = d }
+
+// Устанавливает тип контрольной суммы содержимого
+func (fi *FileItem) SetDigestType(dt DigestType) { fi.digest.Type = dt }
+
+// Возвращает смещение данных файла в распакованном
+// solid-блоке и признак того, что файл входит в блок
+func (fi FileItem) SolidOffset() (int64, bool) { return fi.solidOff, fi.solid }
+
+// Устанавливает смещение данных файла в solid-блоке
+func (fi *FileItem) SetSolidOffset(off int64) { fi.solidOff, fi.solid = off, true }
+
+// Возвращает смещение элемента файла с тем же
+// содержимым и признак того, что файл повторный
+func (fi FileItem) DupOffset() (int64, bool) { return fi.dupOff, fi.dup }
+
+// Устанавливает смещение элемента файла с тем же содержимым
+func (fi *FileItem) SetDupOffset(off int64) { fi.dupOff, fi.dup = off, true }
+
+// Возвращает идентификатор файла на диске, если
+// у файла есть другие жесткие ссылки, иначе nil
+func (fi FileItem) FileID() *FileID { return fi.id }
+
+// Устанавливает идентификатор файла на диске
+func (fi *FileItem) SetFileID(id *FileID) { fi.id = id }
+
 // Создает заголовок файла [header.FileItem]
 func NewFileItem(base *Base, ucSize Size) *FileItem {
 	return &FileItem{Base: *base, ucSize: ucSize}
@@ -58,6 +99,29 @@ func (fi *FileItem) Read(r io.Reader) (err error) {
 		return err
 	}
 
+	if isLegacy() {
+		return nil
+	}
+
+	// Читаем карту дыр
+	if fi.holes, err = readHoles(r, fi.ucSize); err != nil {
+		return err
+	}
+
+	// Читаем кодек, если он сохраняется для файлов
+	if arcFormat.Has(FeatCodec) {
+		if fi.codec, err = readCodec(r); err != nil {
+			return err
+		}
+	}
+
+	// Читаем тип контрольной суммы содержимого
+	if arcFormat.Has(FeatDigest) {
+		if fi.digest.Type, err = readDigestType(r); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -65,6 +129,12 @@ func (fi *FileItem) Read(r io.Reader) (err error) {
 func (fi *FileItem) Write(w io.Writer) (err error) {
 	filesystem.BinaryWrite(w, File)
 
+	return fi.WriteBody(w)
+}
+
+// Сериализует заголовок файла без типа заголовка
+// для элементов, тип которых пишется отдельно
+func (fi *FileItem) WriteBody(w io.Writer) (err error) {
 	if err = fi.Base.Write(w); err != nil {
 		return err
 	}
@@ -74,12 +144,31 @@ func (fi *FileItem) Write(w io.Writer) (err error) {
 		return err
 	}
 
+	// Пишем карту дыр
+	if err = writeHoles(w, fi.holes); err != nil {
+		return err
+	}
+
+	// Пишем кодек
+	if err = writeCodec(w, fi.codec); err != nil {
+		return err
+	}
+
+	// Пишем тип контрольной суммы содержимого,
+	// сама сумма пишется после данных файла
+	if err = filesystem.BinaryWrite(w, fi.digest.Type); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // Восстанавливает путь к файлу
 func (fi FileItem) RestorePath(outDir string) error {
-	outDir = filepath.Join(outDir, fi.pathOnDisk)
+	outDir, err := fi.OutPath(outDir)
+	if err != nil {
+		return err
+	}
 	if err := os.MkdirAll(filepath.Dir(outDir), 0755); err != nil {
 		return err
 	}
@@ -0,0 +1,72 @@
+package header
+
+import "strconv"
+
+// Версия формата архива
+type Version byte
+
+const (
+	Legacy  Version = 1 // Формат 1.0.x без версии в заголовке архива
+	Current Version = 2 // Формат с версией и флагами возможностей
+)
+
+// Реализация fmt.Stringer
+func (v Version) String() string {
+	if v == Legacy {
+		return "1.0.x"
+	}
+	return strconv.Itoa(int(v))
+}
+
+// Признак версии в заголовке архива. В формате 1.0.x
+// на месте версии находится тип компрессора, который
+// не превышает 127
+const VersionMark byte = 0x80
+
+// Флаги возможностей архива, определяют наличие
+// необязательных частей архива
+type Features uint32
+
+const (
+	FeatIndex         Features = 1 << iota // Индекс в конце архива
+	FeatCodec                              // Кодек сжатия у каждого файла
+	FeatBlockFlags                         // Флаг сжатия у каждого блока данных
+	FeatLongPaths                          // Длина пути записывается в 4 байта
+	FeatDigest                             // Контрольная сумма содержимого файлов
+	FeatBlockCRC                           // CRC у каждого блока данных
+	FeatRecovery                           // Запись восстановления в конце архива
+	FeatEncrypted                          // Блоки данных зашифрованы
+	FeatHiddenHeaders                      // Заголовки элементов и индекс зашифрованы
+	FeatRecipients                         // Ключ данных зашифрован для получателей
+	FeatSigned                             // Подпись после индекса архива
+	FeatSolid                              // Solid-блоки с данными нескольких файлов
+	FeatDedup                              // Дедупликация фрагментов данных файлов
+	FeatDupFiles                           // Повторные файлы ссылаются на данные первого
+
+	// Возможности, известные этой версии программы
+	KnownFeatures = FeatIndex | FeatCodec | FeatBlockFlags |
+		FeatLongPaths | FeatDigest | FeatBlockCRC | FeatRecovery |
+		FeatEncrypted | FeatHiddenHeaders | FeatRecipients | FeatSigned |
+		FeatSolid | FeatDedup | FeatDupFiles
+)
+
+// Проверяет наличие возможностей f
+func (fs Features) Has(f Features) bool { return fs&f == f }
+
+// Формат архива
+type Format struct {
+	Version
+	Features
+}
+
+// Формат читаемого архива, определяет разбор заголовков
+var arcFormat = Format{Current, KnownFeatures}
+
+// Устанавливает формат читаемого архива
+func SetFormat(f Format) { arcFormat = f }
+
+// Возвращает формат читаемого архива
+func ArcFormat() Format { return arcFormat }
+
+// Проверяет, что читается архив формата 1.0.x
+func isLegacy() bool { return arcFormat.Version == Legacy }
@@ -0,0 +1,109 @@
+package header
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gh0st17/archiver/arc/internal/platform"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Идентификатор файла на диске для поиска жестких ссылок
+type FileID struct {
+	Dev, Ino uint64 // Устройство и индексный дескриптор
+}
+
+// Описание жесткой ссылки на ранее сохраненный файл
+type LinkItem struct {
+	Base
+	target string // Путь в архиве к первому сохраненному файлу
+}
+
+// Создает заголовок жесткой ссылки [header.LinkItem]
+// на файл с путем в архиве target
+func NewLinkItem(base *Base, target string) *LinkItem {
+	return &LinkItem{Base: *base, target: target}
+}
+
+// Возвращает путь в архиве к файлу, на который указывает ссылка
+func (li LinkItem) Target() string { return li.target }
+
+// Создает жесткую ссылку. Если создать ссылку не удалось,
+// например, из-за ограничений файловой системы, то
+// копирует файл. Возвращает true, если файл был скопирован
+func (li LinkItem) RestorePath(outDir string) (copied bool, err error) {
+	target, err := platform.LongPath(filepath.Join(outDir, li.target))
+	if err != nil {
+		return false, err
+	}
+
+	link, err := li.OutPath(outDir)
+	if err != nil {
+		return false, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		return false, err
+	}
+
+	if err = os.Remove(link); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+
+	if err = os.Link(target, link); err == nil {
+		return false, nil
+	}
+
+	if err = filesystem.CopyFile(target, link); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Реализация fmt.Stringer
+func (li LinkItem) String() string {
+	filename := prefix(li.pathInArc, nameWidth)
+	diff := terminalWidth - len([]rune(filename)) - 4
+	target := prefix(li.target, diff)
+
+	return fmt.Sprintf(
+		"%-s%-*s", filename+" => ",
+		diff, target,
+	)
+}
+
+// Десериализует в себя данные из r
+func (li *LinkItem) Read(r io.Reader) (err error) {
+	if err = li.Base.Read(r); err != nil {
+		return err
+	}
+
+	// Читаем путь к файлу, на который указывает ссылка
+	if li.target, err = readPath(r); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Сериализует данные полей в писатель w
+func (li *LinkItem) Write(w io.Writer) (err error) {
+	if err = filesystem.BinaryWrite(w, Hardlink); err != nil {
+		return err
+	}
+
+	if err = li.Base.Write(w); err != nil {
+		return err
+	}
+
+	// Пишем путь к файлу, на который указывает ссылка
+	if err = writePath(w, li.target); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -33,6 +33,13 @@ type HeaderType byte
 const (
 	Symlink HeaderType = iota
 	File
+	Directory
+	Hardlink
+	Special
+	End       // Конец элементов, за ним следует индекс архива
+	Solid     // Solid-блок: файлы с общими данными
+	Chunked   // Файл, данные которого разбиты на фрагменты
+	Duplicate // Файл с содержимым ранее записанного файла
 )
 
 type Header interface {
@@ -112,6 +119,22 @@ func PrintSummary(compressed, original Size) {
 	)
 }
 
+// Печатает размер данных файлов с фрагментами
+// и размер их уникальных фрагментов
+func PrintDedup(total, unique Size) {
+	ratio := float32(unique) / float32(total) * 100.0
+
+	if math.IsNaN(float64(ratio)) {
+		ratio = 0.0
+	}
+
+	fmt.Printf(
+		"%-*s  %6s  %6s  %7.2f\n",
+		nameWidth, "Дедупликация",
+		total, unique, ratio,
+	)
+}
+
 // Сокращает длинные имена файлов, добавляя '...' в начале
 func prefix(filename string, maxWidth int) string {
 	runes := []rune(filename)
@@ -0,0 +1,14 @@
+package header
+
+// Сигнатура завершающей записи индекса архива
+const IndexMagic uint32 = 0x58444E49
+
+// Длина завершающей записи индекса архива:
+// смещение индекса, CRC индекса и сигнатура
+const TrailerLen int64 = 16
+
+// Запись индекса архива
+type IndexEntry struct {
+	Offset int64 // Смещение заголовка элемента в архиве
+	Header
+}
@@ -0,0 +1,60 @@
+package header
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Десериализует в себя заголовок формата 1.0.x из r:
+// путь, время модификации и время доступа в секундах.
+// Остальные атрибуты в этом формате не сохранялись
+func (b *Base) readLegacy(r io.Reader) (err error) {
+	var (
+		path         string
+		mtime, atime int64
+	)
+
+	if path, err = readPath(r); err != nil {
+		return err
+	}
+	if err = filesystem.BinaryRead(r, &mtime); err != nil {
+		return err
+	}
+	if err = filesystem.BinaryRead(r, &atime); err != nil {
+		return err
+	}
+
+	*b = Base{
+		basePaths: basePaths{path, filesystem.Clean(path)},
+		timeAttr:  timeAttr{atim: time.Unix(atime, 0), mtim: time.Unix(mtime, 0)},
+		legacy:    true,
+	}
+
+	return nil
+}
+
+// Десериализует в себя заголовок символической ссылки
+// формата 1.0.x из r: путь, на который указывает ссылка,
+// и путь самой ссылки
+func (si *SymItem) readLegacy(r io.Reader) (err error) {
+	var target, path string
+
+	if target, err = readPath(r); err != nil {
+		return err
+	}
+	if path, err = readPath(r); err != nil {
+		return err
+	}
+
+	si.Base = Base{
+		basePaths: basePaths{path, filesystem.Clean(path)},
+		mode:      os.ModeSymlink | os.ModePerm,
+		legacy:    true,
+	}
+	si.target = target
+
+	return nil
+}
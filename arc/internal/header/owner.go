@@ -0,0 +1,233 @@
+package header
+
+import (
+	"io"
+	"os/user"
+	"strconv"
+	"sync"
+
+	"github.com/gh0st17/archiver/arc/internal/platform"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Режим восстановления владельца при распаковке
+type OwnerMode byte
+
+const (
+	OwnerNone OwnerMode = iota // Не восстанавливать
+	OwnerName                  // По имени, при его отсутствии по номеру
+	OwnerNum                   // По номеру
+)
+
+// Таблица замены владельцев или групп, ключи и
+// значения могут быть именами или номерами
+type OwnerMap map[string]string
+
+// Параметры восстановления владельца
+type OwnerParams struct {
+	Mode   OwnerMode
+	Users  OwnerMap // Замена пользователей
+	Groups OwnerMap // Замена групп
+}
+
+type ownerAttr struct {
+	uid, gid     uint32 // Номера владельца и группы
+	uname, gname string // Имена владельца и группы
+}
+
+// Возвращает номера владельца и группы
+func (o ownerAttr) Owner() (uid, gid uint32) { return o.uid, o.gid }
+
+// Возвращает имена владельца и группы
+func (o ownerAttr) OwnerNames() (uname, gname string) { return o.uname, o.gname }
+
+// Кэш результатов поиска имен и номеров
+var (
+	lookupMu sync.Mutex
+	lookups  = map[string]string{}
+)
+
+// Выполняет поиск с кэшированием результата,
+// при ошибке возвращается пустая строка
+func cachedLookup(key string, lookup func() (string, error)) string {
+	lookupMu.Lock()
+	defer lookupMu.Unlock()
+
+	if v, ok := lookups[key]; ok {
+		return v
+	}
+
+	v, err := lookup()
+	if err != nil {
+		v = ""
+	}
+	lookups[key] = v
+
+	return v
+}
+
+// Возвращает имя пользователя по номеру
+func userName(uid uint32) string {
+	id := strconv.FormatUint(uint64(uid), 10)
+	return cachedLookup("u"+id, func() (string, error) {
+		u, err := user.LookupId(id)
+		if err != nil {
+			return "", err
+		}
+		return u.Username, nil
+	})
+}
+
+// Возвращает имя группы по номеру
+func groupName(gid uint32) string {
+	id := strconv.FormatUint(uint64(gid), 10)
+	return cachedLookup("g"+id, func() (string, error) {
+		g, err := user.LookupGroupId(id)
+		if err != nil {
+			return "", err
+		}
+		return g.Name, nil
+	})
+}
+
+// Возвращает номер пользователя по имени
+func userID(name string) string {
+	return cachedLookup("U"+name, func() (string, error) {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return "", err
+		}
+		return u.Uid, nil
+	})
+}
+
+// Возвращает номер группы по имени
+func groupID(name string) string {
+	return cachedLookup("G"+name, func() (string, error) {
+		g, err := user.LookupGroup(name)
+		if err != nil {
+			return "", err
+		}
+		return g.Gid, nil
+	})
+}
+
+// Создает описание владельца по его номерам
+func newOwnerAttr(uid, gid uint32) ownerAttr {
+	return ownerAttr{uid, gid, userName(uid), groupName(gid)}
+}
+
+// Десериализует в себя данные из r
+func (o *ownerAttr) read(r io.Reader) (err error) {
+	if err = filesystem.BinaryRead(r, &o.uid); err != nil {
+		return err
+	}
+	if err = filesystem.BinaryRead(r, &o.gid); err != nil {
+		return err
+	}
+	if o.uname, err = readName(r); err != nil {
+		return err
+	}
+	if o.gname, err = readName(r); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Сериализует данные полей в писатель w
+func (o ownerAttr) write(w io.Writer) (err error) {
+	if err = filesystem.BinaryWrite(w, o.uid); err != nil {
+		return err
+	}
+	if err = filesystem.BinaryWrite(w, o.gid); err != nil {
+		return err
+	}
+	if err = writeName(w, o.uname); err != nil {
+		return err
+	}
+	if err = writeName(w, o.gname); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Дериализует короткое имя из r
+func readName(r io.Reader) (string, error) {
+	var length uint8
+
+	if err := filesystem.BinaryRead(r, &length); err != nil {
+		return "", err
+	}
+
+	name := make([]byte, length)
+	if _, err := io.ReadFull(r, name); err != nil {
+		return "", err
+	}
+
+	return string(name), nil
+}
+
+// Сериализует короткое имя name в w, имена
+// длиннее 255 байт не сохраняются
+func writeName(w io.Writer, name string) (err error) {
+	if len(name) > 255 {
+		name = ""
+	}
+
+	if err = filesystem.BinaryWrite(w, uint8(len(name))); err != nil {
+		return err
+	}
+
+	return filesystem.BinaryWrite(w, []byte(name))
+}
+
+// Определяет итоговый номер по таблице замены m и режиму mode.
+// lookupID ищет номер по имени. Если имя не найдено в системе,
+// используется исходный номер
+func resolveID(id uint32, name string, mode OwnerMode, m OwnerMap,
+	lookupID func(string) string) uint32 {
+	var (
+		idStr  = strconv.FormatUint(uint64(id), 10)
+		mapped bool
+	)
+
+	if v, ok := m[name]; ok && name != "" {
+		name, mapped = v, true
+	} else if v, ok := m[idStr]; ok {
+		name, mapped = v, true
+	}
+
+	if mapped { // Значение из таблицы может быть номером
+		if n, err := strconv.ParseUint(name, 10, 32); err == nil {
+			return uint32(n)
+		}
+	}
+
+	if name != "" && (mode == OwnerName || mapped) {
+		if n, err := strconv.ParseUint(lookupID(name), 10, 32); err == nil {
+			return uint32(n)
+		}
+	}
+
+	return id
+}
+
+// Восстанавливает владельца и группу элемента
+// согласно параметрам op
+func (b Base) RestoreOwner(outDir string, op OwnerParams) error {
+	if op.Mode == OwnerNone || b.legacy { // В формате 1.0.x владельца нет
+		return nil
+	}
+
+	uid := resolveID(b.uid, b.uname, op.Mode, op.Users, userID)
+	gid := resolveID(b.gid, b.gname, op.Mode, op.Groups, groupID)
+
+	outDir, err := b.OutPath(outDir)
+	if err != nil {
+		return err
+	}
+
+	return platform.Lchown(outDir, int(uid), int(gid))
+}
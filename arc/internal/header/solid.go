@@ -0,0 +1,147 @@
+package header
+
+import (
+	"io"
+
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Описание solid-блока. Данные файлов блока сжимаются
+// общим потоком один за другим, смещение данных каждого
+// файла в распакованном блоке определяется суммой
+// размеров данных предыдущих файлов
+type SolidItem struct {
+	files []*FileItem
+}
+
+// Создает заголовок solid-блока из файлов files и
+// устанавливает смещения их данных в блоке
+func NewSolidItem(files []*FileItem) *SolidItem {
+	si := &SolidItem{files: files}
+	si.setOffsets()
+	return si
+}
+
+// Возвращает файлы блока в порядке их данных
+func (si SolidItem) Files() []*FileItem { return si.files }
+
+// Возвращает размер распакованных данных блока
+func (si SolidItem) DataSize() (size Size) {
+	for _, fi := range si.files {
+		size += fi.DataSize()
+	}
+
+	return size
+}
+
+// Возвращает заголовок общих данных блока, по которому
+// они сжимаются и распаковываются как данные одного
+// файла. Блок шифруется с путем первого файла
+func (si SolidItem) Data() *FileItem {
+	data := NewFileItem(&si.files[0].Base, si.DataSize())
+	data.codec = si.files[0].codec
+	return data
+}
+
+// Распределяет сжатый размер и CRC блока между
+// файлами пропорционально размерам их данных
+func (si *SolidItem) SetCSize(cSize Size, crc uint32) {
+	var (
+		total = si.DataSize()
+		rest  = cSize
+	)
+
+	for i, fi := range si.files {
+		share := rest
+		if i < len(si.files)-1 && total > 0 {
+			share = Size(float64(cSize) * float64(fi.DataSize()) / float64(total))
+		}
+		rest -= share
+
+		fi.SetCSize(share)
+		fi.SetCRC(crc)
+	}
+}
+
+// Устанавливает смещения данных файлов в блоке
+func (si *SolidItem) setOffsets() {
+	var off int64
+	for _, fi := range si.files {
+		fi.SetSolidOffset(off)
+		off += int64(fi.DataSize())
+	}
+}
+
+// Десериализует заголовок solid-блока из r
+func (si *SolidItem) Read(r io.Reader) (err error) {
+	var (
+		count uint32
+		typ   HeaderType
+	)
+
+	if err = filesystem.BinaryRead(r, &count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrSolidCount
+	}
+
+	si.files = make([]*FileItem, count)
+	for i := range si.files {
+		if err = filesystem.BinaryRead(r, &typ); err != nil {
+			return err
+		}
+		if typ != File {
+			return ErrSolidFile
+		}
+
+		si.files[i] = &FileItem{}
+		if err = si.files[i].Read(r); err != nil {
+			return err
+		}
+	}
+	si.setOffsets()
+
+	return nil
+}
+
+// Сериализует заголовок solid-блока в w. Контрольные
+// суммы содержимого файлов пишутся после данных блока
+func (si *SolidItem) Write(w io.Writer) (err error) {
+	if err = filesystem.BinaryWrite(w, Solid); err != nil {
+		return err
+	}
+	if err = filesystem.BinaryWrite(w, uint32(len(si.files))); err != nil {
+		return err
+	}
+
+	for _, fi := range si.files {
+		if err = fi.Write(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Десериализует контрольные суммы содержимого файлов из r
+func (si *SolidItem) ReadDigestSums(r io.Reader) error {
+	for _, fi := range si.files {
+		if err := fi.ReadDigestSum(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Сериализует контрольные суммы содержимого файлов в w
+func (si SolidItem) WriteDigestSums(w io.Writer) error {
+	for _, fi := range si.files {
+		if err := fi.WriteDigestSum(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
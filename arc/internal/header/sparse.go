@@ -0,0 +1,91 @@
+package header
+
+import (
+	"io"
+
+	"github.com/gh0st17/archiver/arc/internal/platform"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Дыра разреженного файла
+type Hole = platform.Hole
+
+// Возвращает дыры разреженного файла
+func (fi FileItem) Holes() []Hole { return fi.holes }
+
+// Устанавливает дыры разреженного файла
+func (fi *FileItem) SetHoles(holes []Hole) { fi.holes = holes }
+
+// Возвращает размер данных файла без учета дыр
+func (fi FileItem) DataSize() Size {
+	size := fi.ucSize
+	for _, h := range fi.holes {
+		size -= Size(h.Length)
+	}
+
+	return size
+}
+
+// Возвращает смещение в файле байта, находящегося
+// на смещении off в данных файла без учета дыр
+func (fi FileItem) FileOffset(off int64) int64 {
+	for _, h := range fi.holes {
+		if h.Offset > off {
+			break
+		}
+		off += h.Length
+	}
+
+	return off
+}
+
+// Десериализует карту дыр файла размера size из r.
+// Дыры должны быть упорядочены, не пересекаться
+// и не выходить за пределы файла
+func readHoles(r io.Reader, size Size) (holes []Hole, err error) {
+	var (
+		count uint32
+		end   int64
+	)
+
+	if err = filesystem.BinaryRead(r, &count); err != nil {
+		return nil, err
+	}
+
+	for i := uint32(0); i < count; i++ {
+		var h Hole
+		if err = filesystem.BinaryRead(r, &h.Offset); err != nil {
+			return nil, err
+		}
+		if err = filesystem.BinaryRead(r, &h.Length); err != nil {
+			return nil, err
+		}
+
+		if h.Offset < end || h.Length < 1 || h.Offset+h.Length > int64(size) {
+			return nil, ErrHole(h.Offset, h.Length)
+		}
+		end = h.Offset + h.Length
+
+		holes = append(holes, h)
+	}
+
+	return holes, nil
+}
+
+// Сериализует карту дыр holes в w
+func writeHoles(w io.Writer, holes []Hole) (err error) {
+	if err = filesystem.BinaryWrite(w, uint32(len(holes))); err != nil {
+		return err
+	}
+
+	for _, h := range holes {
+		if err = filesystem.BinaryWrite(w, h.Offset); err != nil {
+			return err
+		}
+		if err = filesystem.BinaryWrite(w, h.Length); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
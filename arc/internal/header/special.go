@@ -0,0 +1,142 @@
+package header
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gh0st17/archiver/arc/internal/platform"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Тип специального файла
+type NodeKind = platform.NodeKind
+
+// Описание специального файла: именованного канала,
+// файла устройства или сокета
+type SpecialItem struct {
+	Base
+	kind         NodeKind // Тип специального файла
+	major, minor uint32   // Номера устройства
+}
+
+// Создает заголовок специального файла [header.SpecialItem].
+// Возвращает false, если info не описывает специальный файл
+func NewSpecialItem(base *Base, info os.FileInfo) (*SpecialItem, bool) {
+	var (
+		mode = info.Mode()
+		kind NodeKind
+	)
+
+	switch {
+	case mode&os.ModeNamedPipe != 0:
+		kind = platform.NodeFIFO
+	case mode&os.ModeSocket != 0:
+		kind = platform.NodeSocket
+	case mode&os.ModeCharDevice != 0:
+		kind = platform.NodeChar
+	case mode&os.ModeDevice != 0:
+		kind = platform.NodeBlock
+	default:
+		return nil, false
+	}
+
+	major, minor := platform.Rdev(info)
+	return &SpecialItem{*base, kind, major, minor}, true
+}
+
+// Возвращает тип специального файла
+func (si SpecialItem) Kind() NodeKind { return si.kind }
+
+// Возвращает номера устройства
+func (si SpecialItem) Dev() (major, minor uint32) { return si.major, si.minor }
+
+// Создает специальный файл
+func (si SpecialItem) RestorePath(outDir string) error {
+	outDir, err := si.OutPath(outDir)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outDir), 0755); err != nil {
+		return err
+	}
+
+	if err := os.Remove(outDir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return platform.Mknod(outDir, si.kind, si.mode&os.ModePerm, si.major, si.minor)
+}
+
+// Возвращает краткое обозначение типа специального файла
+func (si SpecialItem) kindString() string {
+	switch si.kind {
+	case platform.NodeFIFO:
+		return "fifo"
+	case platform.NodeChar:
+		return fmt.Sprintf("c %d,%d", si.major, si.minor)
+	case platform.NodeBlock:
+		return fmt.Sprintf("b %d,%d", si.major, si.minor)
+	case platform.NodeSocket:
+		return "socket"
+	}
+	return "?"
+}
+
+// Реализация fmt.Stringer
+func (si SpecialItem) String() string {
+	filename := prefix(si.pathInArc, nameWidth)
+	diff := terminalWidth - len([]rune(filename)) - 2
+
+	return fmt.Sprintf(
+		"%-s%-*s", filename+"  ",
+		diff, "["+si.kindString()+"]",
+	)
+}
+
+// Десериализует в себя данные из r
+func (si *SpecialItem) Read(r io.Reader) (err error) {
+	if err = si.Base.Read(r); err != nil {
+		return err
+	}
+
+	// Читаем тип и номера устройства
+	if err = filesystem.BinaryRead(r, &si.kind); err != nil {
+		return err
+	}
+	if err = filesystem.BinaryRead(r, &si.major); err != nil {
+		return err
+	}
+	if err = filesystem.BinaryRead(r, &si.minor); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Сериализует данные полей в писатель w
+func (si *SpecialItem) Write(w io.Writer) (err error) {
+	if err = filesystem.BinaryWrite(w, Special); err != nil {
+		return err
+	}
+
+	if err = si.Base.Write(w); err != nil {
+		return err
+	}
+
+	// Пишем тип и номера устройства
+	if err = filesystem.BinaryWrite(w, si.kind); err != nil {
+		return err
+	}
+	if err = filesystem.BinaryWrite(w, si.major); err != nil {
+		return err
+	}
+	if err = filesystem.BinaryWrite(w, si.minor); err != nil {
+		return err
+	}
+
+	return nil
+}
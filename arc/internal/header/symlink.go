@@ -12,37 +12,50 @@ import (
 
 // Описание символической ссылки
 type SymItem struct {
-	basePaths
+	Base
+	target string // Путь, на который указывает ссылка
 }
 
 // Создает заголовок символической ссылки [header.SymItem]
-func NewSymItem(symlink, target string) *SymItem {
-	return &SymItem{
-		basePaths{pathOnDisk: target, pathInArc: symlink},
-	}
+func NewSymItem(base *Base, target string) *SymItem {
+	return &SymItem{Base: *base, target: target}
 }
 
-// Создает директорию
+// Возвращает путь, на который указывает ссылка
+func (si SymItem) Target() string { return si.target }
+
+// Создает символическую ссылку
 func (si SymItem) RestorePath(outDir string) error {
-	outDir = filepath.Join(outDir, si.pathInArc)
+	outDir, err := si.OutPath(outDir)
+	if err != nil {
+		return err
+	}
 
 	if err := os.MkdirAll(filepath.Dir(outDir), 0755); err != nil {
 		return err
 	}
 
-	err := os.Symlink(si.pathOnDisk, outDir)
-	if err != nil && !errors.Is(err, os.ErrExist) {
+	err = os.Symlink(si.target, outDir)
+	if !errors.Is(err, os.ErrExist) {
 		return err
 	}
 
-	return nil
+	// Заменяем существующую ссылку с другим путем назначения
+	if target, err := os.Readlink(outDir); err != nil || target == si.target {
+		return nil
+	}
+	if err = os.Remove(outDir); err != nil {
+		return err
+	}
+
+	return os.Symlink(si.target, outDir)
 }
 
 // Реализация fmt.Stringer
 func (si SymItem) String() string {
 	filename := prefix(si.pathInArc, nameWidth)
 	diff := terminalWidth - len([]rune(filename)) - 4
-	target := prefix(si.pathOnDisk, diff)
+	target := prefix(si.target, diff)
 
 	return fmt.Sprintf(
 		"%-s%-*s", filename+" -> ",
@@ -51,39 +64,35 @@ func (si SymItem) String() string {
 }
 
 // Десериализует в себя данные из r
-func (si *SymItem) Read(r io.Reader) error {
-	var (
-		err     error
-		target  string
-		symlink string
-	)
+func (si *SymItem) Read(r io.Reader) (err error) {
+	if isLegacy() {
+		return si.readLegacy(r)
+	}
 
-	// Читаем размер строки target
-	if target, err = readPath(r); err != nil {
+	if err = si.Base.Read(r); err != nil {
 		return err
 	}
 
-	// Читаем размер строки symlink
-	if symlink, err = readPath(r); err != nil {
+	// Читаем путь, на который указывает ссылка
+	if si.target, err = readPath(r); err != nil {
 		return err
 	}
 
-	newSym := NewSymItem(symlink, target)
-	*si = *newSym
-
-	return err
+	return nil
 }
 
 // Сериализует данные полей в писатель w
 func (si *SymItem) Write(w io.Writer) (err error) {
-	filesystem.BinaryWrite(w, Symlink)
+	if err = filesystem.BinaryWrite(w, Symlink); err != nil {
+		return err
+	}
 
-	// Пишем длину строки имени файла или директории
-	if err = writePath(w, si.pathOnDisk); err != nil {
+	if err = si.Base.Write(w); err != nil {
 		return err
 	}
-	// Пишем длину строки имени файла или директории
-	if err = writePath(w, si.pathInArc); err != nil {
+
+	// Пишем путь, на который указывает ссылка
+	if err = writePath(w, si.target); err != nil {
 		return err
 	}
 
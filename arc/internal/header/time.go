@@ -0,0 +1,155 @@
+package header
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gh0st17/archiver/arc/internal/platform"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Формат времени с наносекундами
+const nanoDateFormat string = dateFormat + ".000000000"
+
+// Флаги наличия необязательных временных меток
+const (
+	hasCtime byte = 1 << iota
+	hasBtime
+)
+
+type timeAttr struct {
+	atim time.Time // Последнее время доступа к элементу
+	mtim time.Time // Последнее время измения элемента
+	ctim time.Time // Последнее время изменения метаданных
+	btim time.Time // Время создания элемента
+}
+
+// Создает описание временных меток из t
+func newTimeAttr(t platform.Times) timeAttr {
+	return timeAttr{t.Atime, t.Mtime, t.Ctime, t.Btime}
+}
+
+// Возвращает время доступа и модификации
+func (ta timeAttr) AMTimes() (atime, mtime time.Time) { return ta.atim, ta.mtim }
+
+// Возвращает время изменения метаданных и создания,
+// нулевое значение означает отсутствие метки
+func (ta timeAttr) CBTimes() (ctime, btime time.Time) { return ta.ctim, ta.btim }
+
+// Печатает временные метки с точностью до наносекунд
+func (ta timeAttr) TimesString() string {
+	format := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format(nanoDateFormat)
+	}
+
+	return fmt.Sprintf(
+		"  Модификация: %s\n  Доступ:      %s\n"+
+			"  Метаданные:  %s\n  Создание:    %s",
+		format(ta.mtim), format(ta.atim),
+		format(ta.ctim), format(ta.btim),
+	)
+}
+
+// Дериализует время из r
+func readTime(r io.Reader) (time.Time, error) {
+	var (
+		sec  int64
+		nsec uint32
+	)
+
+	if err := filesystem.BinaryRead(r, &sec); err != nil {
+		return time.Time{}, err
+	}
+	if err := filesystem.BinaryRead(r, &nsec); err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(sec, int64(nsec)), nil
+}
+
+// Сериализует время t в w
+func writeTime(w io.Writer, t time.Time) error {
+	if err := filesystem.BinaryWrite(w, t.Unix()); err != nil {
+		return err
+	}
+
+	return filesystem.BinaryWrite(w, uint32(t.Nanosecond()))
+}
+
+// Десериализует в себя данные из r
+func (ta *timeAttr) read(r io.Reader) (err error) {
+	var flags byte
+
+	// Читаем время модификации
+	if ta.mtim, err = readTime(r); err != nil {
+		return err
+	}
+
+	// Читаем время доступа
+	if ta.atim, err = readTime(r); err != nil {
+		return err
+	}
+
+	// Читаем флаги наличия остальных меток
+	if err = filesystem.BinaryRead(r, &flags); err != nil {
+		return err
+	}
+
+	ta.ctim, ta.btim = time.Time{}, time.Time{}
+	if flags&hasCtime != 0 {
+		if ta.ctim, err = readTime(r); err != nil {
+			return err
+		}
+	}
+	if flags&hasBtime != 0 {
+		if ta.btim, err = readTime(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Сериализует данные полей в писатель w
+func (ta timeAttr) write(w io.Writer) (err error) {
+	var flags byte
+
+	// Пишем время модификации
+	if err = writeTime(w, ta.mtim); err != nil {
+		return err
+	}
+
+	// Пишем время доступа
+	if err = writeTime(w, ta.atim); err != nil {
+		return err
+	}
+
+	if !ta.ctim.IsZero() {
+		flags |= hasCtime
+	}
+	if !ta.btim.IsZero() {
+		flags |= hasBtime
+	}
+
+	// Пишем флаги наличия остальных меток
+	if err = filesystem.BinaryWrite(w, flags); err != nil {
+		return err
+	}
+
+	if flags&hasCtime != 0 {
+		if err = writeTime(w, ta.ctim); err != nil {
+			return err
+		}
+	}
+	if flags&hasBtime != 0 {
+		if err = writeTime(w, ta.btim); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package header
+
+import (
+	"io"
+	"strings"
+
+	"github.com/gh0st17/archiver/arc/internal/platform"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+// Расширенный атрибут элемента
+type Xattr = platform.Xattr
+
+// Максимальный размер значения атрибута (XATTR_SIZE_MAX)
+const maxXattrSize = 65536
+
+// Фильтр расширенных атрибутов по пространствам имен.
+// Элемент фильтра совпадает с атрибутом, если равен его
+// имени или является его префиксом до точки, например
+// 'user' или 'system.posix_acl_access'
+type XattrFilter struct {
+	Include []string // Если не пуст, то сохраняются только эти
+	Exclude []string // Исключаемые атрибуты
+}
+
+// Проверяет совпадение имени атрибута с элементом фильтра
+func matchXattr(name, ns string) bool {
+	return name == ns || strings.HasPrefix(name, ns+".")
+}
+
+// Проверяет проходит ли атрибут с именем name фильтр
+func (f XattrFilter) Match(name string) bool {
+	if len(f.Include) > 0 {
+		included := false
+		for _, ns := range f.Include {
+			if matchXattr(name, ns) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+
+	for _, ns := range f.Exclude {
+		if matchXattr(name, ns) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Ошибка восстановления расширенного атрибута
+type XattrError struct {
+	Name string
+	Err  error
+}
+
+func (e XattrError) Error() string { return e.Name + ": " + e.Err.Error() }
+
+// Пространство имен атрибута, например 'user'
+func (e XattrError) Namespace() string {
+	ns, _, _ := strings.Cut(e.Name, ".")
+	return ns
+}
+
+// Возвращает расширенные атрибуты элемента
+func (b Base) Xattrs() []Xattr { return b.xattrs }
+
+// Устанавливает расширенные атрибуты элемента,
+// прошедшие фильтр f
+func (b *Base) SetXattrs(attrs []Xattr, f XattrFilter) {
+	b.xattrs = nil
+	for _, attr := range attrs {
+		if f.Match(attr.Name) {
+			b.xattrs = append(b.xattrs, attr)
+		}
+	}
+}
+
+// Восстанавливает расширенные атрибуты, прошедшие фильтр f.
+// Отказы файловой системы не прерывают восстановление
+// и возвращаются в виде среза ошибок
+func (b Base) RestoreXattrs(outDir string, f XattrFilter) (errs []XattrError) {
+	outDir, err := b.OutPath(outDir)
+
+	for _, attr := range b.xattrs {
+		if !f.Match(attr.Name) {
+			continue
+		}
+
+		if err != nil {
+			errs = append(errs, XattrError{attr.Name, err})
+		} else if err := platform.Lsetxattr(outDir, attr); err != nil {
+			errs = append(errs, XattrError{attr.Name, err})
+		}
+	}
+
+	return errs
+}
+
+// Дериализует расширенные атрибуты из r
+func readXattrs(r io.Reader) ([]Xattr, error) {
+	var (
+		count  uint16
+		length uint32
+		attrs  []Xattr
+		err    error
+	)
+
+	if err = filesystem.BinaryRead(r, &count); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < int(count); i++ {
+		var attr Xattr
+
+		if attr.Name, err = readName(r); err != nil {
+			return nil, err
+		}
+
+		if err = filesystem.BinaryRead(r, &length); err != nil {
+			return nil, err
+		}
+		if length > maxXattrSize {
+			return nil, ErrXattrSize(attr.Name, length)
+		}
+
+		attr.Value = make([]byte, length)
+		if _, err = io.ReadFull(r, attr.Value); err != nil {
+			return nil, err
+		}
+
+		attrs = append(attrs, attr)
+	}
+
+	return attrs, nil
+}
+
+// Сериализует расширенные атрибуты attrs в w
+func writeXattrs(w io.Writer, attrs []Xattr) (err error) {
+	if err = filesystem.BinaryWrite(w, uint16(len(attrs))); err != nil {
+		return err
+	}
+
+	for _, attr := range attrs {
+		if err = writeName(w, attr.Name); err != nil {
+			return err
+		}
+
+		if err = filesystem.BinaryWrite(w, uint32(len(attr.Value))); err != nil {
+			return err
+		}
+		if err = filesystem.BinaryWrite(w, attr.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -7,13 +7,35 @@ import (
 	"os"
 	"syscall"
 	"time"
+
+	"golang.org/x/sys/unix"
 )
 
-// Возвращает временные метки доступа и изменения
-func amTimes(info os.FileInfo) (atime time.Time, mtime time.Time) {
+// Возвращает временные метки элемента
+func times(_ string, info os.FileInfo) (t Times) {
+	stat := info.Sys().(*syscall.Stat_t)
+	t.Atime = time.Unix(stat.Atimespec.Sec, stat.Atimespec.Nsec)
+	t.Mtime = time.Unix(stat.Mtimespec.Sec, stat.Mtimespec.Nsec)
+	t.Ctime = time.Unix(stat.Ctimespec.Sec, stat.Ctimespec.Nsec)
+	t.Btime = time.Unix(stat.Birthtimespec.Sec, stat.Birthtimespec.Nsec)
+
+	return t
+}
+
+// Возвращает номера владельца и группы
+func owner(info os.FileInfo) (uid, gid uint32) {
 	stat := info.Sys().(*syscall.Stat_t)
-	atime = time.Unix(stat.Atimespec.Sec, stat.Atimespec.Nsec)
-	mtime = time.Unix(stat.Mtimespec.Sec, stat.Mtimespec.Nsec)
+	return stat.Uid, stat.Gid
+}
+
+// Возвращает идентификатор файла и количество жестких ссылок на него
+func fileID(info os.FileInfo) (dev, ino, nlink uint64, ok bool) {
+	stat := info.Sys().(*syscall.Stat_t)
+	return uint64(stat.Dev), uint64(stat.Ino), uint64(stat.Nlink), true
+}
 
-	return atime, mtime
+// Возвращает старший и младший номера устройства
+func rdev(info os.FileInfo) (major, minor uint32) {
+	dev := uint64(info.Sys().(*syscall.Stat_t).Rdev)
+	return unix.Major(dev), unix.Minor(dev)
 }
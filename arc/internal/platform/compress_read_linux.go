@@ -7,13 +7,42 @@ import (
 	"os"
 	"syscall"
 	"time"
+
+	"golang.org/x/sys/unix"
 )
 
-// Возвращает временные метки доступа и изменения
-func amTimes(info os.FileInfo) (atime time.Time, mtime time.Time) {
+// Возвращает временные метки элемента. Время создания
+// запрашивается через statx, если его поддерживает
+// ядро и файловая система
+func times(path string, info os.FileInfo) (t Times) {
+	stat := info.Sys().(*syscall.Stat_t)
+	t.Atime = time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec))
+	t.Mtime = time.Unix(int64(stat.Mtim.Sec), int64(stat.Mtim.Nsec))
+	t.Ctime = time.Unix(int64(stat.Ctim.Sec), int64(stat.Ctim.Nsec))
+
+	var stx unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW, unix.STATX_BTIME, &stx)
+	if err == nil && stx.Mask&unix.STATX_BTIME != 0 {
+		t.Btime = time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec))
+	}
+
+	return t
+}
+
+// Возвращает номера владельца и группы
+func owner(info os.FileInfo) (uid, gid uint32) {
 	stat := info.Sys().(*syscall.Stat_t)
-	atime = time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec))
-	mtime = time.Unix(int64(stat.Mtim.Sec), int64(stat.Mtim.Nsec))
+	return stat.Uid, stat.Gid
+}
+
+// Возвращает идентификатор файла и количество жестких ссылок на него
+func fileID(info os.FileInfo) (dev, ino, nlink uint64, ok bool) {
+	stat := info.Sys().(*syscall.Stat_t)
+	return uint64(stat.Dev), uint64(stat.Ino), uint64(stat.Nlink), true
+}
 
-	return atime, mtime
+// Возвращает старший и младший номера устройства
+func rdev(info os.FileInfo) (major, minor uint32) {
+	dev := uint64(info.Sys().(*syscall.Stat_t).Rdev)
+	return unix.Major(dev), unix.Minor(dev)
 }
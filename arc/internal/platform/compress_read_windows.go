@@ -9,11 +9,23 @@ import (
 	"time"
 )
 
-// Возвращает временные метки доступа и изменения
-func amTimes(info os.FileInfo) (atime time.Time, mtime time.Time) {
+// Возвращает временные метки элемента. Время изменения
+// метаданных в Windows недоступно
+func times(_ string, info os.FileInfo) (t Times) {
 	stat := info.Sys().(*syscall.Win32FileAttributeData)
-	atime = time.Unix(0, stat.LastAccessTime.Nanoseconds())
-	mtime = time.Unix(0, stat.LastWriteTime.Nanoseconds())
+	t.Atime = time.Unix(0, stat.LastAccessTime.Nanoseconds())
+	t.Mtime = time.Unix(0, stat.LastWriteTime.Nanoseconds())
+	t.Btime = time.Unix(0, stat.CreationTime.Nanoseconds())
 
-	return atime, mtime
+	return t
 }
+
+// Владелец в виде номеров в Windows не используется
+func owner(os.FileInfo) (uid, gid uint32) { return 0, 0 }
+
+// Идентификатор файла в Windows недоступен без открытия файла,
+// поэтому жесткие ссылки не определяются
+func fileID(os.FileInfo) (dev, ino, nlink uint64, ok bool) { return 0, 0, 0, false }
+
+// Номера устройств в Windows не используются
+func rdev(os.FileInfo) (major, minor uint32) { return 0, 0 }
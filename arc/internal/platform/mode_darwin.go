@@ -0,0 +1,31 @@
+//go:build darwin
+// +build darwin
+
+package platform
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Устанавливает режим доступа символической ссылки
+func lchmod(path string, mode os.FileMode) error {
+	return unix.Fchmodat(unix.AT_FDCWD, path, syscallMode(mode), unix.AT_SYMLINK_NOFOLLOW)
+}
+
+// Переводит [os.FileMode] в биты режима системного вызова
+func syscallMode(mode os.FileMode) (m uint32) {
+	m = uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		m |= unix.S_ISUID
+	}
+	if mode&os.ModeSetgid != 0 {
+		m |= unix.S_ISGID
+	}
+	if mode&os.ModeSticky != 0 {
+		m |= unix.S_ISVTX
+	}
+
+	return m
+}
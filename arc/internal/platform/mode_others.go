@@ -0,0 +1,10 @@
+//go:build !darwin
+// +build !darwin
+
+package platform
+
+import "os"
+
+// Режим доступа символической ссылки в Linux и Windows
+// не используется и не может быть изменен
+func lchmod(string, os.FileMode) error { return nil }
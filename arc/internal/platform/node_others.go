@@ -0,0 +1,30 @@
+//go:build !windows
+// +build !windows
+
+package platform
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Создает специальный файл
+func mknod(path string, kind NodeKind, perm os.FileMode, major, minor uint32) error {
+	mode := uint32(perm.Perm())
+
+	switch kind {
+	case NodeFIFO:
+		return unix.Mkfifo(path, mode)
+	case NodeChar:
+		mode |= unix.S_IFCHR
+	case NodeBlock:
+		mode |= unix.S_IFBLK
+	case NodeSocket:
+		mode |= unix.S_IFSOCK
+	default:
+		return ErrUnsupportedNode
+	}
+
+	return unix.Mknod(path, mode, int(unix.Mkdev(major, minor)))
+}
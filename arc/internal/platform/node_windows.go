@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import "os"
+
+// Специальные файлы в Windows не поддерживаются
+func mknod(string, NodeKind, os.FileMode, uint32, uint32) error {
+	return ErrUnsupportedNode
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package platform
+
+import "os"
+
+func lchown(path string, uid, gid int) error {
+	return os.Lchown(path, uid, gid)
+}
@@ -0,0 +1,7 @@
+//go:build windows
+// +build windows
+
+package platform
+
+// Смена владельца по номерам в Windows не поддерживается
+func lchown(string, int, int) error { return nil }
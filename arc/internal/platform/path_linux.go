@@ -0,0 +1,115 @@
+//go:build linux
+// +build linux
+
+package platform
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Максимальная длина пути без завершающего нуля
+const maxPath = unix.PathMax - 1
+
+// Открытые директории для доступа к длинным путям.
+// Распаковка выполняется последовательно, поэтому
+// хранится не более maxDirs директорий
+var (
+	dirs    = map[string]int{}
+	lastDir string
+)
+
+const maxDirs = 64
+
+// Если path длиннее maxPath, то возвращает путь вида
+// '/proc/self/fd/N/имя', где N -- дескриптор родительской
+// директории path. Директория открывается по одной
+// компоненте, недостающие компоненты создаются
+func longPath(path string) (string, error) {
+	if len(path) <= maxPath {
+		return path, nil
+	}
+
+	dir, name := filepath.Split(path)
+	dir = filepath.Clean(dir)
+
+	fd, ok := dirs[dir]
+	if !ok {
+		var err error
+		if fd, err = openDirAll(dir); err != nil {
+			return "", err
+		}
+		releaseDirs()
+		dirs[dir] = fd
+	}
+	lastDir = dir
+
+	return "/proc/self/fd/" + strconv.Itoa(fd) + "/" + name, nil
+}
+
+// Закрывает открытые директории при переполнении,
+// кроме последней использованной
+func releaseDirs() {
+	if len(dirs) < maxDirs {
+		return
+	}
+
+	for dir, fd := range dirs {
+		if dir != lastDir {
+			unix.Close(fd)
+			delete(dirs, dir)
+		}
+	}
+}
+
+// Открывает директорию dir, переходя по одной компоненте
+// пути и создавая недостающие директории
+func openDirAll(dir string) (int, error) {
+	var (
+		fd    = unix.AT_FDCWD
+		flags = unix.O_RDONLY | unix.O_DIRECTORY | unix.O_CLOEXEC
+	)
+
+	if filepath.IsAbs(dir) {
+		root, err := unix.Open("/", flags, 0)
+		if err != nil {
+			return -1, err
+		}
+		fd = root
+	}
+
+	for _, name := range strings.Split(dir, "/") {
+		if name == "" {
+			continue
+		}
+
+		err := unix.Mkdirat(fd, name, 0755)
+		if err != nil && !errors.Is(err, unix.EEXIST) {
+			closeDir(fd)
+			return -1, err
+		}
+
+		next, err := unix.Openat(fd, name, flags, 0)
+		closeDir(fd)
+		if err != nil {
+			return -1, err
+		}
+		fd = next
+	}
+
+	if fd == unix.AT_FDCWD {
+		return unix.Open(".", flags, 0)
+	}
+	return fd, nil
+}
+
+// Закрывает дескриптор директории, если он открыт
+func closeDir(fd int) {
+	if fd != unix.AT_FDCWD {
+		unix.Close(fd)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package platform
+
+// Максимальная длина пути без завершающего нуля
+const maxPath = 1023
+
+// Длинные пути поддерживаются только в Linux и Windows
+func longPath(path string) (string, error) { return path, nil }
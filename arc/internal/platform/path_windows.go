@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package platform
+
+// Максимальная длина пути с префиксом '\\?\'
+const maxPath = 32767
+
+// Пакет os сам добавляет префикс '\\?\' к длинным путям
+func longPath(path string) (string, error) { return path, nil }
@@ -2,16 +2,119 @@
 package platform
 
 import (
+	"errors"
 	"os"
 	"time"
 )
 
-// Возвращает временные метки доступа и изменения
-func Timestamp(info os.FileInfo) (atime time.Time, mtime time.Time) {
-	return amTimes(info)
+// Временные метки элемента файловой системы.
+// Нулевое значение означает, что метка недоступна
+type Times struct {
+	Atime time.Time // Время доступа
+	Mtime time.Time // Время модификации
+	Ctime time.Time // Время изменения метаданных
+	Btime time.Time // Время создания
+}
+
+// Возвращает временные метки элемента path с информацией info
+func Timestamp(path string, info os.FileInfo) Times {
+	return times(path, info)
+}
+
+// Устанавливает время доступа и модификации символической
+// ссылки без перехода по ней, если платформа это позволяет
+func Lutimes(path string, atime, mtime time.Time) error {
+	return lutimes(path, atime, mtime)
+}
+
+// Возвращает номера владельца и группы
+func Owner(info os.FileInfo) (uid, gid uint32) {
+	return owner(info)
+}
+
+// Возвращает идентификатор файла (устройство и индексный
+// дескриптор) и количество жестких ссылок на него.
+// Если платформа не предоставляет идентификатор, ok == false
+func FileID(info os.FileInfo) (dev, ino, nlink uint64, ok bool) {
+	return fileID(info)
+}
+
+// Возвращает старший и младший номера устройства
+// для файлов устройств
+func Rdev(info os.FileInfo) (major, minor uint32) {
+	return rdev(info)
 }
 
 // Возвращает размеры терминала
 func GetTerminalSize() (int, int, error) {
 	return getTerminalSize()
 }
+
+// Устанавливает режим доступа символической ссылки
+// без перехода по ней, если платформа это позволяет
+func Lchmod(path string, mode os.FileMode) error {
+	return lchmod(path, mode)
+}
+
+// Устанавливает владельца и группу без перехода
+// по символической ссылке, если платформа это позволяет
+func Lchown(path string, uid, gid int) error {
+	return lchown(path, uid, gid)
+}
+
+// Расширенный атрибут элемента файловой системы
+type Xattr struct {
+	Name  string // Имя вместе с пространством имен
+	Value []byte
+}
+
+// Возвращает расширенные атрибуты элемента path, включая
+// списки ACL, без перехода по символической ссылке
+func Xattrs(path string) ([]Xattr, error) {
+	return xattrs(path)
+}
+
+// Устанавливает расширенный атрибут attr элемента path
+// без перехода по символической ссылке
+func Lsetxattr(path string, attr Xattr) error {
+	return lsetxattr(path, attr)
+}
+
+// Тип специального файла
+type NodeKind byte
+
+const (
+	NodeFIFO   NodeKind = iota // Именованный канал
+	NodeChar                   // Символьное устройство
+	NodeBlock                  // Блочное устройство
+	NodeSocket                 // Сокет
+)
+
+var ErrUnsupportedNode = errors.New("тип специального файла не поддерживается")
+
+// Создает специальный файл типа kind с режимом доступа perm
+// и номерами устройства major и minor
+func Mknod(path string, kind NodeKind, perm os.FileMode, major, minor uint32) error {
+	return mknod(path, kind, perm, major, minor)
+}
+
+// Дыра разреженного файла, область без выделенных блоков
+type Hole struct {
+	Offset, Length int64
+}
+
+// Возвращает дыры в файле f размера size. Позиция
+// чтения файла после вызова не определена
+func Holes(f *os.File, size int64) ([]Hole, error) {
+	return holes(f, size)
+}
+
+// Максимальная длина пути на платформе
+const MaxPath = maxPath
+
+// Возвращает путь, по которому доступен элемент path,
+// даже если длина path превышает [MaxPath]. Недостающие
+// родительские директории такого пути создаются
+func LongPath(path string) (string, error) {
+	return longPath(path)
+}
@@ -0,0 +1,46 @@
+//go:build linux
+// +build linux
+
+package platform
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Находит дыры в файле f размера size с помощью
+// SEEK_DATA и SEEK_HOLE. Если файловая система
+// не поддерживает поиск дыр, возвращает nil
+func holes(f *os.File, size int64) (hs []Hole, err error) {
+	var (
+		fd        = int(f.Fd())
+		off, data int64
+	)
+
+	for off < size {
+		data, err = unix.Seek(fd, off, unix.SEEK_DATA)
+		if errors.Is(err, unix.ENXIO) { // Дальше данных нет
+			data = size
+		} else if errors.Is(err, unix.EINVAL) || errors.Is(err, unix.EOPNOTSUPP) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		data = min(data, size)
+
+		if data > off {
+			hs = append(hs, Hole{Offset: off, Length: data - off})
+		}
+		if data == size {
+			break
+		}
+
+		if off, err = unix.Seek(fd, data, unix.SEEK_HOLE); err != nil {
+			return nil, err
+		}
+	}
+
+	return hs, nil
+}
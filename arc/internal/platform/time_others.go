@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package platform
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Устанавливает время доступа и модификации
+// символической ссылки
+func lutimes(path string, atime, mtime time.Time) error {
+	ts := []unix.Timespec{
+		unix.NsecToTimespec(atime.UnixNano()),
+		unix.NsecToTimespec(mtime.UnixNano()),
+	}
+
+	return unix.UtimesNanoAt(unix.AT_FDCWD, path, ts, unix.AT_SYMLINK_NOFOLLOW)
+}
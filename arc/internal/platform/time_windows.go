@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package platform
+
+import "time"
+
+// Время символической ссылки в Windows не восстанавливается
+func lutimes(string, time.Time, time.Time) error { return nil }
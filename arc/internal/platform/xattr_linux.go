@@ -0,0 +1,70 @@
+//go:build linux
+// +build linux
+
+package platform
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// Возвращает расширенные атрибуты элемента path
+// без перехода по символической ссылке
+func xattrs(path string) ([]Xattr, error) {
+	names, err := readXattr(func(dest []byte) (int, error) {
+		return unix.Llistxattr(path, dest)
+	})
+	if errors.Is(err, unix.ENOTSUP) {
+		return nil, nil // Файловая система не поддерживает атрибуты
+	} else if err != nil {
+		return nil, err
+	}
+
+	var attrs []Xattr
+	for _, name := range bytes.Split(names, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+
+		value, err := readXattr(func(dest []byte) (int, error) {
+			return unix.Lgetxattr(path, string(name), dest)
+		})
+		if errors.Is(err, unix.ENODATA) {
+			continue // Атрибут удален во время чтения
+		} else if err != nil {
+			return nil, err
+		}
+
+		attrs = append(attrs, Xattr{Name: string(name), Value: value})
+	}
+
+	return attrs, nil
+}
+
+// Читает данные переменной длины, повторяя
+// попытку, если буфер оказался мал
+func readXattr(get func([]byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := get(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, size)
+		if size, err = get(buf); errors.Is(err, unix.ERANGE) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		return buf[:size], nil
+	}
+}
+
+// Устанавливает расширенный атрибут без перехода
+// по символической ссылке
+func lsetxattr(path string, attr Xattr) error {
+	return unix.Lsetxattr(path, attr.Name, attr.Value, 0)
+}
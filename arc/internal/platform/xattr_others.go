@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package platform
+
+import "errors"
+
+var errXattrUnsupported = errors.New("расширенные атрибуты не поддерживаются")
+
+// Расширенные атрибуты читаются только в Linux
+func xattrs(string) ([]Xattr, error) { return nil, nil }
+
+func lsetxattr(string, Xattr) error { return errXattrUnsupported }
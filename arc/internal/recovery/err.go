@@ -0,0 +1,12 @@
+package recovery
+
+import "github.com/gh0st17/archiver/arc/internal/errors"
+
+var (
+	ErrWriteRecovery   = errors.ErrWriteRecovery
+	ErrReadRecovery    = errors.ErrReadRecovery
+	ErrNoRecovery      = errors.ErrNoRecovery
+	ErrRecoveryDamaged = errors.ErrRecoveryDamaged
+	ErrUnrepairable    = errors.ErrUnrepairable
+	ErrSeek            = errors.ErrSeek
+)
@@ -0,0 +1,89 @@
+package recovery
+
+// Арифметика поля Галуа GF(2^8) с порождающим
+// многочленом x^8 + x^4 + x^3 + x^2 + 1
+
+const gfPoly = 0x11d
+
+var (
+	gfExp [510]byte      // Степени порождающего элемента
+	gfLog [256]byte      // Логарифмы по порождающему элементу
+	gfMul [256][256]byte // Таблица умножения
+)
+
+// Умножает a на b
+func mul(a, b byte) byte { return gfMul[a][b] }
+
+// Возвращает обратный к a элемент, a не равен нулю
+func inv(a byte) byte { return gfExp[255-int(gfLog[a])] }
+
+// Коэффициент матрицы Коши для блока четности p и
+// блока данных r группы из m блоков четности. Любая
+// квадратная подматрица матрицы Коши обратима, поэтому
+// любые m поврежденных блоков группы восстановимы
+func coef(p, r, m int) byte { return inv(byte(p) ^ byte(m+r)) }
+
+// Прибавляет к dst произведение src на c
+func mulAdd(dst, src []byte, c byte) {
+	mt := &gfMul[c]
+	for i, b := range src {
+		dst[i] ^= mt[b]
+	}
+}
+
+// Обращает квадратную матрицу a методом Гаусса-Жордана.
+// Матрица a обратима, так как является подматрицей
+// матрицы Коши
+func invert(a [][]byte) [][]byte {
+	n := len(a)
+	res := make([][]byte, n)
+	for i := range res {
+		res[i] = make([]byte, n)
+		res[i][i] = 1
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for a[pivot][col] == 0 {
+			pivot++
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		res[col], res[pivot] = res[pivot], res[col]
+
+		c := inv(a[col][col])
+		for j := 0; j < n; j++ {
+			a[col][j] = mul(a[col][j], c)
+			res[col][j] = mul(res[col][j], c)
+		}
+
+		for i := 0; i < n; i++ {
+			if i == col || a[i][col] == 0 {
+				continue
+			}
+			c := a[i][col]
+			for j := 0; j < n; j++ {
+				a[i][j] ^= mul(a[col][j], c)
+				res[i][j] ^= mul(res[col][j], c)
+			}
+		}
+	}
+
+	return res
+}
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i], gfExp[i+255] = byte(x), byte(x)
+		gfLog[x] = byte(i)
+		if x <<= 1; x&0x100 != 0 {
+			x ^= gfPoly
+		}
+	}
+
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			gfMul[a][b] = gfExp[int(gfLog[a])+int(gfLog[b])]
+		}
+	}
+}
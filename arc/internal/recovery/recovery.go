@@ -0,0 +1,510 @@
+// Пакет recovery предоставляет запись восстановления
+// архива: блоки четности кода Рида-Соломона, по которым
+// восстанавливаются поврежденные области архива
+package recovery
+
+import (
+	"bytes"
+	"io"
+
+	"slices"
+	"sync"
+
+	"github.com/gh0st17/archiver/arc/internal/generic"
+	"github.com/gh0st17/archiver/arc/internal/volume"
+	"github.com/gh0st17/archiver/errtype"
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+const (
+	// Сигнатура завершающей записи восстановления
+	Magic uint32 = 0x56434552
+	// Длина завершающей записи восстановления:
+	// смещение записи восстановления и сигнатура
+	TrailerLen int64 = 12
+	// Размер блока данных и блока четности
+	ShardSize = 4096
+
+	// Наибольшее число блоков в группе, при котором
+	// элементы матрицы Коши различны
+	maxShards = 256
+	// Наибольший размер блоков четности,
+	// вычисляемых за один проход по архиву
+	batchSize = 64 << 20
+	// Длина описания записи восстановления
+	layoutLen = 20
+)
+
+// Описание записи восстановления. Защищаемые данные
+// делятся на блоки размера ShardSize, блок i относится
+// к группе i % Groups, поэтому соседние блоки находятся
+// в разных группах и протяженное повреждение затрагивает
+// в каждой группе не более нескольких блоков. Для каждой
+// группы из не более чем K блоков данных хранится M
+// блоков четности
+type layout struct {
+	Size      int64  // Размер защищаемых данных
+	ShardSize uint32 // Размер блока
+	K, M      uint16 // Число блоков данных и четности в группе
+	Groups    uint32 // Число групп
+}
+
+// Возвращает описание записи восстановления для данных
+// размера size с избыточностью percent процентов
+func newLayout(size int64, percent int) layout {
+	l := layout{Size: size, ShardSize: ShardSize}
+
+	n := l.shards()
+	k := min(n, int64(maxShards*100/(100+percent)))
+	m := max(1, (k*int64(percent)+99)/100)
+	k = max(1, min(k, maxShards-m))
+
+	l.K, l.M = uint16(k), uint16(m)
+	l.Groups = uint32((n + k - 1) / k)
+
+	return l
+}
+
+// Возвращает число блоков данных
+func (l layout) shards() int64 {
+	return (l.Size + int64(l.ShardSize) - 1) / int64(l.ShardSize)
+}
+
+// Возвращает число блоков четности
+func (l layout) parity() int64 { return int64(l.Groups) * int64(l.M) }
+
+// Возвращает длину блока данных i, последний
+// блок может быть короче ShardSize
+func (l layout) shardLen(i int64) int {
+	return int(min(int64(l.ShardSize), l.Size-i*int64(l.ShardSize)))
+}
+
+// Возвращает число блоков данных в группе j
+func (l layout) rows(j int64) int {
+	return int((l.shards() - j + int64(l.Groups) - 1) / int64(l.Groups))
+}
+
+// Возвращает длину описания вместе с CRC блоков
+func (l layout) metaLen() int64 {
+	return layoutLen + 4*(l.shards()+l.parity()) + 4
+}
+
+// Возвращает смещение блоков четности в архиве
+func (l layout) parityOffset() int64 { return l.Size + l.metaLen() }
+
+// Возвращает длину записи восстановления
+func (l layout) len() int64 {
+	return l.metaLen() + l.parity()*int64(l.ShardSize) + TrailerLen
+}
+
+// Проверяет корректность описания
+func (l layout) valid() bool {
+	return l.Size > 0 && l.ShardSize > 0 && l.K > 0 && l.M > 0 &&
+		int(l.K)+int(l.M) <= maxShards &&
+		int64(l.Groups) == (l.shards()+int64(l.K)-1)/int64(l.K)
+}
+
+// Записывает в конец f запись восстановления для всего
+// содержимого f с избыточностью percent процентов.
+//
+// Запись восстановления состоит из описания, CRC блоков
+// данных и блоков четности, CRC описания, блоков четности
+// и завершающей записи, содержащей смещение записи
+// восстановления и сигнатуру [Magic]
+func Write(f volume.File, percent int) error {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return errtype.Join(ErrSeek, err)
+	}
+
+	var (
+		l         = newLayout(size, percent)
+		n         = l.shards()
+		groups    = int64(l.Groups)
+		m         = int(l.M)
+		s         = int64(l.ShardSize)
+		batch     = max(1, batchSize/(int64(m)*s))
+		dataCRC   = make([]uint32, n)
+		parityCRC = make([]uint32, l.parity())
+	)
+
+	for j0 := int64(0); j0 < groups; j0 += batch {
+		j1 := min(groups, j0+batch)
+		parity := make([]byte, (j1-j0)*int64(m)*s)
+		buf := make([]byte, (j1-j0)*s)
+
+		// Строка r группы состоит из блоков r*Groups+j,
+		// поэтому блоки строки пакета групп смежны
+		for r := 0; r < int(l.K); r++ {
+			first := int64(r)*groups + j0
+			if first >= n {
+				break
+			}
+			last := min(int64(r)*groups+j1, n)
+
+			clear(buf)
+			length := min((last-first)*s, size-first*s)
+			if _, err = f.ReadAt(buf[:length], first*s); err != nil {
+				return errtype.Join(ErrReadRecovery, err)
+			}
+
+			for i := first; i < last; i++ {
+				off := (i - first) * s
+				dataCRC[i] = generic.Checksum(buf[off : off+int64(l.shardLen(i))])
+			}
+
+			parallel(int(last-first), func(jj int) {
+				shard := buf[int64(jj)*s : int64(jj+1)*s]
+				for p := 0; p < m; p++ {
+					off := int64(jj*m+p) * s
+					mulAdd(parity[off:off+s], shard, coef(p, r, m))
+				}
+			})
+		}
+
+		for i := int64(0); i < int64(len(parity))/s; i++ {
+			parityCRC[j0*int64(m)+i] = generic.Checksum(parity[i*s : (i+1)*s])
+		}
+		if _, err = f.WriteAt(parity, l.parityOffset()+j0*int64(m)*s); err != nil {
+			return errtype.Join(ErrWriteRecovery, err)
+		}
+	}
+
+	meta := bytes.NewBuffer(nil)
+	if err = filesystem.BinaryWrite(meta, l); err != nil {
+		return errtype.Join(ErrWriteRecovery, err)
+	}
+	if err = filesystem.BinaryWrite(meta, dataCRC); err != nil {
+		return errtype.Join(ErrWriteRecovery, err)
+	}
+	if err = filesystem.BinaryWrite(meta, parityCRC); err != nil {
+		return errtype.Join(ErrWriteRecovery, err)
+	}
+	if err = filesystem.BinaryWrite(meta, generic.Checksum(meta.Bytes())); err != nil {
+		return errtype.Join(ErrWriteRecovery, err)
+	}
+	if _, err = f.WriteAt(meta.Bytes(), size); err != nil {
+		return errtype.Join(ErrWriteRecovery, err)
+	}
+
+	// Завершающая запись
+	w := io.NewOffsetWriter(f, size+l.len()-TrailerLen)
+	if err = filesystem.BinaryWrite(w, size); err != nil {
+		return errtype.Join(ErrWriteRecovery, err)
+	}
+	if err = filesystem.BinaryWrite(w, Magic); err != nil {
+		return errtype.Join(ErrWriteRecovery, err)
+	}
+
+	return nil
+}
+
+// Возвращает смещение записи восстановления по
+// завершающей записи в конце r, оно же размер
+// защищаемых данных. Возвращает [ErrNoRecovery],
+// если завершающей записи нет
+func End(r io.ReadSeeker) (int64, error) {
+	var (
+		offset int64
+		magic  uint32
+	)
+
+	end, err := r.Seek(-TrailerLen, io.SeekEnd)
+	if err != nil {
+		return 0, ErrNoRecovery
+	}
+	if err = filesystem.BinaryRead(r, &offset); err != nil {
+		return 0, errtype.Join(ErrReadRecovery, err)
+	}
+	if err = filesystem.BinaryRead(r, &magic); err != nil {
+		return 0, errtype.Join(ErrReadRecovery, err)
+	}
+
+	if magic != Magic {
+		return 0, ErrNoRecovery
+	}
+	if offset <= 0 || offset > end {
+		return 0, ErrRecoveryDamaged
+	}
+
+	return offset, nil
+}
+
+// Результат проверки архива по записи восстановления
+type Report struct {
+	Damaged       int  // Число поврежденных блоков данных
+	DamagedParity int  // Число поврежденных блоков четности
+	Repairable    bool // Повреждения можно исправить
+}
+
+// Проверяет блоки архива f по записи восстановления
+func Verify(f volume.File) (Report, error) {
+	st, err := scan(f)
+	if err != nil {
+		return Report{}, err
+	}
+
+	return st.report(), nil
+}
+
+// Проверяет блоки архива f по записи восстановления
+// и восстанавливает поврежденные блоки. Возвращает
+// [ErrUnrepairable], если повреждений слишком много
+func Repair(f volume.File) (Report, error) {
+	st, err := scan(f)
+	if err != nil {
+		return Report{}, err
+	}
+
+	rep := st.report()
+	if !rep.Repairable {
+		return rep, ErrUnrepairable
+	}
+
+	for j := range st.badData {
+		if err = st.repairGroup(f, j); err != nil {
+			return rep, err
+		}
+	}
+	for j := range st.badParity {
+		if _, ok := st.badData[j]; ok {
+			continue // Уже восстановлена
+		}
+		if err = st.repairGroup(f, j); err != nil {
+			return rep, err
+		}
+	}
+
+	return rep, nil
+}
+
+// Состояние проверки архива
+type state struct {
+	layout
+	dataCRC, parityCRC []uint32
+	badData            map[int64][]int // Поврежденные строки групп
+	badParity          map[int64][]int // Поврежденные блоки четности групп
+}
+
+// Читает запись восстановления архива f
+// и находит поврежденные блоки
+func scan(f volume.File) (*state, error) {
+	var (
+		st   = &state{badData: map[int64][]int{}, badParity: map[int64][]int{}}
+		size int64
+		err  error
+	)
+
+	if size, err = End(f); err != nil {
+		return nil, err
+	}
+
+	r := io.NewSectionReader(f, size, layoutLen)
+	if err = filesystem.BinaryRead(r, &st.layout); err != nil {
+		return nil, errtype.Join(ErrReadRecovery, err)
+	}
+	if !st.valid() || st.Size != size {
+		return nil, ErrRecoveryDamaged
+	}
+	if end, err := f.Seek(0, io.SeekEnd); err != nil || end != size+st.len() {
+		return nil, ErrRecoveryDamaged
+	}
+
+	meta := make([]byte, st.metaLen())
+	if _, err = f.ReadAt(meta, size); err != nil {
+		return nil, errtype.Join(ErrReadRecovery, err)
+	}
+	metaLen := len(meta) - 4
+	var metaCRC uint32
+	filesystem.BinaryRead(bytes.NewReader(meta[metaLen:]), &metaCRC)
+	if generic.Checksum(meta[:metaLen]) != metaCRC {
+		return nil, ErrRecoveryDamaged
+	}
+
+	tables := bytes.NewReader(meta[layoutLen:metaLen])
+	st.dataCRC = make([]uint32, st.shards())
+	st.parityCRC = make([]uint32, st.parity())
+	filesystem.BinaryRead(tables, st.dataCRC)
+	filesystem.BinaryRead(tables, st.parityCRC)
+
+	if err = st.check(f); err != nil {
+		return nil, errtype.Join(ErrReadRecovery, err)
+	}
+
+	return st, nil
+}
+
+// Сверяет CRC блоков данных и блоков четности
+func (st *state) check(f volume.File) error {
+	var (
+		s      = int64(st.ShardSize)
+		groups = int64(st.Groups)
+		m      = int64(st.M)
+		buf    = make([]byte, (int64(generic.BufferSize)/s)*s)
+	)
+
+	// Блоки данных
+	for off := int64(0); off < st.Size; off += int64(len(buf)) {
+		chunk := buf[:min(int64(len(buf)), st.Size-off)]
+		if _, err := f.ReadAt(chunk, off); err != nil {
+			return err
+		}
+		for i := off / s; i*s < off+int64(len(chunk)); i++ {
+			from := i*s - off
+			if generic.Checksum(chunk[from:from+int64(st.shardLen(i))]) != st.dataCRC[i] {
+				st.badData[i%groups] = append(st.badData[i%groups], int(i/groups))
+			}
+		}
+	}
+
+	// Блоки четности
+	total := st.parity() * s
+	for off := int64(0); off < total; off += int64(len(buf)) {
+		chunk := buf[:min(int64(len(buf)), total-off)]
+		if _, err := f.ReadAt(chunk, st.parityOffset()+off); err != nil {
+			return err
+		}
+		for i := off / s; i*s < off+int64(len(chunk)); i++ {
+			from := i*s - off
+			if generic.Checksum(chunk[from:from+s]) != st.parityCRC[i] {
+				st.badParity[i/m] = append(st.badParity[i/m], int(i%m))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Возвращает результат проверки
+func (st *state) report() (rep Report) {
+	rep.Repairable = true
+
+	for j, rows := range st.badData {
+		rep.Damaged += len(rows)
+		if len(rows)+len(st.badParity[j]) > int(st.M) {
+			rep.Repairable = false
+		}
+	}
+	for j, ps := range st.badParity {
+		rep.DamagedParity += len(ps)
+		if len(st.badData[j])+len(ps) > int(st.M) {
+			rep.Repairable = false
+		}
+	}
+
+	return rep
+}
+
+// Восстанавливает поврежденные блоки данных группы j
+// по неповрежденным блокам четности, затем заново
+// вычисляет поврежденные блоки четности
+func (st *state) repairGroup(f volume.File, j int64) error {
+	var (
+		s      = int64(st.ShardSize)
+		groups = int64(st.Groups)
+		m      = int(st.M)
+		rows   = st.rows(j)
+		bad    = st.badData[j]
+		data   = make([][]byte, rows)
+		isBad  = make(map[int]bool, len(bad))
+	)
+
+	for _, r := range bad {
+		isBad[r] = true
+	}
+
+	for r := range data {
+		data[r] = make([]byte, s)
+		if isBad[r] {
+			continue
+		}
+		i := int64(r)*groups + j
+		if _, err := f.ReadAt(data[r][:st.shardLen(i)], i*s); err != nil {
+			return errtype.Join(ErrReadRecovery, err)
+		}
+	}
+
+	if len(bad) > 0 {
+		// Неповрежденные блоки четности, по одному
+		// на каждый поврежденный блок данных
+		var good []int
+		for p := 0; p < m && len(good) < len(bad); p++ {
+			if !slices.Contains(st.badParity[j], p) {
+				good = append(good, p)
+			}
+		}
+
+		// Правая часть: блок четности без вклада
+		// неповрежденных блоков данных
+		rhs := make([][]byte, len(bad))
+		a := make([][]byte, len(bad))
+		for k, p := range good {
+			rhs[k] = make([]byte, s)
+			if _, err := f.ReadAt(rhs[k], st.parityOffset()+(j*int64(m)+int64(p))*s); err != nil {
+				return errtype.Join(ErrReadRecovery, err)
+			}
+			for r := range data {
+				if !isBad[r] {
+					mulAdd(rhs[k], data[r], coef(p, r, m))
+				}
+			}
+
+			a[k] = make([]byte, len(bad))
+			for e, r := range bad {
+				a[k][e] = coef(p, r, m)
+			}
+		}
+
+		ainv := invert(a)
+		for e, r := range bad {
+			for k := range rhs {
+				mulAdd(data[r], rhs[k], ainv[e][k])
+			}
+
+			i := int64(r)*groups + j
+			shard := data[r][:st.shardLen(i)]
+			if generic.Checksum(shard) != st.dataCRC[i] {
+				return ErrUnrepairable
+			}
+			if _, err := f.WriteAt(shard, i*s); err != nil {
+				return errtype.Join(ErrWriteRecovery, err)
+			}
+		}
+	}
+
+	for _, p := range st.badParity[j] {
+		parity := make([]byte, s)
+		for r := range data {
+			mulAdd(parity, data[r], coef(p, r, m))
+		}
+
+		i := j*int64(m) + int64(p)
+		if generic.Checksum(parity) != st.parityCRC[i] {
+			return ErrUnrepairable
+		}
+		if _, err := f.WriteAt(parity, st.parityOffset()+i*s); err != nil {
+			return errtype.Join(ErrWriteRecovery, err)
+		}
+	}
+
+	return nil
+}
+
+// Выполняет f(i) для i от 0 до n параллельно
+func parallel(n int, f func(i int)) {
+	var (
+		ncpu = min(generic.Ncpu(), n)
+		wg   sync.WaitGroup
+	)
+
+	for c := 0; c < ncpu; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			for i := c; i < n; i += ncpu {
+				f(i)
+			}
+		}(c)
+	}
+	wg.Wait()
+}
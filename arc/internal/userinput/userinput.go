@@ -96,3 +96,13 @@ func IsNonInteractive() bool {
 	fi, _ := os.Stdin.Stat()
 	return (fi.Mode()&os.ModeCharDevice) == 0 || !term.IsTerminal(int(os.Stdin.Fd()))
 }
+
+// Читает пароль из терминала без отображения
+// вводимых символов
+func ReadPassword(prompt string) ([]byte, error) {
+	fmt.Print(prompt)
+	pass, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+
+	return pass, err
+}
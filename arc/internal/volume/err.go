@@ -0,0 +1,11 @@
+package volume
+
+import "github.com/gh0st17/archiver/arc/internal/errors"
+
+var (
+	ErrVolumeMissing = errors.ErrVolumeMissing
+	ErrVolumeHeader  = errors.ErrVolumeHeader
+	ErrVolumeSet     = errors.ErrVolumeSet
+	ErrVolumeSize    = errors.ErrVolumeSize
+	ErrSeekVolume    = errors.ErrSeekVolume
+)
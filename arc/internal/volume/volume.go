@@ -0,0 +1,333 @@
+// Пакет volume предоставляет файл архива, который
+// может быть разделен на тома фиксированного размера
+// с именами вида name.arc.001, name.arc.002 и т.д.
+//
+// Каждый том начинается с заголовка, содержащего
+// идентификатор набора томов, номер тома и размер
+// данных тома. Последний том набора отмечается флагом,
+// поэтому отсутствие томов в конце набора обнаруживается
+package volume
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gh0st17/archiver/filesystem"
+)
+
+const (
+	Magic uint32 = 0x314c4f56 // Сигнатура заголовка тома "VOL1"
+
+	flagLast byte = 1 // Последний том набора
+)
+
+// Файл архива: обычный файл или набор томов
+type File interface {
+	io.ReadWriteSeeker
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+	Name() string
+}
+
+// Заголовок тома
+type volHeader struct {
+	Magic   uint32
+	SetID   [8]byte // Идентификатор набора томов
+	Number  uint32  // Номер тома, начиная с 1
+	DataLen int64   // Размер данных каждого тома, кроме последнего
+	Flags   byte
+}
+
+// Длина заголовка тома
+var headerLen = int64(binary.Size(volHeader{}))
+
+// Набор томов, представленный как один файл
+type Set struct {
+	base    string     // Путь архива без номера тома
+	vols    []*os.File // Открытые тома
+	hdr     volHeader  // Заголовок первого тома
+	size    int64      // Размер данных всех томов
+	pos     int64      // Текущее смещение
+	created bool       // Набор создается
+}
+
+// Возвращает путь тома с номером n
+func volPath(base string, n int) string {
+	return fmt.Sprintf("%s.%03d", base, n)
+}
+
+// Возвращает путь архива без номера тома, если
+// path является путем тома
+func baseOf(path string) (string, bool) {
+	ext := filepath.Ext(path)
+	if len(ext) < 4 {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(ext[1:], 10, 32); err != nil {
+		return "", false
+	}
+
+	return strings.TrimSuffix(path, ext), true
+}
+
+// Проверяет, является ли path путем архива из томов
+// или путем одного из его томов
+func IsSet(path string) (base string, ok bool) {
+	if base, ok = baseOf(path); ok && isVolume(path) {
+		return base, true
+	}
+	if _, err := os.Stat(path); err != nil && isVolume(volPath(path, 1)) {
+		return path, true
+	}
+
+	return "", false
+}
+
+// Проверяет, начинается ли файл path с заголовка тома
+func isVolume(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	var h volHeader
+	return filesystem.BinaryRead(f, &h) == nil && h.Magic == Magic
+}
+
+// Проверяет, существует ли архив path
+// в виде файла или набора томов
+func Exists(path string) bool {
+	if _, err := os.Stat(path); err == nil {
+		return true
+	}
+	_, ok := IsSet(path)
+	return ok
+}
+
+// Открывает архив path, который может быть набором
+// томов, с флагами flag. Возвращает [ErrVolumeMissing],
+// если тома набора не хватает
+func Open(path string, flag int) (File, error) {
+	base, ok := IsSet(path)
+	if !ok {
+		return os.OpenFile(path, flag, 0)
+	}
+
+	s := &Set{base: base}
+	for n := 1; ; n++ {
+		name := volPath(base, n)
+		f, err := os.OpenFile(name, flag, 0)
+		if errors.Is(err, os.ErrNotExist) {
+			s.Close()
+			return nil, ErrVolumeMissing(name)
+		} else if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.vols = append(s.vols, f)
+
+		var h volHeader
+		if err = filesystem.BinaryRead(f, &h); err != nil || h.Magic != Magic {
+			s.Close()
+			return nil, ErrVolumeHeader(name)
+		}
+		if n == 1 {
+			s.hdr = h
+		}
+		if h.SetID != s.hdr.SetID || h.Number != uint32(n) || h.DataLen != s.hdr.DataLen || h.DataLen <= 0 {
+			s.Close()
+			return nil, ErrVolumeSet(name)
+		}
+
+		info, err := f.Stat()
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		dataLen := info.Size() - headerLen
+		if h.Flags&flagLast != 0 {
+			s.size += dataLen
+			return s, nil
+		} else if dataLen != h.DataLen {
+			s.Close()
+			return nil, ErrVolumeSize(name)
+		}
+		s.size += dataLen
+	}
+}
+
+// Создает архив path. Если size > 0, то архив
+// разделяется на тома размера size, иначе
+// создается обычный файл
+func Create(path string, size int64) (File, error) {
+	if size <= 0 {
+		return os.Create(path)
+	}
+
+	Remove(path) // Тома прежнего архива
+
+	s := &Set{
+		base:    path,
+		created: true,
+		hdr:     volHeader{Magic: Magic, DataLen: size - headerLen},
+	}
+	if _, err := rand.Read(s.hdr.SetID[:]); err != nil {
+		return nil, err
+	}
+	if err := s.addVolume(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Удаляет архив path вместе со всеми его томами
+func Remove(path string) error {
+	err := os.Remove(path)
+	for n := 1; os.Remove(volPath(path, n)) == nil; n++ {
+		err = nil
+	}
+	return err
+}
+
+// Создает очередной том набора
+func (s *Set) addVolume() error {
+	f, err := os.Create(volPath(s.base, len(s.vols)+1))
+	if err != nil {
+		return err
+	}
+
+	h := s.hdr
+	h.Number = uint32(len(s.vols) + 1)
+	if err = filesystem.BinaryWrite(f, h); err != nil {
+		f.Close()
+		return err
+	}
+	s.vols = append(s.vols, f)
+
+	return nil
+}
+
+// Вызывает op для частей области длины n со смещения off,
+// которые находятся в разных томах. Части передаются как
+// том, смещение в томе и границы части в области
+func (s *Set) span(off int64, n int, op func(vol int, volOff int64, lo, hi int) error) error {
+	for lo := 0; lo < n; {
+		vol := int((off + int64(lo)) / s.hdr.DataLen)
+		within := (off + int64(lo)) % s.hdr.DataLen
+		hi := lo + int(min(int64(n-lo), s.hdr.DataLen-within))
+
+		if err := op(vol, headerLen+within, lo, hi); err != nil {
+			return err
+		}
+		lo = hi
+	}
+
+	return nil
+}
+
+// Реализация io.ReaderAt
+func (s *Set) ReadAt(p []byte, off int64) (n int, err error) {
+	if off >= s.size {
+		return 0, io.EOF
+	}
+	if rest := s.size - off; int64(len(p)) > rest {
+		p, err = p[:rest], io.EOF
+	}
+
+	e := s.span(off, len(p), func(vol int, volOff int64, lo, hi int) error {
+		m, err := s.vols[vol].ReadAt(p[lo:hi], volOff)
+		n += m
+		return err
+	})
+	if e != nil {
+		return n, e
+	}
+
+	return n, err
+}
+
+// Реализация io.WriterAt. При записи в создаваемый набор
+// за пределами последнего тома создаются новые тома
+func (s *Set) WriteAt(p []byte, off int64) (n int, err error) {
+	err = s.span(off, len(p), func(vol int, volOff int64, lo, hi int) error {
+		for s.created && vol >= len(s.vols) {
+			if err := s.addVolume(); err != nil {
+				return err
+			}
+		}
+		if vol >= len(s.vols) {
+			return io.ErrShortWrite
+		}
+
+		m, err := s.vols[vol].WriteAt(p[lo:hi], volOff)
+		n += m
+		return err
+	})
+	s.size = max(s.size, off+int64(n))
+
+	return n, err
+}
+
+// Реализация io.Reader
+func (s *Set) Read(p []byte) (n int, err error) {
+	n, err = s.ReadAt(p, s.pos)
+	s.pos += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// Реализация io.Writer
+func (s *Set) Write(p []byte) (n int, err error) {
+	n, err = s.WriteAt(p, s.pos)
+	s.pos += int64(n)
+	return n, err
+}
+
+// Реализация io.Seeker
+func (s *Set) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += s.pos
+	case io.SeekEnd:
+		offset += s.size
+	}
+	if offset < 0 {
+		return 0, ErrSeekVolume
+	}
+
+	s.pos = offset
+	return offset, nil
+}
+
+// Возвращает путь архива без номера тома
+func (s *Set) Name() string { return s.base }
+
+// Закрывает тома. У создаваемого набора
+// последний том отмечается флагом
+func (s *Set) Close() (err error) {
+	if s.created && len(s.vols) > 0 {
+		last := s.vols[len(s.vols)-1]
+		_, err = last.WriteAt([]byte{flagLast}, headerLen-1)
+	}
+
+	for _, f := range s.vols {
+		if e := f.Close(); err == nil {
+			err = e
+		}
+	}
+	s.vols = nil
+
+	return err
+}
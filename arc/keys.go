@@ -0,0 +1,140 @@
+package arc
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+
+	"github.com/gh0st17/archiver/arc/internal/crypt"
+	"github.com/gh0st17/archiver/errtype"
+)
+
+// Создает пару ключей X25519 для шифрования:
+// закрытый ключ записывается в path, открытый --
+// в path.pub. Существующие файлы не перезаписываются
+func GenerateKeys(path string) error {
+	identity, recipient, err := crypt.GenerateKey()
+	if err != nil {
+		return errtype.ErrRuntime(errtype.Join(ErrWriteKey, err))
+	}
+
+	return writeKeyPair(path, identity, recipient)
+}
+
+// Создает пару ключей Ed25519 для подписи так же,
+// как [GenerateKeys]
+func GenerateSignKeys(path string) error {
+	private, public, err := crypt.GenerateSignKey()
+	if err != nil {
+		return errtype.ErrRuntime(errtype.Join(ErrWriteKey, err))
+	}
+
+	return writeKeyPair(path, private, public)
+}
+
+// Записывает закрытый ключ в path, открытый -- в path.pub
+func writeKeyPair(path string, private, public []byte) error {
+	if err := writeKey(path, private, 0600); err != nil {
+		return errtype.ErrRuntime(errtype.Join(ErrWriteKey, err))
+	}
+	if err := writeKey(path+".pub", public, 0644); err != nil {
+		os.Remove(path)
+		return errtype.ErrRuntime(errtype.Join(ErrWriteKey, err))
+	}
+
+	fmt.Println("Закрытый ключ:", path)
+	fmt.Println("Открытый ключ:", path+".pub")
+
+	return nil
+}
+
+// Записывает ключ в новый файл path с режимом perm
+func writeKey(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+
+	return f.Close()
+}
+
+// Читает открытые ключи получателей из файлов paths
+func readRecipients(paths []string) (keys []*ecdh.PublicKey, err error) {
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errtype.Join(ErrReadKey, err)
+		}
+
+		parsed := crypt.ParseRecipients(data)
+		if len(parsed) == 0 {
+			return nil, ErrKeyFormat(path)
+		}
+		keys = append(keys, parsed...)
+	}
+
+	return keys, nil
+}
+
+// Читает закрытые ключи из файлов paths. Возвращает
+// [ErrNoIdentity], если файлы не заданы
+func readIdentities(paths []string) (keys []*ecdh.PrivateKey, err error) {
+	if len(paths) == 0 {
+		return nil, ErrNoIdentity
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errtype.Join(ErrReadKey, err)
+		}
+
+		parsed := crypt.ParseIdentities(data)
+		if len(parsed) == 0 {
+			return nil, ErrKeyFormat(path)
+		}
+		keys = append(keys, parsed...)
+	}
+
+	return keys, nil
+}
+
+// Читает закрытый ключ подписи из файла path
+func readSignKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errtype.Join(ErrReadKey, err)
+	}
+
+	key := crypt.ParseSignKey(data)
+	if key == nil {
+		return nil, ErrKeyFormat(path)
+	}
+
+	return key, nil
+}
+
+// Читает открытые ключи доверенных подписей из файлов paths
+func readSigners(paths []string) (keys []ed25519.PublicKey, err error) {
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errtype.Join(ErrReadKey, err)
+		}
+
+		parsed := crypt.ParseSigners(data)
+		if len(parsed) == 0 {
+			return nil, ErrKeyFormat(path)
+		}
+		keys = append(keys, parsed...)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,74 @@
+package arc
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gh0st17/archiver/arc/internal/recovery"
+	"github.com/gh0st17/archiver/arc/internal/volume"
+	"github.com/gh0st17/archiver/errtype"
+)
+
+// Исправляет поврежденные области архива
+// по записи восстановления
+func (arc Arc) Repair() error {
+	arcFile, err := volume.Open(arc.path, os.O_RDWR)
+	if err != nil {
+		return errtype.ErrIntegrity(errtype.Join(ErrOpenArc, err))
+	}
+	defer arcFile.Close()
+
+	rep, err := recovery.Repair(arcFile)
+	if err != nil {
+		printRecovery(rep, err)
+		return errtype.ErrIntegrity(errtype.Join(ErrRepair, err))
+	}
+
+	if rep.Damaged+rep.DamagedParity == 0 {
+		fmt.Println("Повреждений нет")
+	} else {
+		fmt.Printf(
+			"Исправлено блоков: %d, блоков четности: %d\n",
+			rep.Damaged, rep.DamagedParity,
+		)
+	}
+
+	return nil
+}
+
+// Проверяет архив по записи восстановления
+// и печатает результат проверки
+func (arc Arc) checkRecovery() {
+	arcFile, err := volume.Open(arc.path, os.O_RDONLY)
+	if err != nil {
+		printRecovery(recovery.Report{}, errtype.Join(ErrOpenArc, err))
+		return
+	}
+	defer arcFile.Close()
+
+	rep, err := recovery.Verify(arcFile)
+	printRecovery(rep, err)
+}
+
+// Печатает результат проверки архива
+// по записи восстановления
+func printRecovery(rep recovery.Report, err error) {
+	fmt.Print("Запись восстановления: ")
+
+	switch {
+	case err == recovery.ErrUnrepairable || err == nil && !rep.Repairable:
+		fmt.Printf(
+			"повреждено блоков: %d, блоков четности: %d, повреждения нельзя исправить\n",
+			rep.Damaged, rep.DamagedParity,
+		)
+	case err != nil:
+		fmt.Println(err)
+	case rep.Damaged+rep.DamagedParity == 0:
+		fmt.Println("повреждений нет")
+	default:
+		fmt.Printf(
+			"повреждено блоков: %d, блоков четности: %d, повреждения можно исправить флагом '-repair'\n",
+			rep.Damaged, rep.DamagedParity,
+		)
+	}
+}
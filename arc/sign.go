@@ -0,0 +1,65 @@
+package arc
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/gh0st17/archiver/arc/internal/crypt"
+	"github.com/gh0st17/archiver/arc/internal/header"
+	"github.com/gh0st17/archiver/arc/internal/recovery"
+	"github.com/gh0st17/archiver/arc/internal/volume"
+	"github.com/gh0st17/archiver/errtype"
+)
+
+// Проверяет подпись архива и печатает ее ключ
+func (arc Arc) Verify() error {
+	key, err := arc.verifySignature()
+	if err != nil {
+		return errtype.ErrIntegrity(err)
+	}
+
+	fmt.Println("Подпись верна, ключ подписи:", base64.StdEncoding.EncodeToString(key))
+	return nil
+}
+
+// Проверяет подпись архива и возвращает ее ключ.
+// Если заданы доверенные ключи, то ключ подписи
+// должен быть одним из них
+func (arc Arc) verifySignature() (ed25519.PublicKey, error) {
+	if !arc.format.Has(header.FeatSigned) {
+		return nil, ErrNotSigned
+	}
+
+	arcFile, err := volume.Open(arc.path, os.O_RDONLY)
+	if err != nil {
+		return nil, errtype.Join(ErrOpenArc, err)
+	}
+	defer arcFile.Close()
+
+	// Подпись предшествует записи восстановления
+	end, err := arcFile.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, errtype.Join(ErrSeek, err)
+	}
+	if arc.format.Has(header.FeatRecovery) {
+		if end, err = recovery.End(arcFile); err != nil {
+			return nil, errtype.Join(ErrReadSignature, err)
+		}
+	}
+
+	key, err := crypt.Verify(arcFile, end)
+	if err != nil {
+		return nil, err
+	}
+
+	trusted := func(k ed25519.PublicKey) bool { return k.Equal(key) }
+	if len(arc.signers) > 0 && !slices.ContainsFunc(arc.signers, trusted) {
+		return nil, ErrUntrustedSigner
+	}
+
+	return key, nil
+}
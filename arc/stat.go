@@ -2,14 +2,17 @@ package arc
 
 import (
 	"fmt"
-	"io"
-	"os"
 
 	"github.com/gh0st17/archiver/arc/internal/decompress"
 	"github.com/gh0st17/archiver/arc/internal/header"
 	"github.com/gh0st17/archiver/errtype"
 )
 
+// Элемент, способный печатать все свои временные метки
+type timesProvider interface {
+	TimesString() string
+}
+
 // Печатает информацию об архиве
 func (arc Arc) ViewStat() error {
 	if !header.IsEnoughWidth() {
@@ -18,55 +21,75 @@ func (arc Arc) ViewStat() error {
 		)
 	}
 
-	arcFile, err := os.OpenFile(arc.path, os.O_RDONLY, 0644)
-	if err != nil {
-		return errtype.ErrRuntime(
-			errtype.Join(ErrOpenArc, err),
-		)
+	if arc.hiddenWithoutKey() {
+		return nil
 	}
 
-	// Пропускаем магическое число и тип компрессора
-	if _, err = arcFile.Seek(headerLen, io.SeekStart); err != nil {
-		return errtype.ErrRuntime(errtype.Join(ErrSeek, err))
+	arcFile, err := arc.openArc()
+	if err != nil {
+		return errtype.ErrRuntime(err)
 	}
+	defer arcFile.Close()
 
-	headers, err := decompress.ReadHeaders(arcFile, headerLen)
+	headers, err := decompress.ReadHeaders(arcFile, arc.start)
 	if err != nil {
 		return errtype.ErrRuntime(errtype.Join(ErrReadHeaders, err))
 	}
 
+	fmt.Printf("Версия формата: %s\n", arc.format.Version)
 	fmt.Printf("Тип компрессора: %s\n", arc.Ct)
 	header.PrintStatHeader()
 
 	var original, compressed header.Size
 	for _, h := range headers {
 		fmt.Println(h)
+		if tp, ok := h.(timesProvider); ok && arc.times {
+			fmt.Println(tp.TimesString())
+		}
 
 		if fi, ok := h.(*header.FileItem); ok {
+			if fi.Digest().Type != header.DigestNone {
+				fmt.Println(fi.Digest())
+			}
 			original += fi.UcSize()
 			compressed += fi.CSize()
 		}
 	}
 	header.PrintSummary(compressed, original)
 
+	if arc.format.Has(header.FeatDedup) {
+		return arc.printDedup()
+	}
+	return nil
+}
+
+// Печатает размер данных файлов с фрагментами, размер
+// их уникальных фрагментов и их отношение
+func (arc Arc) printDedup() error {
+	chunks, err := arc.openChunks()
+	if err != nil {
+		return errtype.ErrRuntime(err)
+	}
+	defer chunks.Close()
+
+	header.PrintDedup(chunks.Stat())
+
 	return nil
 }
 
 // Печатает список файлов в архиве
 func (arc Arc) ViewList() error {
-	arcFile, err := os.OpenFile(arc.path, os.O_RDONLY, 0644)
-	if err != nil {
-		return errtype.ErrRuntime(
-			errtype.Join(ErrOpenArc, err),
-		)
+	if arc.hiddenWithoutKey() {
+		return nil
 	}
 
-	// Пропускаем магическое число и тип компрессора
-	if _, err = arcFile.Seek(headerLen, io.SeekStart); err != nil {
-		return errtype.ErrRuntime(errtype.Join(ErrSeek, err))
+	arcFile, err := arc.openArc()
+	if err != nil {
+		return errtype.ErrRuntime(err)
 	}
+	defer arcFile.Close()
 
-	headers, err := decompress.ReadHeaders(arcFile, headerLen)
+	headers, err := decompress.ReadHeaders(arcFile, arc.start)
 	if err != nil {
 		return errtype.ErrRuntime(
 			errtype.Join(ErrReadHeaders, err),
@@ -75,7 +98,9 @@ func (arc Arc) ViewList() error {
 
 	for _, h := range headers {
 		if si, ok := h.(*header.SymItem); ok {
-			fmt.Println(si.PathInArc(), "->", si.PathOnDisk())
+			fmt.Println(si.PathInArc(), "->", si.Target())
+		} else if li, ok := h.(*header.LinkItem); ok {
+			fmt.Println(li.PathInArc(), "=>", li.Target())
 		} else {
 			fmt.Println(h.PathOnDisk())
 		}
@@ -83,3 +108,19 @@ func (arc Arc) ViewList() error {
 
 	return nil
 }
+
+// Проверяет, что заголовки архива зашифрованы, а пароль
+// не задан, и в этом случае печатает только признак
+// шифрования архива
+func (arc Arc) hiddenWithoutKey() bool {
+	if !arc.format.Has(header.FeatHiddenHeaders) || arc.cipher != nil {
+		return false
+	}
+
+	if arc.format.Has(header.FeatRecipients) {
+		fmt.Println("Архив зашифрован, для просмотра содержимого нужен закрытый ключ")
+	} else {
+		fmt.Println("Архив зашифрован, для просмотра содержимого нужен пароль")
+	}
+	return true
+}
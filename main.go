@@ -12,6 +12,18 @@ func main() {
 		errtype.ErrorHandler(errtype.ErrArgument(err))
 	}
 
+	if p.KeyGen != "" || p.SignKeyGen != "" {
+		if p.KeyGen != "" {
+			err = arc.GenerateKeys(p.KeyGen)
+		} else {
+			err = arc.GenerateSignKeys(p.SignKeyGen)
+		}
+		if err != nil {
+			errtype.ErrorHandler(err)
+		}
+		return
+	}
+
 	a, err := arc.NewArc(*p)
 	if err != nil {
 		errtype.ErrorHandler(err)
@@ -28,6 +40,10 @@ func main() {
 	case p.PrintList:
 		params.PrintListIgnore()
 		err = a.ViewList()
+	case p.Repair:
+		err = a.Repair()
+	case p.Verify:
+		err = a.Verify()
 	case p.IntegTest:
 		params.PrintIntegIgnore()
 		err = a.IntegrityTest()
@@ -13,4 +13,18 @@ var (
 	ErrArchivePath     = fmt.Errorf("имя архива не указано")
 	ErrSelfContains    = fmt.Errorf("путь к файлу не должен указывать на указаннный архив")
 	ErrUnsupportedDict = compressor.ErrUnsupportedDict
+	ErrOwnerMode       = fmt.Errorf("режим восстановления владельца должен быть name, num или none")
+	ErrDigestType      = fmt.Errorf("тип контрольной суммы должен быть none, crc32c или sha256")
+	ErrRecovery        = fmt.Errorf("размер записи восстановления должен быть в пределах от 0 до 100")
+	ErrDamageMode      = fmt.Errorf("обработка поврежденных блоков должна быть zero, trunc или skip")
+	ErrVolumeSize      = fmt.Errorf("размер тома должен быть числом с суффиксом k, M или G не меньше 64k")
+	ErrSolidSize       = fmt.Errorf("размер solid-блока должен быть числом с суффиксом k, M или G не меньше 64k")
+	ErrRequireSigner   = fmt.Errorf("для '-requiresig' нужен хотя бы один ключ '-signer'")
+	ErrOwnerMapMode    = fmt.Errorf("без прав root для '-umap' и '-gmap' нужен '-owner name' или '-owner num'")
+	ErrOwnerMap        = func(pair string) error {
+		return fmt.Errorf("некорректная пара замены '%s', ожидается 'старый=новый'", pair)
+	}
+	ErrCodecRule = func(rule string) error {
+		return fmt.Errorf("некорректное правило '%s', ожидается 'шаблон=тип[:уровень]'", rule)
+	}
 )
@@ -11,9 +11,11 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 
 	c "github.com/gh0st17/archiver/compressor"
@@ -28,15 +30,118 @@ type Params struct {
 	Cl         c.Level  // Уровень сжатия
 	PrintStat  bool     // Флаг вывода информации об архиве
 	PrintList  bool     // Флаг вывода списка содержимого
+	PrintTimes bool     // Флаг вывода всех временных меток
 	IntegTest  bool     // Флаг проверки целостности
 	XIntegTest bool     // Флаг распаковки с учетом целостности
 	// Флаг вывода статистики использования ОЗУ после выполнения
 	MemStat bool
 	// Флаг замены всех файлов при распаковке без подтверждения
 	ReplaceAll bool
-	Verbose    bool
+	// Флаг распаковки без восстановления режима доступа
+	NoPerm bool
+	// Режим восстановления владельца при распаковке
+	Owner OwnerMode
+	// Таблицы замены пользователей и групп при распаковке
+	UserMap, GroupMap map[string]string
+	// Флаг сохранения расширенных атрибутов
+	Xattrs bool
+	// Флаг сохранения абсолютных путей назначения
+	// символических ссылок
+	SymAbs bool
+	// Пространства имен расширенных атрибутов для
+	// включения и исключения
+	XattrInclude, XattrExclude []string
+	// Пути элементов в архиве для выборочной распаковки
+	Extract []string
+	// Правила выбора компрессора для файлов
+	CodecRules []CodecRule
+	// Флаг хранения несжимаемых блоков без сжатия
+	Adaptive bool
+	// Флаг отказа от сжатия файла по первому блоку
+	Sample bool
+	// Тип контрольной суммы содержимого файлов
+	Digest DigestType
+	// Обработка поврежденных блоков при распаковке
+	Damaged DamageMode
+	// Размер записи восстановления в процентах
+	Recovery int
+	// Размер тома архива, 0 -- архив из одного файла
+	VolumeSize int64
+	// Размер данных solid-блока, 0 -- без solid-блоков
+	SolidSize int64
+	// Флаг дедупликации фрагментов данных файлов
+	Dedup bool
+	// Флаг хранения данных одинаковых файлов один раз
+	DupFiles bool
+	// Флаг восстановления архива по записи восстановления
+	Repair bool
+	// Флаг шифрования архива
+	Encrypt bool
+	// Флаг шифрования заголовков элементов и индекса
+	EncHeaders bool
+	// Файлы открытых ключей получателей архива
+	Recipients []string
+	// Файлы закрытых ключей для расшифровки архива
+	Identities []string
+	// Путь к создаваемому файлу закрытого ключа
+	KeyGen string
+	// Файл закрытого ключа подписи архива
+	Sign string
+	// Файлы открытых ключей доверенных подписей
+	Signers []string
+	// Флаг проверки подписи архива
+	Verify bool
+	// Флаг отказа от распаковки архивов
+	// без верной доверенной подписи
+	RequireSig bool
+	// Путь к создаваемому файлу закрытого ключа подписи
+	SignKeyGen string
+	// Переменная окружения и файл с паролем архива
+	PassEnv, PassFile string
+	Verbose           bool
 }
 
+// Правило выбора компрессора для файлов, имя
+// или путь которых соответствует шаблону
+type CodecRule struct {
+	Pattern string  // Шаблон имени или пути файла
+	Ct      c.Type  // Тип компрессора
+	Cl      c.Level // Уровень сжатия
+}
+
+// Тип контрольной суммы содержимого файлов
+type DigestType byte
+
+const (
+	DigestNone   DigestType = iota // Без контрольной суммы
+	DigestCRC32C                   // CRC32C
+	DigestSHA256                   // SHA-256
+)
+
+// Обработка поврежденных блоков при распаковке
+type DamageMode byte
+
+const (
+	DamageZero     DamageMode = iota // Заполнять нулями
+	DamageTruncate                   // Обрезать файл
+	DamageSkip                       // Пропускать
+)
+
+// Режим восстановления владельца
+type OwnerMode byte
+
+const (
+	OwnerAuto OwnerMode = iota // OwnerName для root, иначе OwnerNone
+	OwnerName                  // По имени
+	OwnerNum                   // По номеру
+	OwnerNone                  // Не восстанавливать
+)
+
+const (
+	minVolumeSize int64 = 64 << 10 // Минимальный размер тома архива
+	minSolidSize  int64 = 64 << 10 // Минимальный размер solid-блока
+)
+
 // Печатает справку
 func printHelp() {
 	program := filepath.Base(os.Args[0])
@@ -44,6 +149,7 @@ func printHelp() {
 	fmt.Println("Сжатие:    ", program, compExample)
 	fmt.Println("Распаковка:", program, decompExample)
 	fmt.Println("Просмотр:  ", program, viewExample)
+	fmt.Println("Ключи:     ", program, keyGenExample)
 	fmt.Printf("\nФлаги:\n")
 
 	flag.PrintDefaults()
@@ -62,13 +168,74 @@ func ParseParams() (p *Params, err error) {
 
 	var compType string
 	flag.StringVar(&compType, "c", "gzip", compDesc)
+	flag.Func("cr", codecRuleDesc, func(s string) error {
+		rule, err := parseCodecRule(s)
+		if err == nil {
+			p.CodecRules = append(p.CodecRules, rule)
+		}
+		return err
+	})
+	flag.BoolVar(&p.Adaptive, "adapt", false, adaptDesc)
+	flag.BoolVar(&p.Sample, "sample", false, sampleDesc)
+
+	var digest string
+	flag.StringVar(&digest, "digest", "none", digestDesc)
+
+	var damaged string
+	flag.StringVar(&damaged, "damaged", "zero", damagedDesc)
+	flag.IntVar(&p.Recovery, "rr", 0, recoveryDesc)
+
+	var volSize, solidSize string
+	flag.StringVar(&volSize, "vol", "", volumeDesc)
+	flag.StringVar(&solidSize, "solid", "", solidDesc)
+	flag.BoolVar(&p.Dedup, "dedup", false, dedupDesc)
+	flag.BoolVar(&p.DupFiles, "dupfiles", false, dupFilesDesc)
+	flag.BoolVar(&p.Repair, "repair", false, repairDesc)
+	flag.BoolVar(&p.Encrypt, "encrypt", false, encryptDesc)
+	flag.BoolVar(&p.EncHeaders, "encheaders", false, encHeadersDesc)
+	flag.StringVar(&p.PassEnv, "passenv", "", passEnvDesc)
+	flag.StringVar(&p.PassFile, "passfile", "", passFileDesc)
+	flag.Func("recipient", recipientDesc, func(path string) error {
+		p.Recipients = append(p.Recipients, path)
+		return nil
+	})
+	flag.Func("identity", identityDesc, func(path string) error {
+		p.Identities = append(p.Identities, path)
+		return nil
+	})
+	flag.StringVar(&p.KeyGen, "keygen", "", keyGenDesc)
+	flag.StringVar(&p.Sign, "sign", "", signDesc)
+	flag.Func("signer", signerDesc, func(path string) error {
+		p.Signers = append(p.Signers, path)
+		return nil
+	})
+	flag.BoolVar(&p.Verify, "verify", false, verifyDesc)
+	flag.BoolVar(&p.RequireSig, "requiresig", false, requireSigDesc)
+	flag.StringVar(&p.SignKeyGen, "signkeygen", "", signKeyGenDesc)
 
 	flag.BoolVar(&p.PrintStat, "s", false, statDesc)
 	flag.BoolVar(&p.PrintList, "l", false, listDesc)
+	flag.BoolVar(&p.PrintTimes, "times", false, timesDesc)
 	flag.BoolVar(&p.IntegTest, "integ", false, integDesc)
 	flag.BoolVar(&p.XIntegTest, "xinteg", false, xIntegDesc)
 	flag.BoolVar(&p.MemStat, "mstat", false, memStatDesc)
 	flag.BoolVar(&p.ReplaceAll, "f", false, relaceAllDesc)
+	flag.BoolVar(&p.NoPerm, "noperm", false, noPermDesc)
+
+	var owner, userMap, groupMap string
+	flag.StringVar(&owner, "owner", "", ownerDesc)
+	flag.StringVar(&userMap, "umap", "", userMapDesc)
+	flag.StringVar(&groupMap, "gmap", "", groupMapDesc)
+
+	var xattrInc, xattrExc string
+	flag.BoolVar(&p.Xattrs, "xattr", false, xattrDesc)
+	flag.BoolVar(&p.SymAbs, "symabs", false, symAbsDesc)
+	flag.StringVar(&xattrInc, "xattrinc", "", xattrIncDesc)
+	flag.StringVar(&xattrExc, "xattrexc", "", xattrExcDesc)
+	flag.Func("x", extractDesc, func(path string) error {
+		p.Extract = append(p.Extract, path)
+		return nil
+	})
 	flag.BoolVar(&p.Verbose, "v", false, verboseDesc)
 
 	logging := flag.Bool("log", false, logDesc)
@@ -88,8 +255,11 @@ func ParseParams() (p *Params, err error) {
 		printHelp()
 		os.Exit(0)
 	}
+	if p.KeyGen != "" || p.SignKeyGen != "" {
+		return p, nil
+	}
 
-	if (p.PrintList || p.PrintStat) && len(flag.Args()) == 0 {
+	if (p.PrintList || p.PrintStat || p.Repair || p.Verify) && len(flag.Args()) == 0 {
 		return nil, ErrArchivePath
 	}
 
@@ -103,12 +273,53 @@ func ParseParams() (p *Params, err error) {
 		if err = p.checkCompLevel(level); err != nil {
 			return nil, err
 		}
+		if p.Recovery < 0 || p.Recovery > 100 {
+			return nil, ErrRecovery
+		}
+		if err = p.checkVolumeSize(volSize); err != nil {
+			return nil, err
+		}
+		if err = p.checkSolidSize(solidSize); err != nil {
+			return nil, err
+		}
+	}
+
+	// Подпись без доверенного ключа может сделать кто угодно
+	if p.RequireSig && len(p.Signers) == 0 {
+		return nil, ErrRequireSigner
 	}
 
 	if err = p.checkDict(); err != nil {
 		return nil, err
 	}
 
+	if err = p.checkDigest(digest); err != nil {
+		return nil, err
+	}
+
+	if err = p.checkDamaged(damaged); err != nil {
+		return nil, err
+	}
+
+	if err = p.checkOwner(owner); err != nil {
+		return nil, err
+	}
+	if p.UserMap, err = parseOwnerMap(userMap); err != nil {
+		return nil, err
+	}
+	if p.GroupMap, err = parseOwnerMap(groupMap); err != nil {
+		return nil, err
+	}
+	// Без прав root владелец по умолчанию не
+	// восстанавливается, и замены не применялись бы
+	mapped := len(p.UserMap) > 0 || len(p.GroupMap) > 0
+	if mapped && p.Owner == OwnerAuto && os.Geteuid() != 0 {
+		return nil, ErrOwnerMapMode
+	}
+
+	p.XattrInclude = splitList(xattrInc)
+	p.XattrExclude = splitList(xattrExc)
+
 	return p, nil
 }
 
@@ -125,34 +336,38 @@ func (p Params) PrintNopLevelIgnore() {
 }
 
 // Флаги которые могут быть проигнорированы
-// другими флагами
+// другими флагами: флаги распаковки, флаги режимов
+// и флаги сжатия
 var ignores = [...]string{
-	"f", "o", "xinteg", "dict", "integ", "l", "s", "c", "L",
+	"f", "o", "xinteg", "noperm", "owner", "umap", "gmap", "x",
+	"damaged", "dict", "integ", "l", "s", "c", "L", "cr", "adapt",
+	"sample", "digest", "rr", "vol", "solid", "dedup", "dupfiles",
+	"encrypt", "encheaders", "recipient", "sign", "xattr", "symabs",
 }
 
 // Явный вывод какие флаги игнорирует режим сжатия
 func PrintCompressIgnore() {
-	printIgnore("Сжатие файлов", append(ignores[1:3], ignores[4:7]...))
+	printIgnore("Сжатие файлов", slices.Concat(ignores[1:9], ignores[10:13]))
 }
 
 // Явный вывод какие флаги игнорирует флаг '-s'
 func PrintStatIgnore() {
-	printIgnore("Наличие флага 's'", append(ignores[:6], ignores[7:]...))
+	printIgnore("Наличие флага 's'", slices.Concat(ignores[:12], ignores[13:]))
 }
 
 // Явный вывод какие флаги игнорирует флаг '-l'
 func PrintListIgnore() {
-	printIgnore("Наличие флага 'l'", append(ignores[:5], ignores[7:]...))
+	printIgnore("Наличие флага 'l'", slices.Concat(ignores[:11], ignores[13:]))
 }
 
 // Явный вывод какие флаги игнорирует флаг '--integ'
 func PrintIntegIgnore() {
-	printIgnore("Наличие флага 'integ'", append(ignores[:4], ignores[7:]...))
+	printIgnore("Наличие флага 'integ'", slices.Concat(ignores[:10], ignores[13:]))
 }
 
 // Явный вывод какие флаги игнорирует распаковка архива
 func PrintDecompressIgnore() {
-	printIgnore("Распаковка архива", ignores[4:])
+	printIgnore("Распаковка архива", ignores[10:])
 }
 
 // Общий шаблон вывода информации о том какие
@@ -184,23 +399,61 @@ func (p *Params) checkCompLevel(level int) error {
 }
 
 // Проверяет параметр типа компрессора
-func (p *Params) checkCompType(compType string) error {
-	compType = strings.ToLower(compType)
+func (p *Params) checkCompType(compType string) (err error) {
+	if p.Ct, err = parseCompType(compType); err == nil && p.Ct == c.Nop {
+		return ErrUnknownComp // Без сжатия задается уровнем 0
+	}
+	return err
+}
 
-	switch compType {
+// Разбирает название типа компрессора
+func parseCompType(compType string) (c.Type, error) {
+	switch strings.ToLower(compType) {
+	case "nop":
+		return c.Nop, nil
 	case "gzip":
-		p.Ct = c.GZip
+		return c.GZip, nil
 	case "lzw":
-		p.Ct = c.LempelZivWelch
+		return c.LempelZivWelch, nil
 	case "zlib":
-		p.Ct = c.ZLib
+		return c.ZLib, nil
 	case "flate":
-		p.Ct = c.Flate
+		return c.Flate, nil
 	default:
-		return ErrUnknownComp
+		return 0, ErrUnknownComp
+	}
+}
+
+// Разбирает правило выбора компрессора вида
+// 'шаблон=тип[:уровень]'
+func parseCodecRule(s string) (rule CodecRule, err error) {
+	pattern, codec, ok := strings.Cut(s, "=")
+	if !ok || pattern == "" || codec == "" {
+		return rule, ErrCodecRule(s)
+	}
+	if _, err = filepath.Match(pattern, ""); err != nil {
+		return rule, ErrCodecRule(s)
 	}
 
-	return nil
+	compType, level, hasLevel := strings.Cut(codec, ":")
+	rule = CodecRule{Pattern: pattern, Cl: c.DefaultCompression}
+	if rule.Ct, err = parseCompType(compType); err != nil {
+		return rule, err
+	}
+
+	if hasLevel {
+		l, err := strconv.Atoi(level)
+		if err != nil {
+			return rule, ErrCodecRule(s)
+		}
+		if rule.Cl = c.Level(l); rule.Cl < -2 || rule.Cl > 9 {
+			return rule, ErrCompLevel
+		} else if rule.Cl == 0 {
+			rule.Ct = c.Nop
+		}
+	}
+
+	return rule, nil
 }
 
 // Проверяет пути к файлам и архиву
@@ -238,3 +491,139 @@ func (p Params) checkDict() error {
 
 	return nil
 }
+
+// Проверяет параметр типа контрольной суммы содержимого
+func (p *Params) checkDigest(digest string) error {
+	switch strings.ToLower(digest) {
+	case "none":
+		p.Digest = DigestNone
+	case "crc32c":
+		p.Digest = DigestCRC32C
+	case "sha256":
+		p.Digest = DigestSHA256
+	default:
+		return ErrDigestType
+	}
+
+	return nil
+}
+
+// Проверяет параметр обработки поврежденных блоков
+func (p *Params) checkDamaged(damaged string) error {
+	switch strings.ToLower(damaged) {
+	case "zero":
+		p.Damaged = DamageZero
+	case "trunc":
+		p.Damaged = DamageTruncate
+	case "skip":
+		p.Damaged = DamageSkip
+	default:
+		return ErrDamageMode
+	}
+
+	return nil
+}
+
+// Разбирает размер тома с необязательным
+// суффиксом k, M или G
+func (p *Params) checkVolumeSize(size string) error {
+	if size == "" {
+		return nil
+	}
+
+	n, ok := parseSize(size)
+	if !ok || n < minVolumeSize {
+		return ErrVolumeSize
+	}
+	p.VolumeSize = n
+
+	return nil
+}
+
+// Разбирает размер solid-блока с необязательным
+// суффиксом k, M или G
+func (p *Params) checkSolidSize(size string) error {
+	if size == "" {
+		return nil
+	}
+
+	n, ok := parseSize(size)
+	if !ok || n < minSolidSize {
+		return ErrSolidSize
+	}
+	p.SolidSize = n
+
+	return nil
+}
+
+// Разбирает положительный размер в байтах с
+// необязательным суффиксом k, M или G
+func parseSize(size string) (int64, bool) {
+	mult := int64(1)
+	switch size[len(size)-1] {
+	case 'k', 'K':
+		mult = 1 << 10
+	case 'm', 'M':
+		mult = 1 << 20
+	case 'g', 'G':
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		size = size[:len(size)-1]
+	}
+
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/mult {
+		return 0, false
+	}
+
+	return n * mult, true
+}
+
+// Проверяет параметр режима восстановления владельца
+func (p *Params) checkOwner(owner string) error {
+	switch strings.ToLower(owner) {
+	case "":
+		p.Owner = OwnerAuto
+	case "name":
+		p.Owner = OwnerName
+	case "num":
+		p.Owner = OwnerNum
+	case "none":
+		p.Owner = OwnerNone
+	default:
+		return ErrOwnerMode
+	}
+
+	return nil
+}
+
+// Разбирает таблицу замены вида 'старый=новый,...'
+func parseOwnerMap(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok || from == "" || to == "" {
+			return nil, ErrOwnerMap(pair)
+		}
+		m[from] = to
+	}
+
+	return m, nil
+}
+
+// Разбивает список вида 'a,b,...' на элементы,
+// пропуская пустые
+func splitList(s string) (list []string) {
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
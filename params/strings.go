@@ -4,7 +4,7 @@ package params
 
 const (
 	versionDesc = "Печать номера версии и выход"
-	versionText = "github.com/gh0st17/archiver 1.0.4\n" +
+	versionText = "github.com/gh0st17/archiver 1.1.0\n" +
 		"Copyright (C) 2025\n" +
 		"Лицензия MIT: THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY\n" +
 		"OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO\n" +
@@ -21,6 +21,7 @@ const (
 	compExample   = "[Флаги] <путь до архива> <список директории, файлов для сжатия>"
 	decompExample = "[-o <путь к директории для распаковки>] <путь до архива>"
 	viewExample   = "[-l | -s] <путь до архива>"
+	keyGenExample = "-keygen | -signkeygen <путь к файлу закрытого ключа>"
 
 	outputDirDesc = "Путь к директории для распаковки"
 	dictPathDesc  = "Путь к файлу словаря\n" +
@@ -44,6 +45,107 @@ const (
 	relaceAllDesc = "Автоматически заменять файлы при распаковке без подтверждения"
 	verboseDesc   = "Печатать обработанные файлы"
 	logDesc       = "Печатать логи"
+	noPermDesc    = "Не восстанавливать режим доступа при распаковке,\n" +
+		"права элементов будут определяться маской umask"
+	ownerDesc = "Режим восстановления владельца и группы при распаковке:\n" +
+		"name -- По имени, если имя не найдено, то по номеру\n" +
+		" num -- По номеру\n" +
+		"none -- Не восстанавливать\n" +
+		"По умолчанию name для root, иначе none"
+	userMapDesc = "Замена пользователей при распаковке в виде\n" +
+		"'старый=новый,...', где пользователь задается именем или номером.\n" +
+		"Без прав root требует '-owner'"
+	groupMapDesc = "Замена групп при распаковке в виде\n" +
+		"'старая=новая,...', где группа задается именем или номером.\n" +
+		"Без прав root требует '-owner'"
+	symAbsDesc = "Сохранять вместо пути назначения символической\n" +
+		"ссылки абсолютный путь к конечному элементу,\n" +
+		"испорченные ссылки пропускаются"
+	xattrDesc = "Сохранять расширенные атрибуты и списки ACL при сжатии\n" +
+		"(только Linux). Сохраненные атрибуты восстанавливаются\n" +
+		"при распаковке"
+	xattrIncDesc = "Пространства имен расширенных атрибутов через запятую,\n" +
+		"которые сохраняются и восстанавливаются, например\n" +
+		"'user,system.posix_acl_access'. По умолчанию все"
+	xattrExcDesc = "Пространства имен расширенных атрибутов через запятую,\n" +
+		"которые не сохраняются и не восстанавливаются"
+	timesDesc = "Печать всех временных меток элементов с точностью\n" +
+		"до наносекунд вместе с флагом -s"
+	codecRuleDesc = "Правило выбора компрессора для файлов в виде\n" +
+		"'шаблон=тип[:уровень]', например '*.jpg=nop' или\n" +
+		"'*.txt=flate:9'. Шаблон без '/' сравнивается с именем\n" +
+		"файла, иначе с путем в архиве, без учета регистра.\n" +
+		"Применяется первое подходящее правило, флаг можно\n" +
+		"указать несколько раз"
+	adaptDesc = "Хранить блоки данных без сжатия, если сжатие\n" +
+		"не уменьшает их размер"
+	sampleDesc = "Как '-adapt', но если не сжимается первый блок\n" +
+		"файла, то весь файл хранится без сжатия"
+	digestDesc = "Тип контрольной суммы содержимого файлов: none,\n" +
+		"crc32c или sha256. Сумма проверяется при распаковке\n" +
+		"и проверке целостности"
+	damagedDesc = "Обработка поврежденных блоков при распаковке:\n" +
+		" zero -- Заполнять нулями\n" +
+		"trunc -- Обрезать файл по первому поврежденному блоку\n" +
+		" skip -- Пропускать поврежденные блоки\n" +
+		"Остальные блоки файла восстанавливаются"
+	recoveryDesc = "Размер записи восстановления в процентах от\n" +
+		"размера архива (1-100), по ней исправляются\n" +
+		"поврежденные области архива. 0 -- без записи"
+	volumeDesc = "Размер тома архива, например '100M' или '2G'.\n" +
+		"Архив разделяется на тома name.arc.001, name.arc.002\n" +
+		"и т.д., при распаковке указывается любой из томов или\n" +
+		"путь архива без номера тома"
+	solidDesc = "Размер solid-блока, например '16M'. Файлы меньше\n" +
+		"этого размера сжимаются общим потоком в solid-блоках,\n" +
+		"что улучшает сжатие множества небольших файлов.\n" +
+		"Для распаковки одного файла распаковывается весь\n" +
+		"его блок"
+	dedupDesc = "Дедупликация: данные файлов разбиваются на фрагменты\n" +
+		"по содержимому, и каждый уникальный фрагмент хранится\n" +
+		"в архиве один раз. Файлы solid-блоков не разбиваются"
+	dupFilesDesc = "Хранить данные файлов с одинаковым содержимым один\n" +
+		"раз: повторные файлы ссылаются на данные первого и\n" +
+		"при распаковке получают их копию. Файлы solid-блоков\n" +
+		"не проверяются"
+	repairDesc = "Исправление поврежденных областей архива\n" +
+		"по записи восстановления"
+	encryptDesc = "Шифровать блоки данных алгоритмом AES-256-GCM\n" +
+		"с ключом, получаемым из пароля. Пароль читается\n" +
+		"из файла '-passfile', переменной окружения '-passenv'\n" +
+		"или вводится в терминале"
+	encHeadersDesc = "Шифровать также заголовки элементов и индекс\n" +
+		"архива, скрывая пути, размеры и временные метки.\n" +
+		"Просмотр списка требует пароля или ключа. Без\n" +
+		"'-recipient' включает '-encrypt'"
+	recipientDesc = "Путь к файлу открытого ключа получателя архива.\n" +
+		"Ключ данных архива шифруется для каждого получателя,\n" +
+		"расшифровать архив можно закрытым ключом любого из\n" +
+		"них. Флаг можно указать несколько раз, заменяет пароль"
+	identityDesc = "Путь к файлу закрытого ключа для расшифровки\n" +
+		"архива. Флаг можно указать несколько раз"
+	keyGenDesc = "Создать пару ключей: закрытый ключ записывается\n" +
+		"в указанный файл, открытый -- в файл с расширением '.pub'"
+	signDesc = "Путь к файлу закрытого ключа Ed25519 для подписи\n" +
+		"архива. Подпись охватывает заголовок, элементы с их\n" +
+		"данными и контрольными суммами и индекс архива"
+	signerDesc = "Путь к файлу открытого ключа доверенной подписи.\n" +
+		"Распаковка и проверка подписи требуют, чтобы архив\n" +
+		"был подписан одним из ключей. Флаг можно указать\n" +
+		"несколько раз"
+	verifyDesc     = "Проверка подписи архива"
+	requireSigDesc = "Не распаковывать архивы без подписи или с неверной\n" +
+		"подписью, подпись проверяется перед распаковкой.\n" +
+		"Требует '-signer': архив должен быть подписан одним\n" +
+		"из доверенных ключей"
+	signKeyGenDesc = "Создать пару ключей подписи: закрытый ключ\n" +
+		"записывается в указанный файл, открытый -- в файл\n" +
+		"с расширением '.pub'"
+	passEnvDesc  = "Имя переменной окружения с паролем архива"
+	passFileDesc = "Путь к файлу с паролем архива"
+	extractDesc  = "Путь элемента в архиве для выборочной распаковки,\n" +
+		"директория распаковывается вместе с содержимым.\n" +
+		"Флаг можно указать несколько раз"
 
 	zeroLevel = "Флаг '-L' со значением '0' игнорирует '-c'"
 )